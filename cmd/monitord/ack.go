@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// runAck acknowledges the incident of a down endpoint on the running daemon,
+// pausing its escalation, or clears the acknowledgement with --clear
+func runAck(args []string) error {
+	fs := flag.NewFlagSet("ack", flag.ContinueOnError)
+	by := fs.String("by", os.Getenv("USER"), "who is handling the incident")
+	note := fs.String("note", "", "note shown with the acknowledgement")
+	duration := fs.Duration("for", 0, "resume escalation after this long if still down (default: until recovery)")
+	clear := fs.Bool("clear", false, "clear the acknowledgement so escalation resumes")
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one endpoint URL: monitord ack [--by NAME] [--note TEXT] [--for DURATION] [--clear] URL")
+	}
+	endpoint := fs.Arg(0)
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+
+	path := "/endpoints/" + url.PathEscape(endpoint) + "/acknowledge"
+	if *clear {
+		if err := client.do(http.MethodDelete, path, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s is no longer acknowledged\n", endpoint)
+		return nil
+	}
+	if *by == "" {
+		return fmt.Errorf("--by is required when $USER is not set")
+	}
+
+	body := map[string]string{"by": *by, "note": *note}
+	if *duration > 0 {
+		body["for"] = duration.String()
+	}
+	var ack monitor.Acknowledgement
+	if err := client.send(http.MethodPost, path, body, &ack); err != nil {
+		return err
+	}
+	if ack.Expires == nil {
+		fmt.Printf("%s acknowledged by %s, escalation paused until it recovers\n", endpoint, ack.By)
+	} else {
+		fmt.Printf("%s acknowledged by %s, escalation paused until %s\n", endpoint, ack.By, ack.Expires.Format(time.RFC3339))
+	}
+	return nil
+}
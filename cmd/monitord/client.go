@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/api"
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// daemonClient calls the API of a running monitord
+type daemonClient struct {
+	baseURL string
+	// address is where the daemon is reached, for display
+	address string
+	client  *http.Client
+	// auth is the API credentials from the config, sent with every request
+	auth *config.ServerAuth
+}
+
+// newDaemonClient connects to the given address, or to the API address from
+// the config when addr is empty. A "unix:" address is a socket path. The
+// API credentials from the config are sent either way.
+func newDaemonClient(addr string) (*daemonClient, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	scheme := "http://"
+	var auth *config.ServerAuth
+	if addr != "" {
+		// The config only supplies credentials, so a missing or invalid
+		// one is no reason to fail, nor to create the example config
+		if path, err := config.DefaultPath(); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				if cfg, err := config.LoadFromFile(path); err == nil {
+					auth = cfg.API.Auth
+				}
+			}
+		}
+	} else {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		auth = cfg.API.Auth
+		if !cfg.API.Enabled {
+			return nil, errors.New("the API is not enabled in the config; set api.enabled to use this command")
+		}
+		addr = api.Address(cfg.API)
+		if cfg.API.TLSCertFile != "" {
+			// The daemon's own certificate is trusted, so a self-signed
+			// one works as well as one from a public CA
+			roots, err := apiRoots(cfg.API.TLSCertFile)
+			if err != nil {
+				return nil, err
+			}
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+			scheme = "https://"
+		}
+	}
+	// A socket is shown by its path rather than the placeholder host
+	address := ""
+	if path, ok := strings.CutPrefix(addr, config.UnixSocketPrefix); ok {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		address, addr = addr, "http://monitord"
+	}
+	if !strings.Contains(addr, "://") {
+		addr = scheme + addr
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	if address == "" {
+		address = addr
+	}
+
+	return &daemonClient{
+		baseURL: addr,
+		address: address,
+		client:  client,
+		auth:    auth,
+	}, nil
+}
+
+// apiRoots returns the system roots plus the certificates of the API's
+// TLS certificate file
+func apiRoots(certFile string) (*x509.CertPool, error) {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	certs, err := config.ReadCertificates(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api tls_cert_file: %w", err)
+	}
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+	return roots, nil
+}
+
+// do sends a request and decodes a JSON response into out when it is non-nil
+func (c *daemonClient) do(method, path string, out interface{}) error {
+	return c.send(method, path, nil, out)
+}
+
+// send is do with a request body, encoded as JSON when it is non-nil
+func (c *daemonClient) send(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	api.SetAuth(req, c.auth)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach monitord: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("monitord responded with %d: %s", resp.StatusCode, apiErr.Error)
+		}
+		return fmt.Errorf("monitord responded with %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// openStore loads the configuration and opens its database
+func openStore() (*config.Config, *storage.SQLiteStore, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store, err := storage.NewSQLiteStore(cfg.Database.Path, cfg.Database.Mode())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return cfg, store, nil
+}
+
+// dbUsage describes the --db flag of the commands that read history
+const dbUsage = "read this database file instead, such as a rotated or archived one; .gz files are decompressed"
+
+// openHistory opens the database at db when it is set, such as a rotated or
+// archived one, or the configured database otherwise
+func openHistory(db string) (*storage.SQLiteStore, error) {
+	if db == "" {
+		_, store, err := openStore()
+		return store, err
+	}
+	store, err := storage.OpenArchive(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", db, err)
+	}
+	return store, nil
+}
+
+// parseTime accepts either a duration relative to now (e.g. "24h") or an
+// RFC 3339 timestamp. An empty value yields the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected a duration like 24h or an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// Response-time units accepted by --unit
+const (
+	unitMillis = "ms"
+	unitMicros = "us"
+)
+
+// checkUnit validates a --unit flag
+func checkUnit(unit string) error {
+	if unit != unitMillis && unit != unitMicros {
+		return fmt.Errorf("--unit must be %q or %q, got %q", unitMillis, unitMicros, unit)
+	}
+	return nil
+}
+
+// formatResponseTime formats a response time given in microseconds in the
+// unit, with the unit as suffix
+func formatResponseTime(micros float64, unit string) string {
+	if unit == unitMicros {
+		return fmt.Sprintf("%.0fus", micros)
+	}
+	return fmt.Sprintf("%.0fms", micros/1000)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/notify"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// doctorChecks names the checks run by doctor, in order
+var doctorChecks = []string{"config", "database", "log", "network", "notifiers"}
+
+// doctorTimeout bounds each check that goes over the network
+const doctorTimeout = 15 * time.Second
+
+// doctorReport prints one line per check and counts the failures. With
+// asJSON set the results are collected for printing at the end instead.
+type doctorReport struct {
+	asJSON bool
+	Checks []doctorResult `json:"checks"`
+	Failed int            `json:"failed"`
+}
+
+// doctorResult is one check in the --json output of doctor
+type doctorResult struct {
+	Name    string `json:"name"`
+	Result  string `json:"result"` // "pass", "fail" or "skip"
+	Message string `json:"message"`
+}
+
+func (r *doctorReport) add(name, result, message string) {
+	r.Checks = append(r.Checks, doctorResult{name, result, message})
+	if !r.asJSON {
+		fmt.Printf("%-4s  %-18s %s\n", strings.ToUpper(result), name, message)
+	}
+}
+
+func (r *doctorReport) pass(name, format string, args ...interface{}) {
+	r.add(name, "pass", fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) fail(name string, err error) {
+	r.Failed++
+	r.add(name, "fail", err.Error())
+}
+
+func (r *doctorReport) skip(name, reason string) {
+	r.add(name, "skip", reason)
+}
+
+// runDoctor checks that everything monitord needs on this host works: the
+// config, the database, the log path, the network and the notifiers
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	skipList := fs.String("skip", "", "comma-separated checks to skip: "+strings.Join(doctorChecks, ", "))
+	canary := fs.String("canary", "https://example.com/", "URL fetched to check network reachability")
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	skipped := map[string]bool{}
+	for _, name := range strings.Split(*skipList, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !slices.Contains(doctorChecks, name) {
+			return fmt.Errorf("unknown check %q for --skip; choose from %s", name, strings.Join(doctorChecks, ", "))
+		}
+		skipped[name] = true
+	}
+
+	report := &doctorReport{asJSON: *asJSON}
+	var cfg *config.Config
+	if skipped["config"] {
+		report.skip("config", "skipped with --skip")
+	} else if loaded, path, err := doctorLoadConfig(); err != nil {
+		report.fail("config", err)
+	} else {
+		cfg = loaded
+		report.pass("config", "%s is valid, %d endpoints", path, len(cfg.Monitor.Endpoints))
+	}
+
+	// The remaining checks other than network read their settings from the
+	// config
+	withConfig := func(name string, check func(cfg *config.Config)) {
+		switch {
+		case skipped[name]:
+			report.skip(name, "skipped with --skip")
+		case cfg == nil:
+			report.skip(name, "needs a valid config")
+		default:
+			check(cfg)
+		}
+	}
+
+	withConfig("database", func(cfg *config.Config) {
+		paths := []string{cfg.Database.Path}
+		for _, backend := range cfg.Database.Backends {
+			paths = append(paths, backend.Path)
+		}
+		for _, path := range paths {
+			if err := doctorDatabase(path, cfg.Database.Mode()); err != nil {
+				report.fail("database", err)
+				return
+			}
+		}
+		if len(paths) == 1 {
+			report.pass("database", "%s is writable", paths[0])
+		} else {
+			report.pass("database", "%s are writable", strings.Join(paths, ", "))
+		}
+	})
+	withConfig("log", func(cfg *config.Config) {
+		if err := checkWritablePath(cfg.Logging.Path); err != nil {
+			report.fail("log", err)
+			return
+		}
+		report.pass("log", "%s is writable", cfg.Logging.Path)
+	})
+
+	if skipped["network"] {
+		report.skip("network", "skipped with --skip")
+	} else if status, err := doctorNetwork(*canary); err != nil {
+		report.fail("network", err)
+	} else {
+		report.pass("network", "%s responded %s", *canary, status)
+	}
+
+	withConfig("notifiers", func(cfg *config.Config) {
+		if len(cfg.Notifications.Notifiers) == 0 {
+			report.skip("notifiers", "no notifiers configured")
+			return
+		}
+		for _, notifier := range cfg.Notifications.Notifiers {
+			name := "notifier " + notifier.Name
+			ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+			err := notify.SendTest(ctx, notifier)
+			cancel()
+			if err != nil {
+				report.fail(name, err)
+				continue
+			}
+			report.pass(name, "accepted a test notification")
+		}
+	})
+
+	if *asJSON {
+		if err := writeJSON(report); err != nil {
+			return err
+		}
+	}
+	switch {
+	case report.Failed == 1:
+		return errors.New("1 check failed")
+	case report.Failed > 1:
+		return fmt.Errorf("%d checks failed", report.Failed)
+	}
+	return nil
+}
+
+// doctorLoadConfig loads and validates the config file without creating an
+// example one when it is missing
+func doctorLoadConfig() (*config.Config, string, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, path, err
+	}
+	cfg, err := config.LoadFromFile(path)
+	if err != nil {
+		return nil, path, err
+	}
+	return cfg, path, nil
+}
+
+// doctorDatabase opens the database at path, applying any pending
+// migrations, and takes its write lock
+func doctorDatabase(path string, mode os.FileMode) error {
+	store, err := storage.NewSQLiteStore(path, mode)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer store.Close()
+	if err := store.CheckWritable(); err != nil {
+		return fmt.Errorf("database %s is not writable: %w", store.Path(), err)
+	}
+	return nil
+}
+
+// checkWritablePath reports whether a file can be appended to, or created
+// when it does not exist yet, without changing it
+func checkWritablePath(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("cannot create %s: %w", path, err)
+	}
+	f, err := os.CreateTemp(dir, ".monitord-doctor-*")
+	if err != nil {
+		return fmt.Errorf("cannot create %s: %w", path, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// doctorNetwork fetches the canary URL; any HTTP response shows the network
+// is reachable
+func doctorNetwork(canary string) (string, error) {
+	client := &http.Client{Timeout: doctorTimeout}
+	resp, err := client.Get(canary)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Status, nil
+}
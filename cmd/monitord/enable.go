@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// runEnable asks the running daemon to monitor an endpoint regardless of its
+// enabled setting
+func runEnable(args []string) error {
+	return setEnabled("enable", args)
+}
+
+// runDisable asks the running daemon to stop monitoring an endpoint
+// regardless of its enabled setting
+func runDisable(args []string) error {
+	return setEnabled("disable", args)
+}
+
+// setEnabled sends an enable or disable override for the endpoint URL in
+// args, or clears the override with --reset
+func setEnabled(action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	reset := fs.Bool("reset", false, "clear the override so the endpoint follows its enabled setting again")
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one endpoint URL: monitord %s [--reset] URL", action)
+	}
+	endpoint := fs.Arg(0)
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+
+	path := "/endpoints/" + url.PathEscape(endpoint)
+	if *reset {
+		if err := client.do(http.MethodDelete, path+"/override", nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s follows its enabled setting again\n", endpoint)
+		return nil
+	}
+	if err := client.do(http.MethodPost, path+"/"+action, nil); err != nil {
+		return err
+	}
+	fmt.Printf("%s is %sd until its enabled setting changes in the config\n", endpoint, action)
+	return nil
+}
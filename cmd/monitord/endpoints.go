@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// endpointJSON is an endpoint in the --json output of endpoints
+type endpointJSON struct {
+	URL        string    `json:"url"`
+	Name       string    `json:"name"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Configured bool      `json:"configured"`
+}
+
+// runEndpoints lists every endpoint that has ever been checked, including
+// ones since removed from the config
+func runEndpoints(args []string) error {
+	fs := flag.NewFlagSet("endpoints", flag.ContinueOnError)
+	removed := fs.Bool("removed", false, "only list endpoints no longer in the config")
+	asJSON := fs.Bool("json", false, "print the endpoints as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	records, err := store.ListEndpoints()
+	if err != nil {
+		return fmt.Errorf("failed to list endpoints: %w", err)
+	}
+
+	configured := make(map[string]bool)
+	for _, endpoint := range cfg.Monitor.Endpoints {
+		configured[endpoint.URL] = true
+	}
+
+	if *asJSON {
+		out := []endpointJSON{}
+		for _, r := range records {
+			if *removed && configured[r.URL] {
+				continue
+			}
+			out = append(out, endpointJSON{r.URL, r.Name, r.FirstSeen, r.LastSeen, configured[r.URL]})
+		}
+		return writeJSON(out)
+	}
+
+	const layout = "2006-01-02 15:04"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tNAME\tFIRST SEEN\tLAST SEEN\tCONFIGURED")
+	for _, r := range records {
+		if *removed && configured[r.URL] {
+			continue
+		}
+		state := "no"
+		if configured[r.URL] {
+			state = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.URL, r.Name,
+			r.FirstSeen.Local().Format(layout), r.LastSeen.Local().Format(layout), state)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// csvColumns are the endpoint fields exchanged by export-endpoints and
+// import-endpoints. Tags are separated by semicolons within their cell.
+var csvColumns = []string{
+	"name", "url", "interval", "timeout", "description", "tags", "enabled", "failure_threshold",
+}
+
+// runExportEndpoints writes the configured endpoints as CSV
+func runExportEndpoints(args []string) error {
+	fs := flag.NewFlagSet("export-endpoints", flag.ContinueOnError)
+	output := fs.String("output", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return writeEndpointsCSV(w, cfg.Monitor.Endpoints)
+}
+
+// runImportEndpoints merges endpoints from a CSV file into the config file.
+// Rows are matched to existing endpoints by URL; only the CSV columns are
+// updated, so settings without a column are preserved.
+func runImportEndpoints(args []string) error {
+	fs := flag.NewFlagSet("import-endpoints", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show the changes without writing the config")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: monitord import-endpoints [--dry-run] file.csv")
+	}
+
+	f, err := os.Open(positional[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := readEndpointsCSV(f)
+	if err != nil {
+		return err
+	}
+
+	configPath, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var added, updated, unchanged int
+	for _, row := range rows {
+		index := -1
+		for i, endpoint := range cfg.Monitor.Endpoints {
+			if endpoint.URL == row.values["url"] {
+				index = i
+				break
+			}
+		}
+
+		if index < 0 {
+			endpoint := newCSVEndpoint()
+			if err := row.apply(&endpoint); err != nil {
+				return err
+			}
+			cfg.Monitor.Endpoints = append(cfg.Monitor.Endpoints, endpoint)
+			fmt.Printf("add     %s\n", endpoint.URL)
+			added++
+			continue
+		}
+
+		endpoint := cfg.Monitor.Endpoints[index]
+		if err := row.apply(&endpoint); err != nil {
+			return err
+		}
+		if slices.Equal(endpointRecordFields(endpoint), endpointRecordFields(cfg.Monitor.Endpoints[index])) {
+			unchanged++
+			continue
+		}
+		cfg.Monitor.Endpoints[index] = endpoint
+		fmt.Printf("update  %s\n", endpoint.URL)
+		updated++
+	}
+
+	fmt.Printf("%d added, %d updated, %d unchanged\n", added, updated, unchanged)
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("imported config is invalid, not saving:\n%w", err)
+	}
+	if *dryRun {
+		fmt.Println("Dry run: config not written")
+		return nil
+	}
+	if added+updated == 0 {
+		return nil
+	}
+	if err := cfg.SaveToFile(configPath); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", configPath)
+	return nil
+}
+
+// newCSVEndpoint returns the defaults for endpoints added by an import,
+// matching the example config
+func newCSVEndpoint() config.Endpoint {
+	return config.Endpoint{
+		Interval: config.Duration(60 * time.Second),
+		Timeout:  config.Duration(10 * time.Second),
+		Enabled:  true,
+	}
+}
+
+// writeEndpointsCSV writes a header row followed by one row per endpoint
+func writeEndpointsCSV(w io.Writer, endpoints []config.Endpoint) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		if err := cw.Write(endpointRecordFields(endpoint)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// endpointRecordFields renders an endpoint in csvColumns order
+func endpointRecordFields(e config.Endpoint) []string {
+	return []string{
+		e.Name,
+		e.URL,
+		e.Interval.ToDuration().String(),
+		e.Timeout.ToDuration().String(),
+		e.Description,
+		strings.Join(e.Tags, ";"),
+		strconv.FormatBool(e.Enabled),
+		strconv.Itoa(e.FailureThreshold),
+	}
+}
+
+// csvRow holds the cells of one CSV row keyed by column name
+type csvRow struct {
+	line   int
+	values map[string]string
+}
+
+// readEndpointsCSV reads rows keyed by the header row. Columns may appear in
+// any order and unknown columns are rejected.
+func readEndpointsCSV(r io.Reader) ([]csvRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	hasURL := false
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		header[i] = column
+		if !slices.Contains(csvColumns, column) {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+		hasURL = hasURL || column == "url"
+	}
+	if !hasURL {
+		return nil, errors.New("CSV must include a url column")
+	}
+
+	var rows []csvRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := csvRow{line: line, values: make(map[string]string)}
+		for i, column := range header {
+			row.values[column] = strings.TrimSpace(record[i])
+		}
+		if row.values["url"] == "" {
+			return nil, fmt.Errorf("line %d: url is required", line)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// apply copies the row's cells onto an endpoint
+func (r csvRow) apply(e *config.Endpoint) error {
+	for column, value := range r.values {
+		var err error
+		switch column {
+		case "name":
+			e.Name = value
+		case "url":
+			e.URL = value
+		case "interval":
+			err = parseCSVDuration(value, &e.Interval)
+		case "timeout":
+			err = parseCSVDuration(value, &e.Timeout)
+		case "description":
+			e.Description = value
+		case "tags":
+			e.Tags = nil
+			for _, tag := range strings.Split(value, ";") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+		case "enabled":
+			if value != "" {
+				e.Enabled, err = strconv.ParseBool(value)
+			}
+		case "failure_threshold":
+			if value != "" {
+				e.FailureThreshold, err = strconv.Atoi(value)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: invalid %s %q: %w", r.line, column, value, err)
+		}
+	}
+	return nil
+}
+
+func parseCSVDuration(value string, d *config.Duration) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = config.Duration(parsed)
+	return nil
+}
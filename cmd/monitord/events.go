@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// runEvents lists monitord's recorded startups, shutdowns, config reloads
+// and notifications, newest first
+func runEvents(args []string) error {
+	fs := flag.NewFlagSet("events", flag.ContinueOnError)
+	since := fs.String("since", "168h", "start of the window (duration ago or RFC 3339 time)")
+	until := fs.String("until", "", "end of the window (duration ago or RFC 3339 time)")
+	eventType := fs.String("type", "", "only list events of this type (startup, shutdown, reload, notification, connectivity, watchdog, override, acknowledgement, panic)")
+	url := fs.String("url", "", "only list events for this endpoint URL")
+	limit := fs.Int("limit", 100, "maximum number of events to list (0 for all)")
+	asJSON := fs.Bool("json", false, "print the events as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	events, err := store.QueryEvents(storage.EventFilter{
+		Type:  *eventType,
+		URL:   *url,
+		Since: sinceTime,
+		Until: untilTime,
+		Limit: *limit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+
+	if *asJSON {
+		if events == nil {
+			events = []monitor.Event{}
+		}
+		return writeJSON(events)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tTYPE\tMESSAGE")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.Type, e.Message)
+	}
+	return w.Flush()
+}
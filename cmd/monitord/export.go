@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// runExport streams stored checks as newline-delimited JSON, one check per
+// line, so large histories can be loaded into other tools
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	since := fs.String("since", "", "start of the export window (duration ago or RFC 3339 time); default all history")
+	until := fs.String("until", "", "end of the export window (duration ago or RFC 3339 time)")
+	url := fs.String("url", "", "only export this endpoint URL")
+	tag := fs.String("tag", "", "only export checks with this tag")
+	probe := fs.String("probe", "", "only export checks run by this probe")
+	format := fs.String("format", "ndjson", "output format (only ndjson is supported)")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	db := fs.String("db", "", dbUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "ndjson" {
+		return fmt.Errorf("unsupported format %q: only ndjson is supported", *format)
+	}
+
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	store, err := openHistory(*db)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	count := 0
+	err = store.StreamChecks(storage.CheckFilter{
+		URL:   *url,
+		Tag:   *tag,
+		Probe: *probe,
+		Since: sinceTime,
+		Until: untilTime,
+	}, func(check monitor.HealthCheck) error {
+		count++
+		return enc.Encode(check)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export checks: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d checks to %s\n", count, *output)
+	}
+	return nil
+}
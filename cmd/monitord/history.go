@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// runHistory lists stored checks, oldest first
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	since := fs.String("since", "1h", "start of the window (duration ago or RFC 3339 time)")
+	until := fs.String("until", "", "end of the window (duration ago or RFC 3339 time)")
+	url := fs.String("url", "", "only list checks of this endpoint URL")
+	tag := fs.String("tag", "", "only list checks with this tag")
+	probe := fs.String("probe", "", "only list checks run by this probe")
+	limit := fs.Int("limit", 100, "maximum number of checks to list (0 for all)")
+	asJSON := fs.Bool("json", false, "print the checks as a JSON array")
+	unit := fs.String("unit", unitMillis, "response-time unit: ms or us")
+	db := fs.String("db", "", dbUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkUnit(*unit); err != nil {
+		return err
+	}
+
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	store, err := openHistory(*db)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	checks, err := store.QueryChecks(storage.CheckFilter{
+		URL:   *url,
+		Tag:   *tag,
+		Probe: *probe,
+		Since: sinceTime,
+		Until: untilTime,
+		Limit: *limit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read checks: %w", err)
+	}
+
+	if *asJSON {
+		if checks == nil {
+			checks = []monitor.HealthCheck{}
+		}
+		return writeJSON(checks)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tURL\tSTATUS\tCODE\tRESPONSE\tREASON")
+	for _, check := range checks {
+		code, response := "-", "-"
+		if check.StatusCode != 0 {
+			code = fmt.Sprint(check.StatusCode)
+			response = formatResponseTime(float64(check.ResponseTimeMicros), *unit)
+		}
+		reason := check.Reason
+		if reason == "" {
+			reason = check.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", check.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			check.URL, check.Status, code, response, reason)
+	}
+	return w.Flush()
+}
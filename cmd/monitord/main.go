@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -10,9 +11,80 @@ import (
 
 	"github.com/will-wright-eng/monitord/internal/app"
 	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/logfile"
 )
 
+// command is a CLI subcommand run instead of the daemon
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"status", "show the status of every endpoint from the running daemon", runStatus},
+	{"history", "list stored checks", runHistory},
+	{"endpoints", "list every endpoint that has ever been checked", runEndpoints},
+	{"report", "summarize stored checks per endpoint", runReport},
+	{"events", "list recorded startups, shutdowns, config reloads and notifications", runEvents},
+	{"simulate", "replay historical checks through the alerting logic", runSimulate},
+	{"export", "stream stored checks as newline-delimited JSON", runExport},
+	{"vacuum", "compact the database file to reclaim free space", runVacuum},
+	{"purge", "delete everything stored about an endpoint removed from the config", runPurge},
+	{"backfill-rollups", "rebuild the response-time rollups from stored checks", runBackfillRollups},
+	{"export-endpoints", "write the configured endpoints as CSV", runExportEndpoints},
+	{"import-endpoints", "merge endpoints from a CSV file into the config", runImportEndpoints},
+	{"watch", "show a live table of endpoint statuses from the running daemon", runWatch},
+	{"mute", "suppress notifications on the running daemon", runMute},
+	{"unmute", "resume notifications on the running daemon", runUnmute},
+	{"enable", "start monitoring an endpoint on the running daemon, across restarts", runEnable},
+	{"disable", "stop monitoring an endpoint on the running daemon, across restarts", runDisable},
+	{"ack", "acknowledge an endpoint's incident on the running daemon, pausing escalation", runAck},
+	{"relax", "loosen an endpoint's timeout and thresholds on the running daemon for a while", runRelax},
+	{"validate", "check a config file without starting monitoring", runValidate},
+	{"doctor", "check the config, database, log path, network and notifiers", runDoctor},
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	runDaemon()
+}
+
+// runCommand dispatches a CLI subcommand and returns the process exit code
+func runCommand(name string, args []string) int {
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "monitord %s: %v\n", name, err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "monitord: unknown command %q\n", name)
+	usage()
+	return 2
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: monitord [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command, monitord runs the monitoring daemon.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// runDaemon runs the monitoring service until a shutdown signal is received
+func runDaemon() {
 	// Initialize logger
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 
@@ -21,6 +93,20 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Failed to load config: %v", err)
 	}
+	if cfg.Logging.Output == config.LogOutputFile {
+		logFile, err := logfile.Open(
+			cfg.Logging.Path,
+			cfg.Logging.Mode(),
+			int64(cfg.Logging.MaxSizeMB)<<20,
+			cfg.Logging.MaxBackups,
+			time.Duration(cfg.Logging.MaxAgeDays)*24*time.Hour,
+		)
+		if err != nil {
+			logger.Fatalf("Failed to open log file: %v", err)
+		}
+		defer logFile.Close()
+		logger.SetOutput(logFile)
+	}
 
 	// Log the initial configuration
 	logger.Printf("Initial configuration loaded:\n")
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// runMute asks the running daemon to suppress all notifications
+func runMute(args []string) error {
+	fs := flag.NewFlagSet("mute", flag.ContinueOnError)
+	all := fs.Bool("all", false, "mute notifications for every endpoint")
+	duration := fs.Duration("for", 0, "how long to mute notifications, e.g. 2h")
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !*all {
+		return errors.New("only --all is supported: monitord mute --all --for 2h")
+	}
+	if *duration <= 0 {
+		return errors.New("--for must be a positive duration such as 2h")
+	}
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		MutedUntil time.Time `json:"muted_until"`
+	}
+	query := url.Values{"for": {duration.String()}}
+	if err := client.do(http.MethodPost, "/mute?"+query.Encode(), &result); err != nil {
+		return err
+	}
+
+	fmt.Printf("All notifications muted until %s\n", result.MutedUntil.Local().Format(time.RFC1123))
+	return nil
+}
+
+// runUnmute ends a mute early
+func runUnmute(args []string) error {
+	fs := flag.NewFlagSet("unmute", flag.ContinueOnError)
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+	if err := client.do(http.MethodDelete, "/mute", nil); err != nil {
+		return err
+	}
+
+	fmt.Println("Notifications unmuted")
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// writeJSON prints v as indented JSON, the output of commands run with
+// --json. The shapes written are documented in the README and only change
+// by adding fields.
+func writeJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
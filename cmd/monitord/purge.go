@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// runPurge deletes everything stored about an endpoint. Endpoints still in
+// the config are refused without --force, since the daemon would go on
+// storing their checks.
+func runPurge(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	force := fs.Bool("force", false, "purge the endpoint even though it is still in the config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one endpoint URL: monitord purge [--force] URL")
+	}
+	url := fs.Arg(0)
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if !*force && slices.ContainsFunc(cfg.Monitor.Endpoints, func(e config.Endpoint) bool { return e.URL == url }) {
+		return fmt.Errorf("%s is still in the config; remove it first or use --force", url)
+	}
+	records, err := store.ListEndpoints()
+	if err != nil {
+		return fmt.Errorf("failed to list endpoints: %w", err)
+	}
+	if !slices.ContainsFunc(records, func(r storage.EndpointRecord) bool { return r.URL == url }) {
+		return fmt.Errorf("no data is stored for %s; see monitord endpoints", url)
+	}
+
+	deleted, err := store.DeleteEndpointData(url)
+	if err != nil {
+		return fmt.Errorf("failed to purge %s: %w", url, err)
+	}
+	fmt.Printf("Purged %s: %d checks and its events, rollups and registry entry\n", url, deleted)
+	fmt.Println("Run monitord vacuum to return the space to the filesystem")
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// runRelax relaxes an endpoint's timeout and alert thresholds on the running
+// daemon for a while, such as during a deploy, or ends it with --clear
+func runRelax(args []string) error {
+	fs := flag.NewFlagSet("relax", flag.ContinueOnError)
+	duration := fs.Duration("for", 0, "how long to relax the endpoint, e.g. 5m")
+	clear := fs.Bool("clear", false, "end the relaxation so the endpoint's own settings apply")
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one endpoint URL: monitord relax --for DURATION URL, or monitord relax --clear URL")
+	}
+	endpoint := fs.Arg(0)
+	if !*clear && *duration <= 0 {
+		return errors.New("--for must be a positive duration such as 5m")
+	}
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+
+	path := "/endpoints/" + url.PathEscape(endpoint) + "/relax"
+	if *clear {
+		if err := client.do(http.MethodDelete, path, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s is no longer relaxed\n", endpoint)
+		return nil
+	}
+
+	var relaxation monitor.Relaxation
+	query := url.Values{"for": {duration.String()}}
+	if err := client.do(http.MethodPost, path+"?"+query.Encode(), &relaxation); err != nil {
+		return err
+	}
+	fmt.Printf("%s relaxed until %s: %s\n", endpoint, relaxation.Until.Local().Format(time.RFC1123), relaxation)
+	return nil
+}
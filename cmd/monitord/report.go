@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// reportJSON is an endpoint and probe in the --json output of report
+type reportJSON struct {
+	URL           string  `json:"url"`
+	Name          string  `json:"name"`
+	Probe         string  `json:"probe"`
+	Checks        int     `json:"checks"`
+	Up            int     `json:"up"`
+	UptimePercent float64 `json:"uptime_percent"`
+	// AvgResponseTime and AvgResponseTimeMicros are null when no check
+	// received a response
+	AvgResponseTime       *float64 `json:"avg_response_time_ms"`
+	AvgResponseTimeMicros *float64 `json:"avg_response_time_us"`
+}
+
+// percentilesJSON is an endpoint and probe in the --json output of report
+// --percentiles. Times are in milliseconds.
+type percentilesJSON struct {
+	URL       string  `json:"url"`
+	Probe     string  `json:"probe"`
+	Responses int64   `json:"responses"`
+	Min       int64   `json:"min_ms"`
+	Mean      float64 `json:"mean_ms"`
+	P50       float64 `json:"p50_ms"`
+	P90       float64 `json:"p90_ms"`
+	P95       float64 `json:"p95_ms"`
+	P99       float64 `json:"p99_ms"`
+	Max       int64   `json:"max_ms"`
+}
+
+// runReport summarizes stored checks per endpoint and probe
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	since := fs.String("since", "24h", "start of the report window (duration ago or RFC 3339 time)")
+	until := fs.String("until", "", "end of the report window (duration ago or RFC 3339 time)")
+	url := fs.String("url", "", "only report this endpoint URL")
+	tag := fs.String("tag", "", "only report checks with this tag")
+	probe := fs.String("probe", "", "only report checks run by this probe")
+	percentiles := fs.Bool("percentiles", false, "report response-time percentiles from the rollups")
+	asJSON := fs.Bool("json", false, "print the report as a JSON array")
+	unit := fs.String("unit", unitMillis, "response-time unit: ms or us")
+	db := fs.String("db", "", dbUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *percentiles && *tag != "" {
+		return errors.New("--tag cannot be used with --percentiles; rollups are not kept per tag")
+	}
+	if err := checkUnit(*unit); err != nil {
+		return err
+	}
+	if *percentiles && *unit != unitMillis {
+		return errors.New("--unit us cannot be used with --percentiles; rollups are kept in milliseconds")
+	}
+
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	store, err := openHistory(*db)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if *percentiles {
+		return reportPercentiles(store, storage.StatsFilter{
+			URL:   *url,
+			Probe: *probe,
+			Since: sinceTime,
+			Until: untilTime,
+		}, *asJSON)
+	}
+
+	summaries, err := store.SummarizeChecks(storage.CheckFilter{
+		URL:   *url,
+		Tag:   *tag,
+		Probe: *probe,
+		Since: sinceTime,
+		Until: untilTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to summarize checks: %w", err)
+	}
+
+	if *asJSON {
+		out := []reportJSON{}
+		for _, s := range summaries {
+			row := reportJSON{
+				URL:           s.URL,
+				Name:          s.Name,
+				Probe:         s.Probe,
+				Checks:        s.Checks,
+				Up:            s.Up,
+				UptimePercent: 100 * float64(s.Up) / float64(s.Checks),
+			}
+			if s.Responses > 0 {
+				avg, micros := s.AvgResponseTime, s.AvgResponseTimeMicros
+				row.AvgResponseTime, row.AvgResponseTimeMicros = &avg, &micros
+			}
+			out = append(out, row)
+		}
+		return writeJSON(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tNAME\tPROBE\tCHECKS\tUPTIME\tAVG RESPONSE")
+	for _, s := range summaries {
+		probe := s.Probe
+		if probe == "" {
+			probe = "-"
+		}
+		avg := "-"
+		if s.Responses > 0 {
+			avg = formatResponseTime(s.AvgResponseTimeMicros, *unit)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.2f%%\t%s\n", s.URL, s.Name, probe, s.Checks,
+			100*float64(s.Up)/float64(s.Checks), avg)
+	}
+	return w.Flush()
+}
+
+// reportPercentiles prints response-time percentiles per endpoint and probe
+func reportPercentiles(store *storage.SQLiteStore, filter storage.StatsFilter, asJSON bool) error {
+	stats, err := store.ResponseTimeStats(filter)
+	if err != nil {
+		return fmt.Errorf("failed to read response-time rollups: %w", err)
+	}
+	if asJSON {
+		out := []percentilesJSON{}
+		for _, st := range stats {
+			out = append(out, percentilesJSON{st.URL, st.Probe, st.Count,
+				st.Min, st.Mean, st.P50, st.P90, st.P95, st.P99, st.Max})
+		}
+		return writeJSON(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tPROBE\tRESPONSES\tMIN\tMEAN\tP50\tP90\tP95\tP99\tMAX")
+	for _, st := range stats {
+		probe := st.Probe
+		if probe == "" {
+			probe = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%dms\t%.0fms\t%.0fms\t%.0fms\t%.0fms\t%.0fms\t%dms\n", st.URL, probe, st.Count,
+			st.Min, st.Mean, st.P50, st.P90, st.P95, st.P99, st.Max)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// runBackfillRollups rebuilds the response-time rollups from stored checks,
+// e.g. for checks saved before rollups existed
+func runBackfillRollups(args []string) error {
+	fs := flag.NewFlagSet("backfill-rollups", flag.ContinueOnError)
+	since := fs.String("since", "", "only rebuild rollups from this day on (duration ago or RFC 3339 time; default all checks)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	counted, err := store.RebuildRollups(sinceTime)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild rollups: %w", err)
+	}
+	fmt.Printf("Rebuilt rollups from %d checks in %s\n", counted, time.Since(start).Round(time.Millisecond))
+	return nil
+}
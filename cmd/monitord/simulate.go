@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// simulationJSON is the --json output of simulate
+type simulationJSON struct {
+	Endpoints   []simulatedEndpointJSON `json:"endpoints"`
+	TotalAlerts int                     `json:"total_alerts"`
+}
+
+type simulatedEndpointJSON struct {
+	URL               string          `json:"url"`
+	Name              string          `json:"name"`
+	Checks            int             `json:"checks"`
+	FailureThreshold  int             `json:"failure_threshold"`
+	DegradedThreshold int             `json:"degraded_threshold"`
+	Alerts            []simulatedJSON `json:"alerts"`
+}
+
+// simulatedJSON is an alert that would have fired. Previous is empty for
+// the first status established.
+type simulatedJSON struct {
+	Timestamp time.Time `json:"timestamp"`
+	Previous  string    `json:"previous"`
+	Current   string    `json:"current"`
+	// Duration is how long the previous status lasted, in seconds
+	Duration float64 `json:"duration_seconds"`
+}
+
+// runSimulate replays stored checks through the alert state machine using a
+// candidate configuration and reports the alerts that would have fired
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	since := fs.String("since", "24h", "start of the replay window (duration ago or RFC 3339 time)")
+	until := fs.String("until", "", "end of the replay window (duration ago or RFC 3339 time)")
+	url := fs.String("url", "", "only replay this endpoint URL")
+	candidatePath := fs.String("config", "", "candidate config file providing endpoint thresholds")
+	failureThreshold := fs.Int("failure-threshold", 0, "override the failure threshold for every endpoint")
+	degradedThreshold := fs.Int("degraded-threshold", 0, "override the degraded threshold for every endpoint")
+	asJSON := fs.Bool("json", false, "print the alerts as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sinceTime, err := parseTime(*since)
+	if err != nil {
+		return err
+	}
+	untilTime, err := parseTime(*until)
+	if err != nil {
+		return err
+	}
+
+	cfg, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	candidate := cfg
+	if *candidatePath != "" {
+		if candidate, err = config.LoadFromFile(*candidatePath); err != nil {
+			return fmt.Errorf("failed to load candidate config: %w", err)
+		}
+	}
+
+	endpoints := simulationEndpoints(candidate.Monitor.Endpoints, *url)
+	totalAlerts := 0
+	result := simulationJSON{Endpoints: []simulatedEndpointJSON{}}
+	for _, endpoint := range endpoints {
+		policy := monitor.PolicyFor(endpoint)
+		if *failureThreshold > 0 {
+			policy.FailureThreshold = *failureThreshold
+		}
+		if *degradedThreshold > 0 {
+			policy.DegradedThreshold = *degradedThreshold
+		}
+
+		checks, err := store.QueryChecks(storage.CheckFilter{
+			URL:   endpoint.URL,
+			Since: sinceTime,
+			Until: untilTime,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read checks for %s: %w", endpoint.URL, err)
+		}
+
+		transitions := simulate(checks, policy)
+		totalAlerts += len(transitions)
+
+		if *asJSON {
+			simulated := simulatedEndpointJSON{
+				URL:               endpoint.URL,
+				Name:              endpoint.Name,
+				Checks:            len(checks),
+				FailureThreshold:  policy.FailureThreshold,
+				DegradedThreshold: policy.DegradedThreshold,
+				Alerts:            []simulatedJSON{},
+			}
+			for _, t := range transitions {
+				simulated.Alerts = append(simulated.Alerts, simulatedJSON{
+					t.Check.Timestamp, t.Previous, t.Current, t.Duration.Seconds()})
+			}
+			result.Endpoints = append(result.Endpoints, simulated)
+			continue
+		}
+
+		fmt.Printf("%s (%s)\n", endpoint.URL, endpoint.Name)
+		fmt.Printf("  checks: %d, failure threshold: %d, degraded threshold: %d\n",
+			len(checks), policy.FailureThreshold, policy.DegradedThreshold)
+		for _, t := range transitions {
+			previous := t.Previous
+			if previous == "" {
+				previous = "UNKNOWN"
+			}
+			fmt.Printf("  %s  %s -> %s (after %s)\n",
+				t.Check.Timestamp.Format("2006-01-02 15:04:05"), previous, t.Current, t.Duration.Round(time.Second))
+		}
+		fmt.Printf("  alerts: %d\n\n", len(transitions))
+	}
+
+	if *asJSON {
+		result.TotalAlerts = totalAlerts
+		return writeJSON(result)
+	}
+	fmt.Printf("Total alerts: %d across %d endpoints\n", totalAlerts, len(endpoints))
+	return nil
+}
+
+// simulate runs checks through the alert state machine in order and returns
+// the transitions that would have been notified
+func simulate(checks []monitor.HealthCheck, policy monitor.AlertPolicy) []monitor.Transition {
+	var (
+		state       monitor.AlertState
+		transitions []monitor.Transition
+	)
+	for _, check := range checks {
+		var t *monitor.Transition
+		state, t = monitor.Evaluate(state, check, policy)
+		if t != nil && policy.Notifies(*t) {
+			transitions = append(transitions, *t)
+		}
+	}
+	return transitions
+}
+
+// simulationEndpoints selects the endpoints to replay. A URL missing from the
+// candidate config is replayed with default thresholds.
+func simulationEndpoints(endpoints []config.Endpoint, url string) []config.Endpoint {
+	if url == "" {
+		return endpoints
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.URL == url {
+			return []config.Endpoint{endpoint}
+		}
+	}
+	return []config.Endpoint{{URL: url}}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// runStatus prints the confirmed status of every endpoint from a running
+// daemon
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	asJSON := fs.Bool("json", false, "print the daemon's GET /status response")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+	var status monitor.Status
+	if err := client.do(http.MethodGet, "/status", &status); err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(status)
+	}
+
+	if status.MutedUntil != nil {
+		fmt.Printf("Notifications muted until %s\n\n", status.MutedUntil.Local().Format("2006-01-02 15:04:05"))
+	}
+	if status.ConnectivityLost {
+		fmt.Print("Connectivity lost: every canary is down\n\n")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tNAME\tSTATUS\tLAST CHECK\tLAST STATUS")
+	for _, endpoint := range status.Endpoints {
+		lastCheck, lastStatus := "never", "-"
+		if endpoint.LastCheck != nil {
+			lastCheck = endpoint.LastCheck.Local().Format("2006-01-02 15:04:05")
+			lastStatus = endpoint.LastStatus
+		}
+		if endpoint.Stale {
+			lastCheck += " (stale)"
+		}
+		status := endpoint.Status
+		if endpoint.EnabledSource == monitor.EnabledByOperator {
+			status += " (operator)"
+		}
+		if ack := endpoint.Acknowledgement; ack != nil {
+			status += " (acknowledged by " + ack.By + ")"
+		}
+		if relaxation := endpoint.Relaxation; relaxation != nil {
+			status += " (relaxed until " + relaxation.Until.Local().Format("15:04:05") + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", endpoint.URL, endpoint.Name, status, lastCheck, lastStatus)
+	}
+	return w.Flush()
+}
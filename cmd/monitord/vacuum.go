@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// runVacuum compacts the database file, reclaiming the space of deleted rows
+func runVacuum(args []string) error {
+	fs := flag.NewFlagSet("vacuum", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	start := time.Now()
+	reclaimed, err := store.Vacuum()
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	fmt.Printf("Vacuumed %s in %s, reclaimed %d bytes\n", store.Path(),
+		time.Since(start).Round(time.Millisecond), reclaimed)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// validationResult is the --json output of validate
+type validationResult struct {
+	Path      string   `json:"path"`
+	Valid     bool     `json:"valid"`
+	Endpoints int      `json:"endpoints"`
+	Errors    []string `json:"errors"`
+}
+
+// runValidate checks a config file, the default one unless a path is given,
+// without starting monitoring
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the result as JSON")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return errors.New("usage: monitord validate [--json] [config file]")
+	}
+
+	result := validationResult{Errors: []string{}}
+	var cfg *config.Config
+	if len(positional) == 1 {
+		result.Path = positional[0]
+		cfg, err = config.LoadFromFile(result.Path)
+	} else {
+		cfg, result.Path, err = doctorLoadConfig()
+	}
+	if err == nil {
+		result.Valid = true
+		result.Endpoints = len(cfg.Monitor.Endpoints)
+	} else {
+		result.Errors = errorLines(err)
+	}
+
+	if *asJSON {
+		if err := writeJSON(result); err != nil {
+			return err
+		}
+	} else if result.Valid {
+		fmt.Printf("%s is valid, %d endpoints\n", result.Path, result.Endpoints)
+	}
+	// The loader has already printed the problems
+	if !result.Valid {
+		return fmt.Errorf("%s is not valid", result.Path)
+	}
+	return nil
+}
+
+// errorLines splits an error joined from several problems into one message
+// per problem
+func errorLines(err error) []string {
+	var lines []string
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			lines = append(lines, errorLines(e)...)
+		}
+		return lines
+	}
+	return []string{err.Error()}
+}
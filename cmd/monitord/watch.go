@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/api"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Watch display settings
+const (
+	watchRedrawInterval = 250 * time.Millisecond
+	watchReconnectDelay = 2 * time.Second
+	watchHighlight      = 10 * time.Second
+	watchTransitions    = 10
+	watchStatusWidth    = 8
+)
+
+// ANSI escape sequences used by the watch display
+const (
+	ansiClear   = "\033[H\033[2J"
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiReverse = "\033[7m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiYellow  = "\033[33m"
+)
+
+// watchRow is the latest check of one endpoint
+type watchRow struct {
+	check   monitor.HealthCheck
+	changed time.Time // when the status last changed, zero if it has not
+}
+
+// watchState is the live view, updated from the event stream
+type watchState struct {
+	mu          sync.Mutex
+	rows        map[string]*watchRow
+	transitions []string
+	connected   bool
+	lastError   string
+	dirty       bool
+}
+
+// runWatch shows a live, continuously updated table of endpoint statuses
+// from a running daemon's event stream
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	addr := fs.String("addr", "", "API address of the running daemon (defaults to the configured address)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, err := newDaemonClient(*addr)
+	if err != nil {
+		return err
+	}
+
+	var status monitor.Status
+	if err := client.do(http.MethodGet, "/status", &status); err != nil {
+		return err
+	}
+	state := &watchState{rows: make(map[string]*watchRow), dirty: true}
+	for _, endpoint := range status.Endpoints {
+		if !endpoint.Enabled {
+			continue
+		}
+		row := &watchRow{check: monitor.HealthCheck{Name: endpoint.Name, URL: endpoint.URL, Status: endpoint.LastStatus}}
+		if endpoint.LastCheck != nil {
+			row.check.Timestamp = *endpoint.LastCheck
+		}
+		state.rows[endpoint.URL] = row
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go state.follow(ctx, client)
+
+	ticker := time.NewTicker(watchRedrawInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			state.draw(client.address)
+		}
+	}
+}
+
+// follow applies checks from the event stream until ctx is done,
+// reconnecting whenever the stream ends
+func (s *watchState) follow(ctx context.Context, client *daemonClient) {
+	for ctx.Err() == nil {
+		err := client.stream(ctx, "/events", func(event string, data []byte) {
+			if event != "check" {
+				return
+			}
+			var check monitor.HealthCheck
+			if err := json.Unmarshal(data, &check); err == nil {
+				s.apply(check)
+			}
+		}, func() { s.setConnected(true, "") })
+
+		if ctx.Err() != nil {
+			return
+		}
+		message := "stream ended"
+		if err != nil {
+			message = err.Error()
+		}
+		s.setConnected(false, message)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchReconnectDelay):
+		}
+	}
+}
+
+// apply records a check, noting a transition when the endpoint's status
+// changed
+func (s *watchState) apply(check monitor.HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row, ok := s.rows[check.URL]
+	if !ok {
+		row = &watchRow{}
+		s.rows[check.URL] = row
+	}
+	previous := row.check.Status
+	row.check = check
+	if previous != "" && previous != check.Status {
+		row.changed = time.Now()
+		s.transitions = append(s.transitions, fmt.Sprintf("%s  %s  %s -> %s",
+			check.Timestamp.Local().Format("15:04:05"), check.URL, previous, check.Status))
+		if len(s.transitions) > watchTransitions {
+			s.transitions = s.transitions[len(s.transitions)-watchTransitions:]
+		}
+	}
+	s.dirty = true
+}
+
+func (s *watchState) setConnected(connected bool, lastError string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected, s.lastError, s.dirty = connected, lastError, true
+}
+
+// draw redraws the screen when the state changed or a highlight may have
+// expired
+func (s *watchState) draw(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	highlighted := false
+	for _, row := range s.rows {
+		if !row.changed.IsZero() && now.Sub(row.changed) < watchHighlight+watchRedrawInterval {
+			highlighted = true
+		}
+	}
+	if !s.dirty && !highlighted {
+		return
+	}
+	s.dirty = false
+
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	connection := ansiGreen + "live" + ansiReset
+	if !s.connected {
+		connection = ansiRed + "disconnected" + ansiReset
+		if s.lastError != "" {
+			connection += " (" + s.lastError + ", retrying)"
+		}
+	}
+	fmt.Fprintf(&b, "%smonitord watch%s  %s  %s  %s\n\n", ansiBold, ansiReset, source, connection, now.Format("15:04:05"))
+
+	urls := make([]string, 0, len(s.rows))
+	for url := range s.rows {
+		urls = append(urls, url)
+	}
+	slices.Sort(urls)
+
+	// The colored status column is added after alignment, since tabwriter
+	// would count its escape codes as text
+	var table strings.Builder
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tCODE\tTIME\tLAST CHECK\tERROR")
+	statuses := []string{fmt.Sprintf("%-*s", watchStatusWidth, "STATUS")}
+	for _, url := range urls {
+		row := s.rows[url]
+		check := row.check
+		status := check.Status
+		if status == "" {
+			status = "UNKNOWN"
+		}
+		lastCheck := "-"
+		if !check.Timestamp.IsZero() {
+			lastCheck = check.Timestamp.Local().Format("15:04:05")
+		}
+		problem := check.Error
+		if problem == "" {
+			problem = check.Reason
+		}
+		statuses = append(statuses, colorStatus(status, now.Sub(row.changed) < watchHighlight))
+		fmt.Fprintf(w, "%s\t%s\t%d\t%dms\t%s\t%s\n",
+			check.Name, url, check.StatusCode, check.ResponseTime, lastCheck, problem)
+	}
+	w.Flush()
+	for i, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		fmt.Fprintf(&b, "%s  %s\n", statuses[i], line)
+	}
+
+	if len(s.transitions) > 0 {
+		fmt.Fprintf(&b, "\n%sRecent transitions%s\n", ansiBold, ansiReset)
+		for i := len(s.transitions) - 1; i >= 0; i-- {
+			fmt.Fprintln(&b, s.transitions[i])
+		}
+	}
+	os.Stdout.WriteString(b.String())
+}
+
+// colorStatus colors a status, in reverse video when it just changed. The
+// status is padded inside the colors so the column stays aligned.
+func colorStatus(status string, highlight bool) string {
+	color := ansiGreen
+	switch status {
+	case monitor.StatusError:
+		color = ansiRed
+	case monitor.StatusDegraded:
+		color = ansiYellow
+	case "UNKNOWN":
+		color = ""
+	}
+	if highlight {
+		color += ansiReverse
+	}
+	return fmt.Sprintf("%s%-*s%s", color, watchStatusWidth, status, ansiReset)
+}
+
+// stream reads a Server-Sent Events stream, calling onEvent for each event
+// until the stream ends or ctx is done. onConnect is called once the daemon
+// accepts the request.
+func (c *daemonClient) stream(ctx context.Context, path string, onEvent func(event string, data []byte), onConnect func()) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	api.SetAuth(req, c.auth)
+
+	// The stream is long-lived, so it must not use the client's timeout
+	resp, err := (&http.Client{Transport: c.client.Transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach monitord: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("monitord responded with %d", resp.StatusCode)
+	}
+	onConnect()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var (
+		event string
+		data  []byte
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data != nil {
+				onEvent(event, data)
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, such as a keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")...)
+		}
+	}
+	return scanner.Err()
+}
@@ -2,4 +2,7 @@ module github.com/will-wright-eng/monitord
 
 go 1.23.3
 
-require github.com/mattn/go-sqlite3 v1.14.24
+require (
+	github.com/mattn/go-sqlite3 v1.14.24
+	golang.org/x/time v0.8.0
+)
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// healthzPath is the liveness check, which auth may exempt
+const healthzPath = "/healthz"
+
+// authenticatedKey is the request context key marking requests that carried
+// valid credentials
+type authenticatedKey struct{}
+
+// RequireAuth rejects requests to next without the configured credentials
+// with 401 Unauthorized. With nil auth, every request is let through.
+func RequireAuth(auth *config.ServerAuth, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized(auth, r) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authenticatedKey{}, true)))
+			return
+		}
+		if auth.ExemptHealthz && r.URL.Path == healthzPath || auth.PublicStatus && isPublicStatus(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.Token != "" {
+			w.Header().Add("WWW-Authenticate", `Bearer realm="monitord"`)
+		}
+		if auth.Username != "" {
+			w.Header().Add("WWW-Authenticate", `Basic realm="monitord"`)
+		}
+		writeError(w, http.StatusUnauthorized, "valid credentials are required")
+	})
+}
+
+// isPublicStatus reports whether a request is for one of the views that
+// public_status opens: GET /status and the status page
+func isPublicStatus(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		(r.URL.Path == "/status" || r.URL.Path == "/")
+}
+
+// authenticated reports whether RequireAuth found valid credentials on a
+// request
+func authenticated(r *http.Request) bool {
+	ok, _ := r.Context().Value(authenticatedKey{}).(bool)
+	return ok
+}
+
+// authorized reports whether a request carries the token or the username
+// and password. Secrets are compared in constant time.
+func authorized(auth *config.ServerAuth, r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && auth.Token != "" {
+		return secretEqual(token, auth.Token)
+	}
+	if username, password, ok := r.BasicAuth(); ok && auth.Username != "" {
+		// Both are compared, so a wrong username takes as long as a wrong
+		// password
+		userOK := secretEqual(username, auth.Username)
+		passwordOK := secretEqual(password, auth.Password)
+		return userOK && passwordOK
+	}
+	return false
+}
+
+// secretEqual compares a given secret with the expected one without leaking
+// how much of it matched
+func secretEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+// SetAuth sets the credentials a request to a monitord server needs
+func SetAuth(req *http.Request, auth *config.ServerAuth) {
+	switch {
+	case auth == nil:
+	case auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+}
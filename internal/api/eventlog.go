@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// defaultEventLogLimit caps the events returned when no limit is given
+const defaultEventLogLimit = 100
+
+// EventLog provides the stored log of monitord's own events
+type EventLog interface {
+	QueryEvents(filter storage.EventFilter) ([]monitor.Event, error)
+}
+
+// handleEventLog returns recorded events, newest first. The type, url,
+// since (RFC 3339) and limit query parameters narrow the result.
+func (s *Server) handleEventLog(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusNotFound, "event log is not available")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := storage.EventFilter{
+		Type:  query.Get("type"),
+		URL:   query.Get("url"),
+		Limit: defaultEventLogLimit,
+	}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "query parameter \"since\" must be an RFC 3339 timestamp")
+			return
+		}
+		filter.Since = t
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "query parameter \"limit\" must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	events, err := s.history.QueryEvents(filter)
+	if errors.Is(err, storage.ErrUnavailable) {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Error reading event log: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read event log")
+		return
+	}
+	if events == nil {
+		events = []monitor.Event{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
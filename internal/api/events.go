@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// eventsKeepAlive is how often an idle event stream sends a comment so
+// proxies do not close the connection
+const eventsKeepAlive = 30 * time.Second
+
+// handleEvents streams each completed check as a Server-Sent Event until the
+// client disconnects or the server shuts down
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// The stream outlives the server's write timeout by design
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Printf("Error clearing write deadline for event stream: %v", err)
+	}
+
+	checks, unsubscribe := s.service.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case check := <-checks:
+			data, err := json.Marshal(check)
+			if err != nil {
+				s.logger.Printf("Error encoding check event: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: check\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
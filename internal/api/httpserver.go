@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Server timeout defaults
+const (
+	defaultReadTimeout  = 10 * time.Second
+	defaultWriteTimeout = 30 * time.Second
+	defaultIdleTimeout  = 2 * time.Minute
+)
+
+// NewHTTPServer creates an HTTP server with the configured timeouts, so a
+// client that sends or reads slowly cannot hold a connection open forever.
+// Long-lived handlers such as the event stream clear their write deadline.
+func NewHTTPServer(addr string, handler http.Handler, timeouts config.ServerTimeouts) *http.Server {
+	read := timeouts.ReadTimeout.ToDuration()
+	if read == 0 {
+		read = defaultReadTimeout
+	}
+	write := timeouts.WriteTimeout.ToDuration()
+	if write == 0 {
+		write = defaultWriteTimeout
+	}
+	idle := timeouts.IdleTimeout.ToDuration()
+	if idle == 0 {
+		idle = defaultIdleTimeout
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: read,
+		ReadTimeout:       read,
+		WriteTimeout:      write,
+		IdleTimeout:       idle,
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// listen opens a listener for an API address, a host and port or "unix:"
+// followed by a socket path
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, config.UnixSocketPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	// A socket left behind by a daemon that did not shut down cleanly would
+	// fail the listen, but one still answering belongs to a running daemon
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is in use by another process", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
+
+// isUnixSocket reports whether an API address is a Unix socket path
+func isUnixSocket(addr string) bool {
+	return strings.HasPrefix(addr, config.UnixSocketPrefix)
+}
@@ -0,0 +1,390 @@
+// Package api serves the HTTP interface used to inspect and control a
+// running monitord.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// DefaultAddress is used when the API is enabled without an address
+const DefaultAddress = "127.0.0.1:8484"
+
+// Server is the API HTTP server
+type Server struct {
+	service         *monitor.Service
+	history         History
+	statusPageTitle string
+	auth            *config.ServerAuth
+	logger          *log.Logger
+	server          *http.Server
+	addresses       []string
+	tlsCertFile     string
+	tlsKeyFile      string
+	storage         StorageHealth
+	reconnects      func() int64
+	// done is closed on shutdown to end long-lived event streams
+	done chan struct{}
+}
+
+// History provides the stored checks and events behind the status page and
+// the event log
+type History interface {
+	Summarizer
+	EventLog
+}
+
+// StorageHealth reports whether monitord has its database, as /healthz
+// shows while it runs without one
+type StorageHealth interface {
+	Available() bool
+	Buffered() int
+}
+
+// New creates an API server for the monitor service. The history supplies
+// uptime for the status page and the event log, and may be nil.
+func New(cfg config.APIConfig, service *monitor.Service, history History, logger *log.Logger) *Server {
+	s := &Server{
+		service:         service,
+		history:         history,
+		statusPageTitle: cfg.StatusPageTitle,
+		auth:            cfg.Auth,
+		logger:          logger,
+		addresses:       append([]string{Address(cfg)}, cfg.Addresses...),
+		tlsCertFile:     cfg.TLSCertFile,
+		tlsKeyFile:      cfg.TLSKeyFile,
+		done:            make(chan struct{}),
+	}
+	if s.statusPageTitle == "" {
+		s.statusPageTitle = defaultStatusPageTitle
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+healthzPath, s.handleHealthz)
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /config", s.handleConfig)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /event-log", s.handleEventLog)
+	mux.HandleFunc("POST /endpoints/{url}/check", s.handleCheck)
+	mux.HandleFunc("POST /endpoints/{url}/report", s.handleReport)
+	mux.HandleFunc("GET /endpoints/{url}/body", s.handleBody)
+	mux.HandleFunc("POST /endpoints/{url}/enable", s.handleSetEnabled(true))
+	mux.HandleFunc("POST /endpoints/{url}/disable", s.handleSetEnabled(false))
+	mux.HandleFunc("DELETE /endpoints/{url}/override", s.handleClearOverride)
+	mux.HandleFunc("POST /endpoints/{url}/acknowledge", s.handleAcknowledge)
+	mux.HandleFunc("DELETE /endpoints/{url}/acknowledge", s.handleUnacknowledge)
+	mux.HandleFunc("POST /endpoints/{url}/relax", s.handleRelax)
+	mux.HandleFunc("DELETE /endpoints/{url}/relax", s.handleUnrelax)
+	mux.HandleFunc("POST /mute", s.handleMute)
+	mux.HandleFunc("DELETE /mute", s.handleUnmute)
+	if cfg.StatusPage {
+		mux.HandleFunc("GET /{$}", s.handleStatusPage)
+	}
+
+	s.server = NewHTTPServer(Address(cfg), RequireAuth(cfg.Auth, mux), cfg.ServerTimeouts)
+	return s
+}
+
+// Address returns the main address the API listens on, which commands use to
+// reach the daemon
+func Address(cfg config.APIConfig) string {
+	if cfg.Address == "" {
+		return DefaultAddress
+	}
+	return cfg.Address
+}
+
+// ListenAndServe serves the API on every address until Shutdown is called.
+// When any address cannot be served, the others are closed too.
+func (s *Server) ListenAndServe() error {
+	var listeners []net.Listener
+	for _, addr := range s.addresses {
+		l, err := listen(addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+
+	errs := make(chan error, len(listeners))
+	for i, l := range listeners {
+		go func() { errs <- s.serve(l, s.addresses[i]) }()
+	}
+	var first error
+	for range listeners {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			s.server.Close()
+		}
+	}
+	return first
+}
+
+// serve serves the API on one listener, over TLS when a key pair is
+// configured and the address is not a Unix socket
+func (s *Server) serve(l net.Listener, addr string) error {
+	var err error
+	switch {
+	case isUnixSocket(addr):
+		s.logger.Printf("Serving API on %s", addr)
+		err = s.server.Serve(l)
+	case s.tlsCertFile != "":
+		s.logger.Printf("Serving API on https://%s", addr)
+		err = s.server.ServeTLS(l, s.tlsCertFile, s.tlsKeyFile)
+	default:
+		s.logger.Printf("Serving API on http://%s", addr)
+		err = s.server.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the server, waiting for active requests to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.done)
+	return s.server.Shutdown(ctx)
+}
+
+// SetStorageHealth makes /healthz report the daemon as degraded while the
+// database is unavailable. Call it before serving.
+func (s *Server) SetStorageHealth(storage StorageHealth) {
+	s.storage = storage
+}
+
+// SetReconnects makes /healthz report how many times the database has been
+// reopened after its connection broke, as counted by fn. Call it before
+// serving.
+func (s *Server) SetReconnects(fn func() int64) {
+	s.reconnects = fn
+}
+
+// handleHealthz reports that the daemon is up and serving, for probes that
+// need no details. Running without its database still answers 200, with a
+// degraded status, since restarting monitord would not bring the database
+// back.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	health := map[string]interface{}{"status": "ok"}
+	if s.storage != nil && !s.storage.Available() {
+		health["status"] = "degraded"
+		health["storage"] = "unavailable"
+		health["buffered"] = s.storage.Buffered()
+	}
+	if s.reconnects != nil {
+		health["database_reconnects"] = s.reconnects()
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status(r))
+}
+
+// status returns the service's status as the request may see it: private
+// endpoints are left out unless the API needs no credentials or the request
+// carried them
+func (s *Server) status(r *http.Request) monitor.Status {
+	status := s.service.Status()
+	if s.auth == nil || authenticated(r) {
+		return status
+	}
+	return status.Public()
+}
+
+// handleConfig returns the configuration the daemon is running, with
+// secrets redacted
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.service.Config().Redacted())
+}
+
+// handleCheck runs an immediate check of the endpoint whose URL is given,
+// escaped, in the path. With save=true the result is stored and alerted on
+// like a scheduled check.
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	record, _ := strconv.ParseBool(r.URL.Query().Get("save"))
+	check, err := s.service.CheckNow(r.Context(), r.PathValue("url"), record)
+	switch {
+	case errors.Is(err, monitor.ErrUnknownEndpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitor.ErrPassive):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, check)
+	}
+}
+
+// handleReport records a check of a passive endpoint run elsewhere. The body
+// is an optional CheckReport; an empty one reports the endpoint UP.
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	var report monitor.CheckReport
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&report); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid report: "+err.Error())
+		return
+	}
+	check, err := s.service.ReportCheck(r.PathValue("url"), report)
+	switch {
+	case errors.Is(err, monitor.ErrUnknownEndpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitor.ErrNotPassive):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, check)
+	}
+}
+
+// handleBody returns the last response body captured for an endpoint with
+// capture_body enabled
+func (s *Server) handleBody(w http.ResponseWriter, r *http.Request) {
+	body, err := s.service.LastBody(r.PathValue("url"))
+	switch {
+	case errors.Is(err, monitor.ErrUnknownEndpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	case body == nil:
+		writeError(w, http.StatusNotFound, "no response body captured; enable capture_body for the endpoint")
+	default:
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// handleSetEnabled starts or stops monitoring the endpoint whose URL is
+// given, escaped, in the path, overriding its enabled setting
+func (s *Server) handleSetEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.writeOverrideResult(w, s.service.SetEnabled(r.PathValue("url"), enabled))
+	}
+}
+
+// handleClearOverride makes an endpoint follow its enabled setting again
+func (s *Server) handleClearOverride(w http.ResponseWriter, r *http.Request) {
+	s.writeOverrideResult(w, s.service.ClearOverride(r.PathValue("url")))
+}
+
+func (s *Server) writeOverrideResult(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, monitor.ErrUnknownEndpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// acknowledgeRequest is the body of an acknowledgement
+type acknowledgeRequest struct {
+	By   string `json:"by"`
+	Note string `json:"note"`
+	// For is how long escalation stays paused, such as "2h"; empty waits
+	// for recovery
+	For string `json:"for"`
+}
+
+// handleAcknowledge acknowledges the incident of the endpoint whose URL is
+// given, escaped, in the path, pausing its escalation
+func (s *Server) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	var req acknowledgeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid acknowledgement: "+err.Error())
+		return
+	}
+	if req.By == "" {
+		writeError(w, http.StatusBadRequest, "\"by\" is required")
+		return
+	}
+	var d time.Duration
+	if req.For != "" {
+		var err error
+		if d, err = time.ParseDuration(req.For); err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "\"for\" must be a positive duration such as 2h")
+			return
+		}
+	}
+
+	ack, err := s.service.Acknowledge(r.PathValue("url"), req.By, req.Note, d)
+	switch {
+	case errors.Is(err, monitor.ErrUnknownEndpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, monitor.ErrNotDown):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, ack)
+	}
+}
+
+// handleUnacknowledge clears an endpoint's acknowledgement, resuming its
+// escalation
+func (s *Server) handleUnacknowledge(w http.ResponseWriter, r *http.Request) {
+	s.writeOverrideResult(w, s.service.Unacknowledge(r.PathValue("url")))
+}
+
+// handleRelax relaxes the endpoint whose URL is given, escaped, in the path
+// for the duration in the "for" query parameter
+func (s *Server) handleRelax(w http.ResponseWriter, r *http.Request) {
+	d, err := time.ParseDuration(r.URL.Query().Get("for"))
+	if err != nil || d <= 0 {
+		writeError(w, http.StatusBadRequest, "query parameter \"for\" must be a positive duration such as 5m")
+		return
+	}
+
+	relaxation, err := s.service.Relax(r.PathValue("url"), d)
+	if err != nil {
+		s.writeOverrideResult(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, relaxation)
+}
+
+// handleUnrelax ends an endpoint's relaxation early
+func (s *Server) handleUnrelax(w http.ResponseWriter, r *http.Request) {
+	s.writeOverrideResult(w, s.service.Unrelax(r.PathValue("url")))
+}
+
+// handleMute mutes all notifications for the duration in the "for" query
+// parameter
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	d, err := time.ParseDuration(r.URL.Query().Get("for"))
+	if err != nil || d <= 0 {
+		writeError(w, http.StatusBadRequest, "query parameter \"for\" must be a positive duration such as 2h")
+		return
+	}
+
+	until := s.service.Mute(d)
+	writeJSON(w, http.StatusOK, map[string]time.Time{"muted_until": until})
+}
+
+func (s *Server) handleUnmute(w http.ResponseWriter, r *http.Request) {
+	s.service.Unmute()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
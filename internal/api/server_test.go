@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// discardStorage drops every check and event
+type discardStorage struct{}
+
+func (discardStorage) SaveCheck(monitor.HealthCheck) error { return nil }
+func (discardStorage) SaveEvent(monitor.Event) error       { return nil }
+func (discardStorage) Close() error                        { return nil }
+
+func TestConfigRedactsHeaders(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	secrets := []string{"global-secret", "endpoint-secret", "endpoint-body-secret", "login-secret", "login-body-secret", "step-secret", "step-body-secret"}
+	cfg := config.Config{Monitor: config.MonitorConfig{
+		ConfigCheck:   config.Duration(time.Hour),
+		GlobalHeaders: map[string]string{"Authorization": "Bearer global-secret"},
+		Endpoints: []config.Endpoint{{
+			Name:     "api",
+			URL:      target.URL,
+			Interval: config.Duration(time.Hour),
+			Headers:  map[string]string{"X-Api-Key": "endpoint-secret"},
+			Method:   http.MethodPost,
+			Body:     `{"token": "endpoint-body-secret"}`,
+			PreRequest: &config.PreRequest{
+				URL:     target.URL + "/login",
+				Body:    `{"password": "login-body-secret"}`,
+				Headers: map[string]string{"X-Login": "login-secret"},
+			},
+		}, {
+			Name:     "flow",
+			URL:      target.URL + "/flow",
+			Interval: config.Duration(time.Hour),
+			Steps: []config.TransactionStep{{
+				Path:    "/step",
+				Body:    "step-body-secret",
+				Headers: map[string]string{"X-Step": "step-secret"},
+			}},
+		}},
+	}}
+	logger := log.New(io.Discard, "", 0)
+	service := monitor.NewService(discardStorage{}, nil, nil, logger, cfg, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() {
+		cancel()
+		service.Shutdown(context.Background())
+	}()
+
+	server := New(config.APIConfig{}, service, nil, logger)
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /config = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, secret := range secrets {
+		if strings.Contains(body, secret) {
+			t.Errorf("GET /config shows %q:\n%s", secret, body)
+		}
+	}
+	if !strings.Contains(body, "Authorization") {
+		t.Errorf("GET /config should keep header names:\n%s", body)
+	}
+
+	// The running config is left untouched
+	if got := service.Config().Monitor.GlobalHeaders["Authorization"]; got != "Bearer global-secret" {
+		t.Errorf("global header changed to %q", got)
+	}
+}
+
+func TestCheckReportsFailure(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	cfg := config.Config{Monitor: config.MonitorConfig{
+		ConfigCheck: config.Duration(time.Hour),
+		Endpoints:   []config.Endpoint{{Name: "api", URL: target.URL, Enabled: true, Interval: config.Duration(time.Hour)}},
+	}}
+	logger := log.New(io.Discard, "", 0)
+	service := monitor.NewService(discardStorage{}, nil, nil, logger, cfg, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() {
+		cancel()
+		service.Shutdown(context.Background())
+	}()
+	server := New(config.APIConfig{}, service, nil, logger)
+	path := "/endpoints/" + url.PathEscape(target.URL) + "/check"
+
+	rec := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST %s = %d: %s", path, rec.Code, rec.Body)
+	}
+
+	// A check cut short by the request going away is not a result
+	canceled, stop := context.WithCancel(context.Background())
+	stop()
+	rec = httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil).WithContext(canceled))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("POST %s with the request canceled = %d, want %d: %s", path, rec.Code, http.StatusInternalServerError, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), context.Canceled.Error()) {
+		t.Errorf("error body %q does not give the error", rec.Body)
+	}
+}
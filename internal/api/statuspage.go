@@ -0,0 +1,158 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/storage"
+)
+
+// Status page settings
+const (
+	defaultStatusPageTitle = "monitord status"
+	uptimeWindow           = 24 * time.Hour
+)
+
+// Summarizer provides the stored check history behind the status page
+type Summarizer interface {
+	SummarizeChecks(filter storage.CheckFilter) ([]storage.CheckSummary, error)
+}
+
+// statusPageRow is one endpoint on the status page
+type statusPageRow struct {
+	Name      string
+	URL       string
+	Status    string
+	Class     string
+	Note      string
+	LastCheck string
+	Uptime    string
+}
+
+// statusPageTemplate is self-contained so the page works without any
+// external assets
+var statusPageTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.5rem 0.75rem; border-bottom: 1px solid #ddd; }
+th { background: #f5f5f5; }
+.status { font-weight: bold; border-radius: 4px; padding: 0.15rem 0.5rem; color: #fff; }
+.up { background: #2e7d32; }
+.degraded { background: #ef6c00; }
+.error { background: #c62828; }
+.unknown { background: #757575; }
+.muted { background: #fff3e0; padding: 0.5rem 0.75rem; margin-bottom: 1rem; }
+footer { margin-top: 1rem; color: #757575; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .MutedUntil}}<p class="muted">Notifications are muted until {{.MutedUntil}}.</p>{{end}}
+<table>
+<tr><th>Endpoint</th><th>Status</th><th>Last check</th><th>Uptime (24h)</th></tr>
+{{range .Rows}}<tr>
+<td>{{.Name}}<br><small>{{.URL}}</small></td>
+<td><span class="status {{.Class}}">{{.Status}}</span>{{if .Note}}<br><small>{{.Note}}</small>{{end}}</td>
+<td>{{.LastCheck}}</td>
+<td>{{.Uptime}}</td>
+</tr>
+{{else}}<tr><td colspan="4">No endpoints are being monitored.</td></tr>
+{{end}}</table>
+<footer>Updated {{.Updated}}. This page refreshes every 30 seconds.</footer>
+</body>
+</html>
+`))
+
+// handleStatusPage renders the current status of every endpoint as HTML
+func (s *Server) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	status := s.status(r)
+
+	checks := make(map[string]int)
+	ups := make(map[string]int)
+	if s.history != nil {
+		summaries, err := s.history.SummarizeChecks(storage.CheckFilter{Since: time.Now().Add(-uptimeWindow)})
+		if err != nil {
+			s.logger.Printf("Error reading uptime for status page: %v", err)
+		}
+		// Combine the rows of every probe for each URL
+		for _, summary := range summaries {
+			checks[summary.URL] += summary.Checks
+			ups[summary.URL] += summary.Up
+		}
+	}
+
+	const layout = "2006-01-02 15:04:05"
+	data := struct {
+		Title      string
+		MutedUntil string
+		Updated    string
+		Rows       []statusPageRow
+	}{
+		Title:   s.statusPageTitle,
+		Updated: time.Now().Format(layout),
+	}
+	if status.MutedUntil != nil {
+		data.MutedUntil = status.MutedUntil.Format(layout)
+	}
+
+	for _, endpoint := range status.Endpoints {
+		if !endpoint.Enabled {
+			continue
+		}
+		row := statusPageRow{
+			Name:      endpoint.Name,
+			URL:       endpoint.URL,
+			Status:    endpoint.Status,
+			Class:     statusClass(endpoint.Status),
+			LastCheck: "never",
+			Uptime:    "-",
+		}
+		if endpoint.LastCheck != nil {
+			row.LastCheck = endpoint.LastCheck.Format(layout)
+		}
+		if endpoint.Stale {
+			row.LastCheck += " (stale)"
+		}
+		if ack := endpoint.Acknowledgement; ack != nil {
+			row.Note = "Acknowledged by " + ack.By
+			if ack.Note != "" {
+				row.Note += ": " + ack.Note
+			}
+		}
+		if n := checks[endpoint.URL]; n > 0 {
+			row.Uptime = formatPercent(ups[endpoint.URL], n)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusPageTemplate.Execute(w, data); err != nil {
+		s.logger.Printf("Error rendering status page: %v", err)
+	}
+}
+
+// statusClass maps a status to its CSS class
+func statusClass(status string) string {
+	switch status {
+	case monitor.StatusUp:
+		return "up"
+	case monitor.StatusDegraded:
+		return "degraded"
+	case monitor.StatusError:
+		return "error"
+	}
+	return "unknown"
+}
+
+func formatPercent(part, total int) string {
+	return strconv.FormatFloat(100*float64(part)/float64(total), 'f', 2, 64) + "%"
+}
@@ -2,69 +2,555 @@ package app
 
 import (
     "context"
+    "errors"
     "fmt"
     "log"
+    "net/http"
+    "os/exec"
+    "runtime"
     "sync"
+    "sync/atomic"
+    "time"
 
+    "github.com/will-wright-eng/monitord/internal/api"
     "github.com/will-wright-eng/monitord/internal/config"
+    "github.com/will-wright-eng/monitord/internal/metrics"
     "github.com/will-wright-eng/monitord/internal/monitor"
+    "github.com/will-wright-eng/monitord/internal/notify"
     "github.com/will-wright-eng/monitord/internal/storage"
 )
 
+// defaultMetricsAddress is used when metrics are enabled without an address
+const defaultMetricsAddress = "127.0.0.1:9464"
+
+// purgeCheckInterval is how often endpoints missing from the config are
+// looked for when purging them is enabled
+const purgeCheckInterval = time.Hour
+
+// Bounds of the backoff between attempts to open a database that was
+// unavailable at startup
+const (
+    minStorageRetry = time.Second
+    maxStorageRetry = time.Minute
+)
+
 // App represents the main application
 type App struct {
-    cfg     *config.Config
-    monitor *monitor.Service
-    storage storage.Storage
-    logger  *log.Logger
-    wg      sync.WaitGroup
+    cfg           *config.Config
+    monitor       *monitor.Service
+    dispatcher    *notify.Dispatcher
+    metricsServer *http.Server
+    apiServer     *api.Server
+    // storage is nil while running without the database; pending then
+    // holds what is written until it opens
+    storage       atomic.Pointer[storage.SQLiteStore]
+    pending       *storage.PendingStore
+    backends      []*storage.SQLiteStore
+    // changeOnly is the store_on_change_only decorator, if enabled
+    changeOnly    *storage.ChangeOnlyStore
+    logger        *log.Logger
+    cancel        context.CancelFunc
+    wg            sync.WaitGroup
 }
 
-// New creates a new application instance
+// New creates a new application instance that reloads its configuration from
+// the default config file
 func New(cfg *config.Config, logger *log.Logger) (*App, error) {
-    store, err := storage.NewSQLiteStore(cfg.Database.Path)
+    return NewWithReload(cfg, logger, config.Load)
+}
+
+// NewWithReload creates an application instance that calls reloadFn on
+// every config check to pick up configuration changes
+func NewWithReload(cfg *config.Config, logger *log.Logger, reloadFn func() (*config.Config, error)) (*App, error) {
+    store, err := storage.NewSQLiteStore(cfg.Database.Path, cfg.Database.Mode())
+    var pending *storage.PendingStore
     if err != nil {
+        if !cfg.Database.StartWithoutStorage {
+            return nil, err
+        }
+        logger.Printf("WARN Database %s is unavailable, starting without storage: %v", cfg.Database.Path, err)
+        logger.Printf("WARN Up to %d checks, events and notifications each are kept in memory until it opens",
+            cfg.Database.StartupBufferSize())
+        store, pending = nil, storage.NewPendingStore(cfg.Database.StartupBufferSize())
+    } else {
+        store.SetCompression(cfg.Database.CompressOver)
+    }
+    backends, err := openBackends(cfg.Database)
+    if err != nil {
+        if store != nil {
+            store.Close()
+        }
         return nil, err
     }
+    closeStores := func() {
+        if store != nil {
+            store.Close()
+        }
+        for _, backend := range backends {
+            backend.Close()
+        }
+    }
+
+    a := &App{cfg: cfg, pending: pending, backends: backends, logger: logger}
+    // Without the database everything goes to the pending store, which
+    // hands it on once the database opens
+    var (
+        queue   notify.Queue
+        primary storage.Storage
+        history api.History
+    )
+    if store != nil {
+        a.storage.Store(store)
+        queue, primary, history = store, a.withBackends(store), store
+    } else {
+        queue, primary, history = pending, pending, pending
+    }
 
-    // Create reload function
-    reloadFn := func() (*config.Config, error) {
-        return config.Load()
+    dispatcher, err := notify.NewDispatcher(cfg.Notifications, queue, logger)
+    if err != nil {
+        closeStores()
+        return nil, fmt.Errorf("failed to configure notifications: %w", err)
     }
 
-    monitorService := monitor.NewService(
-        store,
+    var (
+        registry       *metrics.Registry
+        monitorMetrics *monitor.Metrics
+        metricsServer  *http.Server
+    )
+    if cfg.Metrics.Enabled {
+        registry = metrics.NewRegistry()
+        monitorMetrics = monitor.NewMetrics(registry, cfg.Metrics)
+        registry.NewGaugeFunc("monitord_notifications_pending",
+            "Notifications waiting to be delivered.", func() float64 {
+                pending, _ := dispatcher.Pending()
+                return float64(pending)
+            })
+        metricsServer = newMetricsServer(cfg.Metrics, registry)
+    }
+
+    // Checks are trimmed to their endpoint's detail level and go through
+    // the change-only decorator when enabled, then are written to the
+    // backends along with the events; everything else uses the store
+    // directly. The detail levels come from the service, which needs the
+    // store, so the lookup refers to it once created.
+    var monitorService *monitor.Service
+    var checkStore storage.Storage = storage.NewDetailLevelStore(primary, func(url string) string {
+        return monitorService.DetailLevel(url)
+    })
+    if cfg.Database.StoreOnChangeOnly {
+        a.changeOnly = storage.NewChangeOnlyStore(checkStore, cfg.Database)
+        checkStore = a.changeOnly
+    }
+
+    monitorService = monitor.NewService(
+        checkStore,
+        dispatcher,
+        monitorMetrics,
         logger,
-        cfg.Monitor,
+        *cfg,
         reloadFn,
     )
+    monitorService.SetBaselineSource(func(url, probe string, since time.Time) (float64, int64, error) {
+        store := a.storage.Load()
+        if store == nil {
+            return 0, 0, nil
+        }
+        stats, err := store.ResponseTimeStats(storage.StatsFilter{URL: url, Probe: probe, Since: since})
+        if err != nil || len(stats) == 0 {
+            return 0, 0, err
+        }
+        return stats[0].P50, stats[0].Count, nil
+    })
+    if registry != nil && cfg.Metrics.ReportsSelf() {
+        registerSelfMetrics(registry, monitorService, dispatcher, a.reconnects)
+    }
+    // Overrides and acknowledgements made before the database opens last
+    // until it does, when the saved ones are loaded
+    if store != nil {
+        if err := monitorService.SetOverrideStore(store); err != nil {
+            closeStores()
+            return nil, fmt.Errorf("failed to load endpoint overrides: %w", err)
+        }
+        if err := monitorService.SetAcknowledgementStore(store); err != nil {
+            closeStores()
+            return nil, fmt.Errorf("failed to load acknowledgements: %w", err)
+        }
+    }
+
+    var apiServer *api.Server
+    if cfg.API.Enabled {
+        apiServer = api.New(cfg.API, monitorService, history, logger)
+        if pending != nil {
+            apiServer.SetStorageHealth(pending)
+        }
+        apiServer.SetReconnects(a.reconnects)
+    }
 
-    return &App{
-        cfg:     cfg,
-        monitor: monitorService,
-        storage: store,
-        logger:  logger,
-    }, nil
+    a.monitor = monitorService
+    a.dispatcher = dispatcher
+    a.metricsServer = metricsServer
+    a.apiServer = apiServer
+    return a, nil
+}
+
+// reconnects returns how many times the database has been reopened after
+// its connection broke, or 0 while it is not open
+func (a *App) reconnects() int64 {
+    store := a.storage.Load()
+    if store == nil {
+        return 0
+    }
+    return store.Reconnects()
+}
+
+// withBackends wraps the database so checks and events are also written to
+// the backends, if any
+func (a *App) withBackends(store *storage.SQLiteStore) storage.Storage {
+    if len(a.backends) == 0 {
+        return store
+    }
+    multi := make([]storage.Backend, len(a.backends))
+    for i, backend := range a.backends {
+        multi[i] = storage.Backend{Name: a.cfg.Database.Backends[i].Path, Store: backend}
+    }
+    return storage.NewMultiStore(store, multi, a.cfg.Database.BackendPolicy, a.logger)
+}
+
+// openBackends opens the further databases checks are written to
+func openBackends(cfg config.DatabaseConfig) ([]*storage.SQLiteStore, error) {
+    var backends []*storage.SQLiteStore
+    for _, backend := range cfg.Backends {
+        store, err := storage.NewSQLiteStore(backend.Path, cfg.Mode())
+        if err != nil {
+            for _, opened := range backends {
+                opened.Close()
+            }
+            return nil, fmt.Errorf("failed to open database backend %s: %w", backend.Path, err)
+        }
+        store.SetCompression(cfg.CompressOver)
+        backends = append(backends, store)
+    }
+    return backends, nil
+}
+
+// newMetricsServer creates the HTTP server exposing /metrics
+func newMetricsServer(cfg config.MetricsConfig, registry *metrics.Registry) *http.Server {
+    address := cfg.Address
+    if address == "" {
+        address = defaultMetricsAddress
+    }
+
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", api.RequireAuth(cfg.Auth, registry.Handler()))
+    return api.NewHTTPServer(address, mux, cfg.ServerTimeouts)
+}
+
+// registerSelfMetrics adds the series about monitord itself that are not
+// kept by the monitor service's metrics
+func registerSelfMetrics(registry *metrics.Registry, service *monitor.Service, dispatcher *notify.Dispatcher, reconnects func() int64) {
+    registry.NewGaugeFunc("monitord_goroutines",
+        "Goroutines currently running in monitord.", func() float64 {
+            return float64(runtime.NumGoroutine())
+        })
+    registry.NewGaugeFunc("monitord_active_monitors",
+        "Endpoints currently being monitored.", func() float64 {
+            return float64(service.ActiveMonitors())
+        })
+    registry.NewCounterFunc("monitord_database_reconnects_total",
+        "Times the database was reopened after its connection broke.", func() float64 {
+            return float64(reconnects())
+        })
+    dispatcher.SetMetrics(registry)
+}
+
+// Monitor returns the monitor service
+func (a *App) Monitor() *monitor.Service {
+    return a.monitor
 }
 
 // Start initializes and starts all application components
 func (a *App) Start(ctx context.Context) error {
     a.logger.Println("Starting application...")
 
+    ctx, a.cancel = context.WithCancel(ctx)
+
+    a.wg.Add(1)
+    go func() {
+        defer a.wg.Done()
+        a.dispatcher.Run(ctx)
+    }()
+
+    if a.metricsServer != nil {
+        a.wg.Add(1)
+        go func() {
+            defer a.wg.Done()
+            a.logger.Printf("Serving metrics on http://%s/metrics", a.metricsServer.Addr)
+            if err := a.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+                a.logger.Printf("Metrics server error: %v", err)
+            }
+        }()
+    }
+
+    if a.apiServer != nil {
+        a.wg.Add(1)
+        go func() {
+            defer a.wg.Done()
+            if err := a.apiServer.ListenAndServe(); err != nil {
+                a.logger.Printf("API server error: %v", err)
+            }
+        }()
+    }
+
+    if interval := a.cfg.Database.VacuumInterval.ToDuration(); interval > 0 {
+        a.wg.Add(1)
+        go func() {
+            defer a.wg.Done()
+            a.vacuumPeriodically(ctx, interval)
+        }()
+    }
+
+    if store := a.storage.Load(); store != nil {
+        a.archiveRollovers(ctx, store)
+    } else {
+        a.wg.Add(1)
+        go func() {
+            defer a.wg.Done()
+            a.openStorageLater(ctx)
+        }()
+    }
+
     if err := a.monitor.Start(ctx); err != nil {
-        return fmt.Errorf("failed to start monitor service: %w", err)
+        // Endpoints that failed to start are logged; the others keep running
+        var startErr *monitor.StartError
+        if !errors.As(err, &startErr) {
+            return fmt.Errorf("failed to start monitor service: %w", err)
+        }
+        a.logger.Printf("WARN %v", startErr)
+    }
+
+    // Removed endpoints are told apart from the configured ones, which are
+    // only known once the monitor service has started
+    if after := a.cfg.Database.PurgeRemovedAfter.ToDuration(); after > 0 {
+        a.wg.Add(1)
+        go func() {
+            defer a.wg.Done()
+            a.purgeRemovedPeriodically(ctx, after)
+        }()
     }
 
     return nil
 }
 
+// openStorageLater retries opening the database that was unavailable at
+// startup, backing off between attempts, until it opens or ctx is done
+func (a *App) openStorageLater(ctx context.Context) {
+    backoff := minStorageRetry
+    timer := time.NewTimer(backoff)
+    defer timer.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-timer.C:
+        }
+        err := a.openStorage(ctx)
+        if err == nil {
+            return
+        }
+        backoff = min(backoff*2, maxStorageRetry)
+        a.logger.Printf("WARN Database %s is still unavailable, running without storage with %d items buffered and %d dropped, retrying in %s: %v",
+            a.cfg.Database.Path, a.pending.Buffered(), a.pending.Dropped(), backoff, err)
+        timer.Reset(backoff)
+    }
+}
+
+// openStorage opens the database, writes what was buffered while it was
+// unavailable and switches everything over to it
+func (a *App) openStorage(ctx context.Context) error {
+    store, err := storage.NewSQLiteStore(a.cfg.Database.Path, a.cfg.Database.Mode())
+    if err != nil {
+        return err
+    }
+    store.SetCompression(a.cfg.Database.CompressOver)
+    buffered := a.pending.Buffered()
+    if err := a.pending.Attach(a.withBackends(store), store); err != nil {
+        store.Close()
+        return fmt.Errorf("failed to save buffered items: %w", err)
+    }
+    a.storage.Store(store)
+    a.archiveRollovers(ctx, store)
+    a.logger.Printf("Database %s is available, saved %d buffered checks, events and notifications",
+        a.cfg.Database.Path, buffered)
+    if dropped := a.pending.Dropped(); dropped > 0 {
+        a.logger.Printf("WARN %d checks, events and notifications were dropped while the database was unavailable", dropped)
+    }
+
+    if err := a.monitor.SetOverrideStore(store); err != nil {
+        a.logger.Printf("Error loading endpoint overrides: %v", err)
+    }
+    if err := a.monitor.SetAcknowledgementStore(store); err != nil {
+        a.logger.Printf("Error loading acknowledgements: %v", err)
+    }
+    return nil
+}
+
+// archiveRollovers archives each database file the store rolls over from,
+// when an archive directory or command is configured
+func (a *App) archiveRollovers(ctx context.Context, store *storage.SQLiteStore) {
+    if a.cfg.Database.ArchiveDir == "" && len(a.cfg.Database.ArchiveCommand) == 0 {
+        return
+    }
+    // The hook runs in its own goroutine, which closing the store waits for
+    store.SetRolloverHook(func(path string) {
+        if ctx.Err() != nil {
+            a.logger.Printf("WARN Not archiving %s while shutting down", path)
+            return
+        }
+        a.archive(ctx, path)
+    })
+}
+
+// archive runs the archive command on a finished database file, or gzips
+// it into the archive directory
+func (a *App) archive(ctx context.Context, path string) {
+    start := time.Now()
+    if command := a.cfg.Database.ArchiveCommand; len(command) > 0 {
+        args := append(append([]string(nil), command[1:]...), path)
+        output, err := exec.CommandContext(ctx, command[0], args...).CombinedOutput()
+        if err != nil {
+            a.logger.Printf("Error archiving %s with %s: %v: %s", path, command[0], err, output)
+            return
+        }
+        a.logger.Printf("Archived %s with %s in %s", path, command[0], time.Since(start).Round(time.Millisecond))
+        return
+    }
+
+    archived, err := storage.ArchiveDatabase(path, a.cfg.Database.ArchiveDir, a.cfg.Database.Mode())
+    if err != nil {
+        a.logger.Printf("Error archiving %s: %v", path, err)
+        return
+    }
+    a.logger.Printf("Archived %s to %s in %s", path, archived, time.Since(start).Round(time.Millisecond))
+}
+
+// vacuumPeriodically compacts the database every interval until ctx is done
+func (a *App) vacuumPeriodically(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            store := a.storage.Load()
+            if store == nil {
+                continue
+            }
+            start := time.Now()
+            reclaimed, err := store.Vacuum()
+            if err != nil {
+                a.logger.Printf("Error vacuuming database: %v", err)
+                continue
+            }
+            a.logger.Printf("Vacuumed database in %s, reclaimed %d bytes",
+                time.Since(start).Round(time.Millisecond), reclaimed)
+        }
+    }
+}
+
+// purgeRemovedPeriodically deletes the data of endpoints missing from the
+// config for longer than after, looking for them right away and then every
+// purgeCheckInterval until ctx is done
+func (a *App) purgeRemovedPeriodically(ctx context.Context, after time.Duration) {
+    ticker := time.NewTicker(purgeCheckInterval)
+    defer ticker.Stop()
+
+    for {
+        a.purgeRemoved(after)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// purgeRemoved records which stored endpoints are missing from the config
+// and deletes the data of those missing for longer than after. Nothing is
+// purged while the configured endpoints are not fully known.
+func (a *App) purgeRemoved(after time.Duration) {
+    store := a.storage.Load()
+    if store == nil {
+        return
+    }
+    configured, ok := a.monitor.ConfiguredURLs()
+    if !ok {
+        a.logger.Printf("Not purging removed endpoints: remote endpoints have not been fetched")
+        return
+    }
+    now := time.Now()
+    if err := store.MarkRemovedEndpoints(configured, now); err != nil {
+        a.logger.Printf("Error recording removed endpoints: %v", err)
+        return
+    }
+    removed, err := store.RemovedEndpoints(now.Add(-after))
+    if err != nil {
+        a.logger.Printf("Error listing removed endpoints: %v", err)
+        return
+    }
+    for _, endpoint := range removed {
+        deleted, err := store.DeleteEndpointData(endpoint.URL)
+        if err != nil {
+            a.logger.Printf("Error purging data of removed endpoint %s: %v", endpoint.URL, err)
+            continue
+        }
+        a.logger.Printf("Purged data of %s, missing from the config since %s: %d checks and its events, rollups and registry entry",
+            endpoint.URL, endpoint.RemovedAt.Local().Format(time.RFC3339), deleted)
+    }
+}
+
 // Shutdown gracefully stops all application components
 func (a *App) Shutdown(ctx context.Context) error {
     a.logger.Println("Shutting down application...")
 
+    // Cancelling the application context stops the config watcher and the
+    // notification dispatcher along with the endpoint monitors
+    if a.cancel != nil {
+        a.cancel()
+    }
+
     if err := a.monitor.Shutdown(ctx); err != nil {
         a.logger.Printf("Error shutting down monitor service: %v", err)
     }
+    if a.changeOnly != nil {
+        if err := a.changeOnly.Flush(); err != nil {
+            a.logger.Printf("Error saving the checks left out by store_on_change_only: %v", err)
+        }
+    }
+    if a.metricsServer != nil {
+        if err := a.metricsServer.Shutdown(ctx); err != nil {
+            a.logger.Printf("Error shutting down metrics server: %v", err)
+        }
+    }
+    if a.apiServer != nil {
+        if err := a.apiServer.Shutdown(ctx); err != nil {
+            a.logger.Printf("Error shutting down API server: %v", err)
+        }
+    }
+    a.wg.Wait()
 
-    return a.storage.Close()
+    for _, backend := range a.backends {
+        if err := backend.Close(); err != nil {
+            a.logger.Printf("Error closing database backend %s: %v", backend.Path(), err)
+        }
+    }
+    store := a.storage.Load()
+    if store == nil {
+        a.logger.Printf("WARN Database never became available, %d buffered checks, events and notifications were not saved",
+            a.pending.Buffered())
+        return nil
+    }
+    return store.Close()
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// bodyFilePrefix marks a request body that should be read from a file
+const bodyFilePrefix = "@"
+
+// resolveBodies replaces request bodies given as "@path" with the contents of
+// the file. Only endpoints from the config file are resolved; bodies of
+// remote endpoints are always sent as given.
+func (c *Config) resolveBodies() error {
+	var errs []error
+	for i := range c.Monitor.Endpoints {
+		endpoint := &c.Monitor.Endpoints[i]
+		if err := resolveBody(&endpoint.Body); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %q: body: %w", endpoint.Name, err))
+		}
+		if endpoint.PreRequest != nil {
+			if err := resolveBody(&endpoint.PreRequest.Body); err != nil {
+				errs = append(errs, fmt.Errorf("endpoint %q: pre_request: body: %w", endpoint.Name, err))
+			}
+		}
+		for j := range endpoint.Steps {
+			step := &endpoint.Steps[j]
+			if err := resolveBody(&step.Body); err != nil {
+				errs = append(errs, fmt.Errorf("endpoint %q: steps: %s: body: %w", endpoint.Name, step.Label(j), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveBody sets body from the file it references, if any
+func resolveBody(body *string) error {
+	path, ok := strings.CutPrefix(*body, bodyFilePrefix)
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	*body = string(data)
+	return nil
+}
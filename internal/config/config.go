@@ -1,41 +1,761 @@
 package config
 
 import (
+    "crypto/tls"
     "encoding/json"
+    "errors"
     "fmt"
+    "net/http"
     "os"
     "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
     "time"
 )
 
 type Config struct {
-    Database DatabaseConfig `json:"database"`
-    Monitor  MonitorConfig `json:"monitor"`
-    Logging  LogConfig     `json:"logging"`
+    Database      DatabaseConfig     `json:"database"`
+    Monitor       MonitorConfig      `json:"monitor"`
+    Logging       LogConfig          `json:"logging"`
+    Notifications NotificationConfig `json:"notifications"`
+    Metrics       MetricsConfig      `json:"metrics"`
+    API           APIConfig          `json:"api"`
 }
 
 type DatabaseConfig struct {
     Path string `json:"path"`
+    // StoreOnChangeOnly skips saving an UP check that matches the previous
+    // stored one, except for a heartbeat every HeartbeatInterval. A change of
+    // status, status code or more than ResponseTimeDelta in response time is
+    // always stored.
+    StoreOnChangeOnly bool     `json:"store_on_change_only,omitempty"`
+    HeartbeatInterval Duration `json:"heartbeat_interval,omitempty"`
+    ResponseTimeDelta Duration `json:"response_time_delta,omitempty"`
+    // VacuumInterval compacts the database file this often to reclaim the
+    // space of deleted rows. Zero disables it.
+    VacuumInterval Duration `json:"vacuum_interval,omitempty"`
+    // FileMode is the octal permission mode, e.g. "0600", given to database
+    // files monitord creates, and with execute bits added to the directories
+    // it creates for them. Existing files are left as they are.
+    FileMode string `json:"file_mode,omitempty"`
+    // SaveQueue is how many checks may wait to be saved, so a slow disk does
+    // not delay checks. Zero saves each check before the next one starts.
+    SaveQueue int `json:"save_queue,omitempty"`
+    // SaveQueuePolicy decides what happens when the save queue is full:
+    // "block" (the default) or "drop_successes"
+    SaveQueuePolicy string `json:"save_queue_policy,omitempty"`
+    // CompressOver gzips a check's error, headers, detail, body snippet and
+    // components when longer than this many bytes. Zero stores them as text.
+    CompressOver int `json:"compress_over,omitempty"`
+    // Backends are further databases every check and event is also written
+    // to, such as one on shared storage, so Path is not the only copy.
+    // Queries read from Path alone.
+    Backends []DatabaseBackend `json:"backends,omitempty"`
+    // BackendPolicy decides what a failed write to a backend does:
+    // "best_effort" (the default) logs it, while "require_all" fails the save
+    BackendPolicy string `json:"backend_policy,omitempty"`
+    // PurgeRemovedAfter deletes the stored data of an endpoint once it has
+    // been missing from the config for this long. Zero keeps it forever.
+    PurgeRemovedAfter Duration `json:"purge_removed_after,omitempty"`
+    // StartWithoutStorage keeps monitord running when the database cannot
+    // be opened at startup: checks go on, their results wait in memory and
+    // opening the database is retried in the background until it succeeds
+    StartWithoutStorage bool `json:"start_without_storage,omitempty"`
+    // StartupBuffer is how many checks, events and notifications each are
+    // kept in memory while the database is unavailable, dropping the oldest
+    // beyond it. Zero uses DefaultStartupBuffer.
+    StartupBuffer int `json:"startup_buffer,omitempty"`
+    // ArchiveDir receives each finished file of a Path with date
+    // placeholders, gzipped, when the database rolls over to the next one;
+    // the finished file is then removed
+    ArchiveDir string `json:"archive_dir,omitempty"`
+    // ArchiveCommand is run instead with the finished file's path appended,
+    // as an argv array without a shell, to archive it some other way
+    ArchiveCommand []string `json:"archive_command,omitempty"`
+}
+
+// DefaultStartupBuffer is the StartupBuffer used when none is set
+const DefaultStartupBuffer = 10000
+
+// MinPurgeRemovedAfter is the shortest PurgeRemovedAfter accepted, so an
+// endpoint dropped from the config by mistake can be restored in time
+const MinPurgeRemovedAfter = Duration(24 * time.Hour)
+
+// DatabaseBackend is a further database written alongside the main one.
+// Only SQLite files are supported; there is no driver for server databases
+// such as Postgres.
+type DatabaseBackend struct {
+    // Path is a SQLite database file, which may use the same %Y, %m and %d
+    // placeholders as the main path
+    Path string `json:"path"`
+}
+
+// Values accepted by DatabaseConfig.BackendPolicy
+const (
+    BackendBestEffort = "best_effort"
+    BackendRequireAll = "require_all"
+)
+
+// RedirectLimit is how many redirects a check follows before failing, as
+// with the net/http default
+const RedirectLimit = 10
+
+// Values accepted by DatabaseConfig.SaveQueuePolicy
+const (
+    // SaveQueueBlock makes a check wait for room in the queue
+    SaveQueueBlock = "block"
+    // SaveQueueDropSuccesses drops the oldest queued UP check to make room,
+    // or the new check when it is UP and none is queued; other checks wait
+    SaveQueueDropSuccesses = "drop_successes"
+)
+
+// Mode returns the configured database file mode, or 0 when none is set
+func (d DatabaseConfig) Mode() os.FileMode {
+    mode, _ := ParseFileMode(d.FileMode)
+    return mode
+}
+
+// StartupBufferSize returns the configured startup buffer, or
+// DefaultStartupBuffer when none is set
+func (d DatabaseConfig) StartupBufferSize() int {
+    if d.StartupBuffer == 0 {
+        return DefaultStartupBuffer
+    }
+    return d.StartupBuffer
 }
 
 type MonitorConfig struct {
     Endpoints    []Endpoint     `json:"endpoints"`
     ConfigCheck  Duration   `json:"config_check_interval"`
+    // RemoteEndpoints fetches additional endpoints over HTTP on every config
+    // check and merges them with Endpoints
+    RemoteEndpoints *RemoteEndpointsConfig `json:"remote_endpoints,omitempty"`
+    // ProbeName identifies this instance, e.g. "us-east", and is recorded on
+    // every check so results from several instances can be told apart
+    ProbeName string `json:"probe_name,omitempty"`
+    // MinTLSVersion is the lowest TLS version accepted from https endpoints,
+    // e.g. "1.2"; endpoints can override it
+    MinTLSVersion string `json:"min_tls_version,omitempty"`
+    // StartupRamp spreads the start of endpoint monitors over this window so
+    // large configs do not check every endpoint at the same moment. Defaults
+    // to a window proportional to the number of endpoints.
+    StartupRamp Duration `json:"startup_ramp,omitempty"`
+    // StartupGrace holds notifications for this long after the service
+    // starts; checks are still recorded and establish each endpoint's
+    // baseline status, and endpoints not UP when it ends are notified then
+    StartupGrace Duration `json:"startup_grace,omitempty"`
+    // StaleAfterIntervals is how many intervals past its due time an
+    // endpoint's check may be before its status is reported as stale, which
+    // means its monitoring has stopped producing results. Defaults to 3.
+    StaleAfterIntervals int `json:"stale_after_intervals,omitempty"`
+    // WatchdogInterval is how often endpoints are checked for being stale,
+    // 1s by default. Changes take effect on restart.
+    WatchdogInterval Duration `json:"watchdog_interval,omitempty"`
+    // WatchdogRestart restarts the monitoring of an endpoint once it is
+    // stale, keeping its alerting state
+    WatchdogRestart bool `json:"watchdog_restart,omitempty"`
+    // Scheduler selects how checks are scheduled: "goroutines" (the default)
+    // runs a goroutine per endpoint, "heap" runs every endpoint from a
+    // time-ordered queue served by SchedulerWorkers goroutines. Changes take
+    // effect on restart.
+    Scheduler        string `json:"scheduler,omitempty"`
+    SchedulerWorkers int    `json:"scheduler_workers,omitempty"`
+    // GlobalHeaders are sent with every check request and pre-request. A
+    // header the endpoint also sets, compared case-insensitively, takes the
+    // endpoint's value.
+    GlobalHeaders map[string]string `json:"global_headers,omitempty"`
+    // DNSCacheTTL keeps resolved addresses for this long instead of looking
+    // up the host on every check. If a lookup fails after the TTL, the
+    // previous addresses are used and the check records the stale entry.
+    // Disabled by default; endpoints can override it.
+    DNSCacheTTL Duration `json:"dns_cache_ttl,omitempty"`
+    // HostRateLimits caps how often checks are sent to matching hosts, so
+    // many endpoints on one origin do not overload it. Hosts without a
+    // matching entry are not limited.
+    HostRateLimits []HostRateLimit `json:"host_rate_limits,omitempty"`
+    // GeoIPDatabases are MaxMind DB files, such as GeoLite2-ASN and
+    // GeoLite2-City, used to record the network and location of the address
+    // each check connected to. Lookups are cached per address.
+    GeoIPDatabases []string `json:"geoip_databases,omitempty"`
+    // CircuitBreaker backs off the checks of endpoints that keep failing;
+    // endpoints can override it
+    CircuitBreaker *CircuitBreaker `json:"circuit_breaker,omitempty"`
+    // ResponseBaseline marks checks much slower than the endpoint's recent
+    // median as DEGRADED; endpoints can override it
+    ResponseBaseline *ResponseBaseline `json:"response_baseline,omitempty"`
+    // Canaries are endpoints, such as well-known reliable sites, whose all
+    // being down at once means monitord itself has lost connectivity
+    Canaries *CanaryConfig `json:"canaries,omitempty"`
+    // CABundle is a PEM file of CA certificates trusted by every check, in
+    // addition to the system roots, e.g. for a private CA. It is read at
+    // startup and on every reload.
+    CABundle string `json:"ca_bundle,omitempty"`
+    // Heartbeat pings a dead man's switch service while monitoring is
+    // healthy, so monitord itself stopping or getting stuck is noticed
+    Heartbeat *HeartbeatConfig `json:"heartbeat,omitempty"`
+    // MaintenanceCalendar suppresses notifications during the events of an
+    // iCal feed, such as a team's maintenance schedule
+    MaintenanceCalendar *MaintenanceCalendarConfig `json:"maintenance_calendar,omitempty"`
+}
+
+// MaintenanceCalendarConfig fetches an iCal feed from URL every Interval
+// (default 15m), waiting up to Timeout (default 10s). Events in progress
+// whose summary contains SummaryTag, when set, are maintenance windows for
+// the endpoints selected by Match. URL may be a file:// secret.
+type MaintenanceCalendarConfig struct {
+    URL        string   `json:"url"`
+    Interval   Duration `json:"interval,omitempty"`
+    Timeout    Duration `json:"timeout,omitempty"`
+    SummaryTag string   `json:"summary_tag,omitempty"`
+    // Match selects the endpoints an event covers: "all" (the default),
+    // "name" for those whose name or URL the event's summary or description
+    // mentions, or "tags" for those with a tag among the event's categories
+    // or written as #tag in its summary
+    Match string `json:"match,omitempty"`
+}
+
+// Values accepted by MaintenanceCalendarConfig.Match
+const (
+    MaintenanceMatchAll  = "all"
+    MaintenanceMatchName = "name"
+    MaintenanceMatchTags = "tags"
+)
+
+// HeartbeatConfig posts a JSON summary of endpoint statuses to URL every
+// Interval (default 1m), waiting up to Timeout (default 10s) for a 2xx
+// response. No ping is sent while any endpoint is stale, so a service such
+// as healthchecks.io alerts when the pings stop. URL may be a file:// secret.
+type HeartbeatConfig struct {
+    URL      string   `json:"url"`
+    Interval Duration `json:"interval,omitempty"`
+    Timeout  Duration `json:"timeout,omitempty"`
+}
+
+// CanaryConfig lists the URLs of monitored endpoints used as canaries. While
+// every canary's confirmed status is ERROR, other endpoints' notifications
+// are suppressed as selected by Suppress, and a single connectivity
+// notification is sent to Notifiers (the fallback route when empty) instead.
+// Canaries do not send notifications of their own.
+type CanaryConfig struct {
+    URLs      []string `json:"urls"`
+    Suppress  string   `json:"suppress,omitempty"`
+    Notifiers []string `json:"notifiers,omitempty"`
+}
+
+// Suppression modes accepted by CanaryConfig.Suppress
+const (
+    // CanarySuppressAll holds every notification (the default)
+    CanarySuppressAll = "all"
+    // CanarySuppressFailures holds only notifications of endpoints going
+    // DEGRADED or ERROR, letting recoveries through
+    CanarySuppressFailures = "failures"
+    // CanarySuppressNone only sends the connectivity notification
+    CanarySuppressNone = "none"
+)
+
+// CircuitBreaker backs off the checks of an endpoint that keeps failing.
+// After FailureThreshold consecutive ERROR checks the breaker opens and the
+// interval is multiplied by Multiplier (default 2) after each further
+// failure, up to MaxInterval (default 1h). The first success half-opens it,
+// returning to the normal interval, and SuccessThreshold consecutive
+// successes (default 1) close it; a failure while half-open reopens it. A
+// FailureThreshold of 0 disables the breaker.
+type CircuitBreaker struct {
+    FailureThreshold int      `json:"failure_threshold"`
+    SuccessThreshold int      `json:"success_threshold,omitempty"`
+    Multiplier       float64  `json:"multiplier,omitempty"`
+    MaxInterval      Duration `json:"max_interval,omitempty"`
+}
+
+// ResponseBaseline marks a successful check DEGRADED when its response time
+// is more than Multiplier times the endpoint's median over the last Window
+// (default 1h). The median is read from the response-time rollups every
+// Refresh (default 5m) and is only used once it covers MinSamples responses
+// (default 20). A Multiplier of 0 disables it.
+type ResponseBaseline struct {
+    Multiplier float64  `json:"multiplier"`
+    Window     Duration `json:"window,omitempty"`
+    MinSamples int      `json:"min_samples,omitempty"`
+    Refresh    Duration `json:"refresh,omitempty"`
+}
+
+// RelaxSettings loosen an endpoint for a while, so a deploy does not alert
+// on the slow or failed checks it is expected to cause. Unset values are
+// twice the endpoint's own.
+type RelaxSettings struct {
+    Timeout           Duration `json:"timeout,omitempty"`
+    FailureThreshold  int      `json:"failure_threshold,omitempty"`
+    DegradedThreshold int      `json:"degraded_threshold,omitempty"`
+}
+
+// HostRateLimit limits checks to hosts matching Host, a hostname or a
+// pattern where * matches any characters, e.g. "*.example.com". Each
+// matching host is allowed Rate checks per second, with bursts of up to
+// Burst checks (default 1). The first matching entry applies.
+type HostRateLimit struct {
+    Host  string  `json:"host"`
+    Rate  float64 `json:"rate"`
+    Burst int     `json:"burst,omitempty"`
+}
+
+// RemoteEndpointsConfig points at a service-discovery URL returning a JSON
+// array of endpoints in the same shape as the endpoints setting
+type RemoteEndpointsConfig struct {
+    URL     string   `json:"url"`
+    Timeout Duration `json:"timeout,omitempty"`
 }
 
+// Endpoint is a monitored URL. The URL identifies the endpoint everywhere:
+// in storage, reports, the API and alerts. Name is a display label and need
+// not be unique.
 type Endpoint struct {
     Name        string        `json:"name"`
     URL         string        `json:"url"`
     Interval    Duration `json:"interval"`
     Timeout     Duration `json:"timeout"`
+    // ConnectTimeout limits establishing the connection, separately from
+    // Timeout, which covers the whole request
+    ConnectTimeout Duration `json:"connect_timeout,omitempty"`
     Description string        `json:"description,omitempty"`
     Tags        []string      `json:"tags,omitempty"`
     Enabled     bool          `json:"enabled"`
+    // Private leaves the endpoint out of the API's status and status page
+    // for requests let through without credentials by the API's
+    // public_status setting. It is monitored as usual.
+    Private bool `json:"private,omitempty"`
+    // FailureThreshold is the number of consecutive failed checks before the
+    // endpoint is considered down (defaults to 1)
+    FailureThreshold int `json:"failure_threshold,omitempty"`
+    // DegradedThreshold is the number of consecutive DEGRADED checks before
+    // the endpoint is considered degraded (defaults to 1). A check that is
+    // UP resets the count.
+    DegradedThreshold int `json:"degraded_threshold,omitempty"`
+    // Relax is what Timeout, FailureThreshold and DegradedThreshold become
+    // while the endpoint is relaxed through the API, such as during a deploy
+    Relax *RelaxSettings `json:"relax,omitempty"`
+    // AlertOnDegraded controls whether changes to or from DEGRADED are
+    // notified (defaults to true). When false, DEGRADED is still checked,
+    // stored and exported but alerts treat it as UP.
+    AlertOnDegraded *bool `json:"alert_on_degraded,omitempty"`
+    // ExpectInaccessible inverts the check for endpoints that should stay
+    // down: connection failures and 404/410 responses are reported as UP
+    ExpectInaccessible bool `json:"expect_inaccessible,omitempty"`
+    // CaptureHeadersOnFailure stores the (redacted) response headers with
+    // checks that are not UP
+    CaptureHeadersOnFailure bool `json:"capture_headers_on_failure,omitempty"`
+    // ExpectHeaders lists response headers that must be present. Values are
+    // matched exactly, or as a regular expression when prefixed with
+    // "regex:"; an empty value only requires the header to exist.
+    ExpectHeaders map[string]string `json:"expect_headers,omitempty"`
+    // headerPatterns holds the "regex:" values of ExpectHeaders, compiled
+    // by Prepare
+    headerPatterns map[string]*regexp.Regexp
+    // AlertCooldown is the minimum time between notifications for the
+    // endpoint; the latest status is sent when the cooldown ends if it changed
+    AlertCooldown Duration `json:"alert_cooldown,omitempty"`
+    // MinSuccessRate is the percentage of UP checks, over the last
+    // SuccessRateWindow checks, below which a success-rate alert is sent
+    MinSuccessRate    float64 `json:"min_success_rate,omitempty"`
+    SuccessRateWindow int     `json:"success_rate_window,omitempty"`
+    // HTTPVersion selects the protocol: "1.1" disables HTTP/2 and "2" requires
+    // it (https only), marking checks DEGRADED if the server negotiates
+    // HTTP/1.1. Empty negotiates either.
+    HTTPVersion string `json:"http_version,omitempty"`
+    // ExpectRedirectTo stops redirects from being followed and requires a
+    // redirect whose Location matches: exactly, or with a "prefix:" or
+    // "glob:" prefix
+    ExpectRedirectTo string `json:"expect_redirect_to,omitempty"`
+    // MaxRedirects marks checks DEGRADED when more redirects than this were
+    // followed to reach the final response. Zero leaves only the
+    // RedirectLimit, past which checks fail.
+    MaxRedirects int `json:"max_redirects,omitempty"`
+    // ExpectProtocol marks checks DEGRADED when the negotiated protocol
+    // differs, e.g. "HTTP/2.0" to catch a downgrade to HTTP/1.1
+    ExpectProtocol string `json:"expect_protocol,omitempty"`
+    // MinTLSVersion overrides the monitor-wide minimum TLS version
+    MinTLSVersion string `json:"min_tls_version,omitempty"`
+    // CaptureBody keeps the last response body in memory, truncated to
+    // CaptureBodyLimit bytes, for inspection through the API
+    CaptureBody      bool `json:"capture_body,omitempty"`
+    CaptureBodyLimit int  `json:"capture_body_limit,omitempty"`
+    // DecodeBody requests gzip or deflate encoding, reads the whole body and
+    // decompresses it, recording the size before and after. Bodies that
+    // decode to more than DecodedBodyLimit bytes (10 MiB by default) or fail
+    // to decode mark the check DEGRADED.
+    DecodeBody       bool `json:"decode_body,omitempty"`
+    DecodedBodyLimit int  `json:"decoded_body_limit,omitempty"`
+    // DetailLevel selects how much of each check is stored: "normal" (the
+    // default) stores what the endpoint's settings collect, "minimal" only
+    // the outcome, and "verbose-on-failure" adds response headers, a body
+    // snippet and a timing breakdown to failed checks while storing
+    // successes minimally
+    DetailLevel string `json:"detail_level,omitempty"`
+    // DebugTrace logs the request's connection timeline and the request and
+    // response headers, redacted, for every check that is not UP. Meant for
+    // chasing an intermittent failure, not for normal operation.
+    DebugTrace bool `json:"debug_trace,omitempty"`
+    // IPVersion forces checks over IPv4 ("4") or IPv6 ("6") instead of
+    // letting the resolver pick ("auto", the default). "both" checks over
+    // each in turn and reports the worse result.
+    IPVersion string `json:"ip_version,omitempty"`
+    // Type selects how the endpoint is checked: "http" (the default),
+    // "exec", which runs Command, an argv array run without a shell, and
+    // uses URL only as an identifier, "websocket", which performs a
+    // WebSocket upgrade handshake with a ws:// or wss:// URL, or "passive",
+    // which is never checked but receives checks reported through the API
+    Type    string   `json:"type,omitempty"`
+    Command []string `json:"command,omitempty"`
+    // Grace is how long past its interval a passive endpoint's report may
+    // be late before the check is recorded as missed
+    Grace Duration `json:"grace,omitempty"`
+    // WebSocketPing sends a ping after a websocket endpoint's handshake and
+    // requires a pong within the timeout
+    WebSocketPing bool `json:"websocket_ping,omitempty"`
+    // Method is the HTTP method of the check request (defaults to GET)
+    Method string `json:"method,omitempty"`
+    // Body is sent as the request body. A value starting with "@" names a
+    // file whose contents are read when the config is loaded.
+    Body string `json:"body,omitempty"`
+    // Headers are added to the check request
+    Headers map[string]string `json:"headers,omitempty"`
+    // ExpectContinueTimeout sends the body after an "Expect: 100-continue"
+    // header, waiting up to this long for the server's 100 Continue
+    ExpectContinueTimeout Duration `json:"expect_continue_timeout,omitempty"`
+    // ChunkedBody sends the body with chunked transfer encoding instead of a
+    // Content-Length header
+    ChunkedBody bool `json:"chunked_body,omitempty"`
+    // PreRequest is sent before every check, e.g. to log in. Cookies it
+    // receives are kept for the endpoint and sent with the check.
+    PreRequest *PreRequest `json:"pre_request,omitempty"`
+    // Steps turns an http endpoint's check into a transaction: the steps
+    // are sent in order instead of a single request to URL, and the check
+    // is UP only if every step passes
+    Steps []TransactionStep `json:"steps,omitempty"`
+    // DNSCacheTTL overrides the monitor-wide DNS cache TTL and
+    // DisableDNSCache resolves the host on every check regardless of it
+    DNSCacheTTL     Duration `json:"dns_cache_ttl,omitempty"`
+    DisableDNSCache bool     `json:"disable_dns_cache,omitempty"`
+    // CircuitBreaker overrides the monitor-wide circuit breaker
+    CircuitBreaker *CircuitBreaker `json:"circuit_breaker,omitempty"`
+    // ResponseBaseline overrides the monitor-wide response baseline
+    ResponseBaseline *ResponseBaseline `json:"response_baseline,omitempty"`
+    // CAFile is a PEM file of CA certificates trusted for this endpoint on
+    // top of the system roots and the monitor-wide CABundle
+    CAFile string `json:"ca_file,omitempty"`
+}
+
+// AlertsOnDegraded reports whether DEGRADED transitions are notified
+func (e Endpoint) AlertsOnDegraded() bool {
+    return e.AlertOnDegraded == nil || *e.AlertOnDegraded
+}
+
+// PreRequest is a request made before an endpoint's check, such as a login.
+// Body accepts an "@path" file reference like Endpoint.Body.
+type PreRequest struct {
+    URL     string            `json:"url"`
+    Method  string            `json:"method,omitempty"`
+    Body    string            `json:"body,omitempty"`
+    Headers map[string]string `json:"headers,omitempty"`
+}
+
+// TransactionStep is one request of a transaction check. Path is resolved
+// against the endpoint's URL, so it is either a path or an absolute URL.
+// Path, Body and header values may use the variables captured by earlier
+// steps as {{name}}. ExpectStatus is the status code the step must return;
+// zero accepts any below 400. Capture maps a variable name to the field of
+// the JSON response holding its value, as a dotted path such as "data.id"
+// or "items.0.id".
+type TransactionStep struct {
+    Name         string            `json:"name,omitempty"`
+    Method       string            `json:"method,omitempty"`
+    Path         string            `json:"path"`
+    Body         string            `json:"body,omitempty"`
+    Headers      map[string]string `json:"headers,omitempty"`
+    ExpectStatus int               `json:"expect_status,omitempty"`
+    Capture      map[string]string `json:"capture,omitempty"`
+}
+
+// Label names a step in messages: by its name, or by its position
+func (s TransactionStep) Label(i int) string {
+    if s.Name != "" {
+        return fmt.Sprintf("step %d (%s)", i+1, s.Name)
+    }
+    return fmt.Sprintf("step %d", i+1)
+}
+
+// HTTP versions accepted by Endpoint.HTTPVersion
+const (
+    HTTPVersion1 = "1.1"
+    HTTPVersion2 = "2"
+)
+
+// SendsRequests reports whether checks of the endpoint are network requests
+// to its URL, rather than a command or reports from elsewhere
+func (e Endpoint) SendsRequests() bool {
+    return e.Type != EndpointTypeExec && e.Type != EndpointTypePassive
+}
+
+// Prepare compiles the "regex:" values of the endpoint's expect_headers once,
+// so checks do not compile them again. Patterns that do not compile are left
+// for validation to report.
+func (e *Endpoint) Prepare() {
+    e.headerPatterns = nil
+    for name, value := range e.ExpectHeaders {
+        pattern, ok := strings.CutPrefix(value, "regex:")
+        if !ok {
+            continue
+        }
+        re, err := regexp.Compile(pattern)
+        if err != nil {
+            continue
+        }
+        if e.headerPatterns == nil {
+            e.headerPatterns = make(map[string]*regexp.Regexp)
+        }
+        e.headerPatterns[name] = re
+    }
+}
+
+// HeaderPattern returns the regular expression of an expect_headers value
+// given as "regex:". It was compiled by Prepare, or is compiled now for an
+// endpoint that was never prepared.
+func (e Endpoint) HeaderPattern(name string) (*regexp.Regexp, error) {
+    if re, ok := e.headerPatterns[name]; ok {
+        return re, nil
+    }
+    pattern, _ := strings.CutPrefix(e.ExpectHeaders[name], "regex:")
+    return regexp.Compile(pattern)
+}
+
+// Endpoint types accepted by Endpoint.Type
+const (
+    EndpointTypeHTTP      = "http"
+    EndpointTypeExec      = "exec"
+    EndpointTypeWebSocket = "websocket"
+    EndpointTypePassive   = "passive"
+)
+
+// Detail levels accepted by Endpoint.DetailLevel
+const (
+    DetailMinimal          = "minimal"
+    DetailNormal           = "normal"
+    DetailVerboseOnFailure = "verbose-on-failure"
+)
+
+// Scheduler modes accepted by MonitorConfig.Scheduler
+const (
+    SchedulerGoroutines = "goroutines"
+    SchedulerHeap       = "heap"
+)
+
+// IP versions accepted by Endpoint.IPVersion
+const (
+    IPVersionAuto = "auto"
+    IPVersion4    = "4"
+    IPVersion6    = "6"
+    IPVersionBoth = "both"
+)
+
+// NormalizeProtocol converts a protocol such as "2", "HTTP/2" or "http/1.1" to
+// the form reported in responses ("HTTP/2.0", "HTTP/1.1")
+func NormalizeProtocol(value string) (string, bool) {
+    value = strings.ToUpper(strings.TrimSpace(value))
+    if !strings.HasPrefix(value, "HTTP/") {
+        value = "HTTP/" + value
+    }
+    if !strings.Contains(value, ".") {
+        value += ".0"
+    }
+    if _, _, ok := http.ParseHTTPVersion(value); !ok {
+        return "", false
+    }
+    return value, true
+}
+
+// tlsVersions maps the accepted min_tls_version values to crypto/tls versions
+var tlsVersions = map[string]uint16{
+    "1.0": tls.VersionTLS10,
+    "1.1": tls.VersionTLS11,
+    "1.2": tls.VersionTLS12,
+    "1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion converts a version such as "1.2" to its crypto/tls value
+func ParseTLSVersion(value string) (uint16, bool) {
+    version, ok := tlsVersions[value]
+    return version, ok
 }
 
 type LogConfig struct {
     Path  string `json:"path"`
     Level string `json:"level"`
+    // SlowCheckThreshold replaces the log lines for routine successful
+    // checks with a warning for those slower than this. Failed checks are
+    // always logged.
+    SlowCheckThreshold Duration `json:"slow_check_threshold,omitempty"`
+    // Output is where the daemon logs: "stdout" (the default) or "file",
+    // which appends to Path
+    Output string `json:"output,omitempty"`
+    // MaxSizeMB rotates the log file before it grows past this size. Rotated
+    // files beyond MaxBackups or older than MaxAgeDays are removed. Zero
+    // leaves each unbounded.
+    MaxSizeMB  int `json:"max_size_mb,omitempty"`
+    MaxBackups int `json:"max_backups,omitempty"`
+    MaxAgeDays int `json:"max_age_days,omitempty"`
+    // FileMode is the octal permission mode, e.g. "0640", given to log files
+    // monitord creates, and with execute bits added to the directories it
+    // creates for them. Defaults to "0600".
+    FileMode string `json:"file_mode,omitempty"`
+}
+
+// Mode returns the configured log file mode, or 0 when none is set
+func (l LogConfig) Mode() os.FileMode {
+    mode, _ := ParseFileMode(l.FileMode)
+    return mode
+}
+
+// Log outputs accepted by LogConfig.Output
+const (
+    LogOutputStdout = "stdout"
+    LogOutputFile   = "file"
+)
+
+// NotificationConfig configures where status changes are sent. Undelivered
+// notifications are retried with backoff until they exceed MaxAge.
+type NotificationConfig struct {
+    Notifiers     []NotifierConfig `json:"notifiers,omitempty"`
+    RetryInterval Duration         `json:"retry_interval,omitempty"`
+    MaxAge        Duration         `json:"max_age,omitempty"`
+    // Routes maps endpoint tags to the names of the notifiers that receive
+    // their notifications. Endpoints matching no tag use the "*" route, or
+    // every notifier when there is none. Without routes every notifier
+    // receives every notification.
+    Routes map[string][]string `json:"routes,omitempty"`
+    // QuietHours holds notifications for endpoints not tagged CriticalTag
+    // until the quiet period ends
+    QuietHours *QuietHours `json:"quiet_hours,omitempty"`
+    // Escalations maps endpoint tags to escalation ladders: notifiers that
+    // are also notified while an endpoint stays down. An endpoint uses the
+    // ladder of its first tag that has one, or the "*" ladder.
+    Escalations map[string][]EscalationStep `json:"escalations,omitempty"`
+}
+
+// EscalationStep notifies Notifiers once an endpoint has been down for After
+type EscalationStep struct {
+    After     Duration `json:"after"`
+    Notifiers []string `json:"notifiers"`
+}
+
+// QuietHours is a daily period, from Start to End as "HH:MM" in Timezone
+// (local time when empty), during which non-critical notifications are
+// held. A period whose end is before its start runs past midnight.
+type QuietHours struct {
+    Start    string `json:"start"`
+    End      string `json:"end"`
+    Timezone string `json:"timezone,omitempty"`
+}
+
+// CriticalTag marks endpoints whose notifications bypass quiet hours
+const CriticalTag = "critical"
+
+// RouteFallback is the Routes and Escalations key used for endpoints matching
+// no other tag
+const RouteFallback = "*"
+
+// NotifierConfig configures a single notification destination. The URL may
+// carry credentials, so it can instead be read from a file with a "file://"
+// value or URLFile.
+type NotifierConfig struct {
+    Name    string   `json:"name"`
+    Type    string   `json:"type"`
+    URL     string   `json:"url,omitempty"`
+    URLFile string   `json:"url_file,omitempty"`
+    Timeout Duration `json:"timeout,omitempty"`
+    // Template is a text/template that replaces the default message, e.g.
+    // "{{.Name}} ({{.URL}}) is {{.Status}} after {{.Downtime}}"
+    Template string `json:"template,omitempty"`
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint
+type MetricsConfig struct {
+    Enabled bool   `json:"enabled"`
+    Address string `json:"address,omitempty"`
+    // Buckets are the response-time histogram bounds in seconds
+    Buckets []float64 `json:"buckets,omitempty"`
+    // UpStatus selects what monitord_endpoint_up reports: "raw" (the
+    // default) follows the last check, "confirmed" the status confirmed by
+    // failure_threshold, as used for alerting
+    UpStatus string `json:"up_status,omitempty"`
+    // SelfMetrics adds series about monitord itself, such as goroutines,
+    // save latency and notifications sent (defaults to true)
+    SelfMetrics *bool `json:"self_metrics,omitempty"`
+    // Auth requires credentials to scrape metrics, separate from the API's
+    Auth *ServerAuth `json:"auth,omitempty"`
+    ServerTimeouts
+}
+
+// ReportsSelf reports whether the metrics include monitord's own
+func (m MetricsConfig) ReportsSelf() bool {
+    return m.SelfMetrics == nil || *m.SelfMetrics
+}
+
+// Values accepted by MetricsConfig.UpStatus
+const (
+    UpStatusRaw       = "raw"
+    UpStatusConfirmed = "confirmed"
+)
+
+// APIConfig configures the HTTP API used to inspect and control the daemon
+type APIConfig struct {
+    Enabled bool `json:"enabled"`
+    // Address is a host and port, or "unix:" followed by the path of a
+    // Unix socket for local-only access
+    Address string `json:"address,omitempty"`
+    // Addresses are further addresses to serve the API on, in the same forms
+    Addresses []string `json:"addresses,omitempty"`
+    // TLSCertFile and TLSKeyFile are PEM files that, when both are set, serve
+    // the API over HTTPS on its TCP addresses. Unix sockets stay plain HTTP.
+    TLSCertFile string `json:"tls_cert_file,omitempty"`
+    TLSKeyFile  string `json:"tls_key_file,omitempty"`
+    // Auth requires credentials on every API request
+    Auth *ServerAuth `json:"auth,omitempty"`
+    // StatusPage serves an HTML status page at / for people without API
+    // tooling, titled StatusPageTitle
+    StatusPage      bool   `json:"status_page,omitempty"`
+    StatusPageTitle string `json:"status_page_title,omitempty"`
+    ServerTimeouts
+}
+
+// ServerAuth is the credentials a server requires: a bearer token, a basic
+// auth username and password, or either one when both are set
+type ServerAuth struct {
+    Token        string `json:"token,omitempty"`
+    TokenFile    string `json:"token_file,omitempty"`
+    Username     string `json:"username,omitempty"`
+    Password     string `json:"password,omitempty"`
+    PasswordFile string `json:"password_file,omitempty"`
+    // ExemptHealthz lets the API's /healthz through without credentials,
+    // for load balancer and orchestrator probes
+    ExemptHealthz bool `json:"exempt_healthz,omitempty"`
+    // PublicStatus lets the API's GET /status and status page through
+    // without credentials, leaving out private endpoints
+    PublicStatus bool `json:"public_status,omitempty"`
+}
+
+// UnixSocketPrefix marks an API address as the path of a Unix socket
+const UnixSocketPrefix = "unix:"
+
+// ServerTimeouts bound how long the API and metrics servers wait on a
+// client, so slow or stalled connections cannot hold them open. Zero values
+// use the defaults.
+type ServerTimeouts struct {
+    // ReadTimeout covers reading a request, headers and body
+    ReadTimeout Duration `json:"read_timeout,omitempty"`
+    // WriteTimeout covers handling a request and writing the response
+    WriteTimeout Duration `json:"write_timeout,omitempty"`
+    // IdleTimeout closes keep-alive connections idle for this long
+    IdleTimeout Duration `json:"idle_timeout,omitempty"`
 }
 
 // Add this custom type and methods
@@ -63,27 +783,64 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
     }
 }
 
+// MarshalJSON writes durations in the same string form accepted by
+// UnmarshalJSON (e.g. "1m0s") so saved configs load back unchanged
+func (d Duration) MarshalJSON() ([]byte, error) {
+    return json.Marshal(time.Duration(d).String())
+}
+
 // Add this method to convert Duration to time.Duration
 func (d Duration) ToDuration() time.Duration {
     return time.Duration(d)
 }
 
-// Load reads configuration from the default location
-func Load() (*Config, error) {
+// DefaultPath returns the location of the configuration file
+func DefaultPath() (string, error) {
     homeDir, err := os.UserHomeDir()
     if err != nil {
-        return nil, err
+        return "", err
     }
+    return filepath.Join(homeDir, ".config/monitord/config.json"), nil
+}
+
+// NoExampleConfigEnv names the environment variable that, when true, makes
+// Load fail instead of creating an example config
+const NoExampleConfigEnv = "MONITORD_NO_EXAMPLE_CONFIG"
 
-    configPath := filepath.Join(homeDir, ".config/monitord/config.json")
+// ErrNoConfig is returned by Load when there is no config file and creating
+// an example one is disabled
+var ErrNoConfig = errors.New("no config found")
+
+// exampleConfigDisabled reports whether NoExampleConfigEnv is set. Any value
+// other than an explicit false disables the example config.
+func exampleConfigDisabled() bool {
+    value := os.Getenv(NoExampleConfigEnv)
+    if value == "" {
+        return false
+    }
+    disabled, err := strconv.ParseBool(value)
+    return err != nil || disabled
+}
+
+// Load reads configuration from the default location. If the file does not
+// exist, an example config is written there first unless NoExampleConfigEnv
+// is set.
+func Load() (*Config, error) {
+    configPath, err := DefaultPath()
+    if err != nil {
+        return nil, err
+    }
 
     // Check if config file exists
     if _, err := os.Stat(configPath); os.IsNotExist(err) {
+        if exampleConfigDisabled() {
+            return nil, fmt.Errorf("%w at %s", ErrNoConfig, configPath)
+        }
         // Create example config if file doesn't exist
         if err := SaveExampleConfig(configPath); err != nil {
             return nil, fmt.Errorf("failed to create example config: %w", err)
         }
-        fmt.Printf("Created example config at: %s\n", configPath)
+        fmt.Fprintf(os.Stderr, "Created example config at: %s\n", configPath)
     }
 
     return LoadFromFile(configPath)
@@ -91,24 +848,33 @@ func Load() (*Config, error) {
 
 // LoadFromFile reads configuration from a specific file
 func LoadFromFile(path string) (*Config, error) {
-    fmt.Printf("Loading config from: %s\n", path)
+    fmt.Fprintf(os.Stderr, "Loading config from: %s\n", path)
 
-    data, err := os.ReadFile(path)
+    config, err := ReadFile(path)
     if err != nil {
-        fmt.Printf("Error reading config file: %v\n", err)
+        fmt.Fprintf(os.Stderr, "Error reading config file: %v\n", err)
         return nil, err
     }
 
-    var config Config
-    if err := json.Unmarshal(data, &config); err != nil {
-        fmt.Printf("Error parsing config file: %v\n", err)
+    if err := config.resolveSecrets(); err != nil {
+        fmt.Fprintf(os.Stderr, "Error resolving config secrets: %v\n", err)
+        return nil, err
+    }
+
+    if err := config.resolveBodies(); err != nil {
+        fmt.Fprintf(os.Stderr, "Error reading request bodies: %v\n", err)
+        return nil, err
+    }
+
+    if err := config.Validate(); err != nil {
+        fmt.Fprintf(os.Stderr, "Invalid config file: %v\n", err)
         return nil, err
     }
 
     // Handle relative paths by joining with home directory
     homeDir, err := os.UserHomeDir()
     if err != nil {
-        fmt.Printf("Error getting user home directory: %v\n", err)
+        fmt.Fprintf(os.Stderr, "Error getting user home directory: %v\n", err)
         return nil, err
     }
 
@@ -116,27 +882,45 @@ func LoadFromFile(path string) (*Config, error) {
     if !filepath.IsAbs(config.Database.Path) {
         config.Database.Path = filepath.Join(homeDir, config.Database.Path)
     }
+    if config.Database.ArchiveDir != "" && !filepath.IsAbs(config.Database.ArchiveDir) {
+        config.Database.ArchiveDir = filepath.Join(homeDir, config.Database.ArchiveDir)
+    }
 
     // If log path is relative, make it absolute
     if !filepath.IsAbs(config.Logging.Path) {
         config.Logging.Path = filepath.Join(homeDir, config.Logging.Path)
     }
 
+    return config, nil
+}
+
+// ReadFile parses a configuration file as written, without resolving paths
+// or secrets or validating it. Use it when the file will be modified and
+// saved back.
+func ReadFile(path string) (*Config, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var config Config
+    if err := json.Unmarshal(data, &config); err != nil {
+        return nil, err
+    }
     return &config, nil
 }
 
 // Save writes the configuration to the default location
 func (c *Config) Save() error {
-    homeDir, err := os.UserHomeDir()
+    configPath, err := DefaultPath()
     if err != nil {
         return err
     }
-
-    configPath := filepath.Join(homeDir, ".config/monitord/config.json")
     return c.SaveToFile(configPath)
 }
 
-// SaveToFile writes the configuration to a specific file
+// SaveToFile writes the configuration to a specific file. A new file is only
+// readable by its owner, since the config may contain secrets.
 func (c *Config) SaveToFile(path string) error {
     if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
         return err
@@ -147,7 +931,7 @@ func (c *Config) SaveToFile(path string) error {
         return err
     }
 
-    return os.WriteFile(path, data, 0644)
+    return os.WriteFile(path, data, 0600)
 }
 
 // SaveExampleConfig creates a default configuration file at the specified path
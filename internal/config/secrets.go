@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// secretFilePrefix marks a secret value that should be read from a file, as
+// with Docker and Kubernetes secrets mounted into the container
+const secretFilePrefix = "file://"
+
+// resolveSecrets replaces secret-bearing fields with the contents of the files
+// they reference. Secrets may be given inline, as a "file://" path, or through
+// the field's companion *_file setting.
+func (c *Config) resolveSecrets() error {
+	var errs []error
+	for i := range c.Notifications.Notifiers {
+		notifier := &c.Notifications.Notifiers[i]
+		if err := resolveSecret(&notifier.URL, notifier.URLFile); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %q: url: %w", notifier.Name, err))
+		}
+	}
+	if heartbeat := c.Monitor.Heartbeat; heartbeat != nil {
+		if err := resolveSecret(&heartbeat.URL, ""); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: heartbeat: url: %w", err))
+		}
+	}
+	if calendar := c.Monitor.MaintenanceCalendar; calendar != nil {
+		if err := resolveSecret(&calendar.URL, ""); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: maintenance_calendar: url: %w", err))
+		}
+	}
+	if remote := c.Monitor.RemoteEndpoints; remote != nil {
+		if err := resolveSecret(&remote.URL, ""); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: remote_endpoints: url: %w", err))
+		}
+	}
+	errs = append(errs, resolveHeaderSecrets(c.Monitor.GlobalHeaders, "monitor: global_headers")...)
+	for i := range c.Monitor.Endpoints {
+		endpoint := &c.Monitor.Endpoints[i]
+		label := fmt.Sprintf("endpoint %q", endpoint.Name)
+		errs = append(errs, resolveHeaderSecrets(endpoint.Headers, label+": headers")...)
+		if pre := endpoint.PreRequest; pre != nil {
+			errs = append(errs, resolveHeaderSecrets(pre.Headers, label+": pre_request: headers")...)
+			if err := resolveSecret(&pre.Body, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: pre_request: body: %w", label, err))
+			}
+		}
+		for j := range endpoint.Steps {
+			step := &endpoint.Steps[j]
+			stepLabel := fmt.Sprintf("%s: steps: %s", label, step.Label(j))
+			errs = append(errs, resolveHeaderSecrets(step.Headers, stepLabel+": headers")...)
+			if err := resolveSecret(&step.Body, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s: body: %w", stepLabel, err))
+			}
+		}
+	}
+	for _, server := range []struct {
+		name string
+		auth *ServerAuth
+	}{{"api", c.API.Auth}, {"metrics", c.Metrics.Auth}} {
+		if server.auth == nil {
+			continue
+		}
+		if err := resolveSecret(&server.auth.Token, server.auth.TokenFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s: auth: token: %w", server.name, err))
+		}
+		if err := resolveSecret(&server.auth.Password, server.auth.PasswordFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s: auth: password: %w", server.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveHeaderSecrets replaces header values given as "file://" paths,
+// labelling errors with field
+func resolveHeaderSecrets(headers map[string]string, field string) []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		value := headers[name]
+		if err := resolveSecret(&value, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", field, name, err))
+			continue
+		}
+		headers[name] = value
+	}
+	return errs
+}
+
+// resolveSecret sets value from the referenced file, if any
+func resolveSecret(value *string, file string) error {
+	path, fromPrefix := strings.CutPrefix(*value, secretFilePrefix)
+	switch {
+	case fromPrefix && file != "":
+		return errors.New("use either a file:// value or the _file setting, not both")
+	case fromPrefix:
+	case file != "" && *value != "":
+		return errors.New("use either an inline value or the _file setting, not both")
+	case file != "":
+		path = file
+	default:
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return fmt.Errorf("secret file %s is empty", path)
+	}
+	*value = secret
+	return nil
+}
+
+// redactedValue replaces secrets in configuration shown to users
+const redactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the configuration with secret values masked,
+// suitable for display. Header values and the request bodies of endpoints,
+// pre-requests and transaction steps are masked too, as they commonly carry
+// credentials.
+func (c Config) Redacted() Config {
+	c.Monitor.GlobalHeaders = redactHeaders(c.Monitor.GlobalHeaders)
+	c.Monitor.Endpoints = append([]Endpoint(nil), c.Monitor.Endpoints...)
+	for i := range c.Monitor.Endpoints {
+		c.Monitor.Endpoints[i] = c.Monitor.Endpoints[i].redacted()
+	}
+	c.Notifications.Notifiers = append([]NotifierConfig(nil), c.Notifications.Notifiers...)
+	for i := range c.Notifications.Notifiers {
+		if c.Notifications.Notifiers[i].URL != "" {
+			c.Notifications.Notifiers[i].URL = redactedValue
+		}
+	}
+	if heartbeat := c.Monitor.Heartbeat; heartbeat != nil {
+		redacted := *heartbeat
+		redacted.URL = redactedValue
+		c.Monitor.Heartbeat = &redacted
+	}
+	if calendar := c.Monitor.MaintenanceCalendar; calendar != nil {
+		redacted := *calendar
+		redacted.URL = redactedValue
+		c.Monitor.MaintenanceCalendar = &redacted
+	}
+	if remote := c.Monitor.RemoteEndpoints; remote != nil {
+		redacted := *remote
+		redacted.URL = redactedValue
+		c.Monitor.RemoteEndpoints = &redacted
+	}
+	c.API.Auth = c.API.Auth.redacted()
+	c.Metrics.Auth = c.Metrics.Auth.redacted()
+	return c
+}
+
+// redacted returns a copy of the endpoint with its header values, its body
+// and the bodies and headers of its pre-request and steps masked
+func (e Endpoint) redacted() Endpoint {
+	e.Headers = redactHeaders(e.Headers)
+	e.Body = redact(e.Body)
+	if pre := e.PreRequest; pre != nil {
+		redacted := *pre
+		redacted.Headers = redactHeaders(redacted.Headers)
+		redacted.Body = redact(redacted.Body)
+		e.PreRequest = &redacted
+	}
+	e.Steps = append([]TransactionStep(nil), e.Steps...)
+	for i := range e.Steps {
+		e.Steps[i].Headers = redactHeaders(e.Steps[i].Headers)
+		e.Steps[i].Body = redact(e.Steps[i].Body)
+	}
+	return e
+}
+
+// redactHeaders returns a copy of headers with every value masked, keeping
+// the names so it is clear which are sent
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		redacted[name] = redact(value)
+	}
+	return redacted
+}
+
+// redact masks a value unless it is empty
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redacted returns a copy of the credentials with the secrets masked
+func (a *ServerAuth) redacted() *ServerAuth {
+	if a == nil {
+		return nil
+	}
+	redacted := *a
+	if redacted.Token != "" {
+		redacted.Token = redactedValue
+	}
+	if redacted.Password != "" {
+		redacted.Password = redactedValue
+	}
+	return &redacted
+}
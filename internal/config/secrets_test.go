@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveHeaderAndBodySecrets(t *testing.T) {
+	dir := t.TempDir()
+	secret := func(name, value string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return secretFilePrefix + path
+	}
+
+	c := Config{Monitor: MonitorConfig{
+		GlobalHeaders: map[string]string{"Authorization": secret("global", "Bearer global"), "X-Monitor": "monitord"},
+		Endpoints: []Endpoint{{
+			Name:       "api",
+			Headers:    map[string]string{"X-Api-Key": secret("endpoint", "endpoint-key")},
+			PreRequest: &PreRequest{Headers: map[string]string{"X-Login": secret("login", "login-key")}, Body: secret("login-body", `{"password": "p"}`)},
+			Steps:      []TransactionStep{{Headers: map[string]string{"X-Step": secret("step", "step-key")}, Body: secret("step-body", "step-body")}},
+		}},
+	}}
+	if err := c.resolveSecrets(); err != nil {
+		t.Fatalf("resolveSecrets: %v", err)
+	}
+
+	endpoint := c.Monitor.Endpoints[0]
+	for _, tt := range []struct{ field, got, want string }{
+		{"global_headers", c.Monitor.GlobalHeaders["Authorization"], "Bearer global"},
+		{"inline global header", c.Monitor.GlobalHeaders["X-Monitor"], "monitord"},
+		{"headers", endpoint.Headers["X-Api-Key"], "endpoint-key"},
+		{"pre_request headers", endpoint.PreRequest.Headers["X-Login"], "login-key"},
+		{"pre_request body", endpoint.PreRequest.Body, `{"password": "p"}`},
+		{"step headers", endpoint.Steps[0].Headers["X-Step"], "step-key"},
+		{"step body", endpoint.Steps[0].Body, "step-body"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	missing := Config{Monitor: MonitorConfig{Endpoints: []Endpoint{{
+		Name:    "api",
+		Headers: map[string]string{"Authorization": secretFilePrefix + filepath.Join(dir, "missing")},
+	}}}}
+	err := missing.resolveSecrets()
+	if err == nil || !strings.Contains(err.Error(), `endpoint "api": headers: Authorization: reading secret file`) {
+		t.Fatalf("resolveSecrets() = %v, want the missing file reported with the header", err)
+	}
+}
+
+func TestRemoteEndpointsURLIsSecret(t *testing.T) {
+	const url = "https://discovery.internal/endpoints?token=remote-secret"
+	path := filepath.Join(t.TempDir(), "remote")
+	if err := os.WriteFile(path, []byte(url), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := Config{Monitor: MonitorConfig{RemoteEndpoints: &RemoteEndpointsConfig{URL: secretFilePrefix + path}}}
+	if err := c.resolveSecrets(); err != nil {
+		t.Fatalf("resolveSecrets: %v", err)
+	}
+	if c.Monitor.RemoteEndpoints.URL != url {
+		t.Fatalf("url = %q, want %q", c.Monitor.RemoteEndpoints.URL, url)
+	}
+
+	if got := c.Redacted().Monitor.RemoteEndpoints.URL; got != redactedValue {
+		t.Errorf("redacted url = %q", got)
+	}
+	if c.Monitor.RemoteEndpoints.URL != url {
+		t.Error("Redacted changed the running config")
+	}
+}
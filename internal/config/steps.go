@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+var (
+	// stepVariable matches a reference to a captured variable, such as
+	// {{id}}
+	stepVariable = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+	// variableName matches the names variables may be captured as
+	variableName = regexp.MustCompile(`^\w+$`)
+)
+
+// ExpandStepVariables replaces the {{name}} references in s with the values
+// in vars, passed through escape when it is not nil. References to unknown
+// variables are left as they are.
+func ExpandStepVariables(s string, vars map[string]string, escape func(string) string) string {
+	return stepVariable.ReplaceAllStringFunc(s, func(ref string) string {
+		value, ok := vars[stepVariable.FindStringSubmatch(ref)[1]]
+		if !ok {
+			return ref
+		}
+		if escape != nil {
+			return escape(value)
+		}
+		return value
+	})
+}
+
+// validateSteps checks an endpoint's transaction steps, including that each
+// variable used is captured by an earlier step
+func (e Endpoint) validateSteps() []error {
+	if len(e.Steps) == 0 {
+		return nil
+	}
+	var errs []error
+	if e.Type != "" && e.Type != EndpointTypeHTTP {
+		errs = append(errs, errors.New("steps are only used by http endpoints"))
+	}
+	if e.Method != "" || e.Body != "" {
+		errs = append(errs, errors.New("method and body are set on each step of endpoints with steps"))
+	}
+	if e.ExpectInaccessible || e.ExpectRedirectTo != "" {
+		errs = append(errs, errors.New("steps cannot be used with expect_inaccessible or expect_redirect_to"))
+	}
+
+	captured := make(map[string]bool)
+	for i, step := range e.Steps {
+		label := step.Label(i)
+		if step.Path == "" {
+			errs = append(errs, fmt.Errorf("%s: path is required", label))
+		}
+		for _, err := range validateRequest(step.Method, step.Headers) {
+			errs = append(errs, fmt.Errorf("%s: %w", label, err))
+		}
+		if step.ExpectStatus != 0 && (step.ExpectStatus < 100 || step.ExpectStatus > 599) {
+			errs = append(errs, fmt.Errorf("%s: expect_status must be between 100 and 599, got %d", label, step.ExpectStatus))
+		}
+
+		used := []string{step.Path, step.Body}
+		for _, value := range step.Headers {
+			used = append(used, value)
+		}
+		var unknown []string
+		for _, value := range used {
+			for _, match := range stepVariable.FindAllStringSubmatch(value, -1) {
+				if !captured[match[1]] && !slices.Contains(unknown, match[1]) {
+					unknown = append(unknown, match[1])
+				}
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			errs = append(errs, fmt.Errorf("%s: {{%s}} is not captured by an earlier step", label, name))
+		}
+
+		names := make([]string, 0, len(step.Capture))
+		for name := range step.Capture {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch {
+			case !variableName.MatchString(name):
+				errs = append(errs, fmt.Errorf("%s: capture name %q must be letters, digits and underscores", label, name))
+			case step.Capture[name] == "":
+				errs = append(errs, fmt.Errorf("%s: capture %s needs the field to capture", label, name))
+			}
+			captured[name] = true
+		}
+	}
+	return errs
+}
@@ -0,0 +1,763 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MaxCaptureBodyLimit bounds the memory held per endpoint by capture_body
+const MaxCaptureBodyLimit = 1 << 20
+
+// Validate reports every problem with the configuration that would stop
+// monitord from running it correctly
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Database.HeartbeatInterval < 0 || c.Database.ResponseTimeDelta < 0 {
+		errs = append(errs, errors.New("database: heartbeat_interval and response_time_delta must not be negative"))
+	}
+	if c.Logging.SlowCheckThreshold < 0 {
+		errs = append(errs, errors.New("logging: slow_check_threshold must not be negative"))
+	}
+	switch c.Logging.Output {
+	case "", LogOutputStdout, LogOutputFile:
+	default:
+		errs = append(errs, fmt.Errorf("logging: output must be %q or %q, got %q", LogOutputStdout, LogOutputFile, c.Logging.Output))
+	}
+	if c.Logging.MaxSizeMB < 0 || c.Logging.MaxBackups < 0 || c.Logging.MaxAgeDays < 0 {
+		errs = append(errs, errors.New("logging: max_size_mb, max_backups and max_age_days must not be negative"))
+	}
+	if _, err := ParseFileMode(c.Logging.FileMode); err != nil {
+		errs = append(errs, fmt.Errorf("logging: file_mode: %w", err))
+	}
+	if c.Logging.Output == LogOutputFile && c.Logging.Path == "" {
+		errs = append(errs, errors.New("logging: output \"file\" requires a path"))
+	}
+	if c.Database.VacuumInterval < 0 {
+		errs = append(errs, errors.New("database: vacuum_interval must not be negative"))
+	}
+	if c.Database.PurgeRemovedAfter != 0 && c.Database.PurgeRemovedAfter < MinPurgeRemovedAfter {
+		errs = append(errs, errors.New("database: purge_removed_after must be at least 24h, if set"))
+	}
+	if _, err := ParseFileMode(c.Database.FileMode); err != nil {
+		errs = append(errs, fmt.Errorf("database: file_mode: %w", err))
+	}
+	if c.Database.SaveQueue < 0 {
+		errs = append(errs, errors.New("database: save_queue must not be negative"))
+	}
+	if c.Database.CompressOver < 0 {
+		errs = append(errs, errors.New("database: compress_over must not be negative"))
+	}
+	if c.Database.StartupBuffer < 0 {
+		errs = append(errs, errors.New("database: startup_buffer must not be negative"))
+	}
+	if archive := c.Database.ArchiveCommand; len(archive) > 0 {
+		if c.Database.ArchiveDir != "" {
+			errs = append(errs, errors.New("database: set archive_dir or archive_command, not both"))
+		}
+		if archive[0] == "" {
+			errs = append(errs, errors.New("database: archive_command needs a program to run"))
+		}
+	}
+	if (c.Database.ArchiveDir != "" || len(c.Database.ArchiveCommand) > 0) && !hasDatePlaceholder(c.Database.Path) {
+		errs = append(errs, errors.New("database: archive_dir and archive_command need %Y, %m or %d in path, as only rotated files are archived"))
+	}
+	switch c.Database.SaveQueuePolicy {
+	case "", SaveQueueBlock, SaveQueueDropSuccesses:
+	default:
+		errs = append(errs, fmt.Errorf("database: save_queue_policy must be %q or %q, got %q",
+			SaveQueueBlock, SaveQueueDropSuccesses, c.Database.SaveQueuePolicy))
+	}
+	switch c.Database.BackendPolicy {
+	case "", BackendBestEffort, BackendRequireAll:
+	default:
+		errs = append(errs, fmt.Errorf("database: backend_policy must be %q or %q, got %q",
+			BackendBestEffort, BackendRequireAll, c.Database.BackendPolicy))
+	}
+	seenBackends := map[string]bool{c.Database.Path: true}
+	for i, backend := range c.Database.Backends {
+		switch {
+		case backend.Path == "":
+			errs = append(errs, fmt.Errorf("database: backend %d requires a path", i+1))
+		case strings.Contains(backend.Path, "://"):
+			// Catches a DSN such as postgres://, which would otherwise
+			// be created as an oddly named SQLite file
+			errs = append(errs, fmt.Errorf("database: backend %d: only SQLite files are supported, not %s", i+1, backend.Path))
+		case seenBackends[backend.Path]:
+			errs = append(errs, fmt.Errorf("database: backend %d: %s is already written to", i+1, backend.Path))
+		}
+		seenBackends[backend.Path] = true
+	}
+	if c.Monitor.ConfigCheck <= 0 {
+		errs = append(errs, errors.New("monitor: config_check_interval must be positive"))
+	}
+	if v := c.Monitor.MinTLSVersion; v != "" {
+		if _, ok := ParseTLSVersion(v); !ok {
+			errs = append(errs, fmt.Errorf("monitor: min_tls_version must be 1.0, 1.1, 1.2 or 1.3, got %q", v))
+		}
+	}
+	if c.Monitor.StartupRamp < 0 {
+		errs = append(errs, errors.New("monitor: startup_ramp must not be negative"))
+	}
+	switch c.Monitor.Scheduler {
+	case "", SchedulerGoroutines, SchedulerHeap:
+	default:
+		errs = append(errs, fmt.Errorf("monitor: scheduler must be %q or %q, got %q", SchedulerGoroutines, SchedulerHeap, c.Monitor.Scheduler))
+	}
+	if c.Monitor.SchedulerWorkers < 0 {
+		errs = append(errs, errors.New("monitor: scheduler_workers must not be negative"))
+	}
+	if c.Monitor.StartupGrace < 0 {
+		errs = append(errs, errors.New("monitor: startup_grace must not be negative"))
+	}
+	if c.Monitor.StaleAfterIntervals < 0 {
+		errs = append(errs, errors.New("monitor: stale_after_intervals must not be negative"))
+	}
+	if c.Monitor.WatchdogInterval < 0 {
+		errs = append(errs, errors.New("monitor: watchdog_interval must not be negative"))
+	}
+	if c.Monitor.DNSCacheTTL < 0 {
+		errs = append(errs, errors.New("monitor: dns_cache_ttl must not be negative"))
+	}
+	if err := c.Monitor.CircuitBreaker.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("monitor: %w", err))
+	}
+	if err := c.Monitor.ResponseBaseline.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("monitor: %w", err))
+	}
+	for i, limit := range c.Monitor.HostRateLimits {
+		if limit.Host == "" {
+			errs = append(errs, fmt.Errorf("monitor: host_rate_limits %d: host is required", i+1))
+		}
+		if limit.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("monitor: host_rate_limits %d: rate must be positive", i+1))
+		}
+		if limit.Burst < 0 {
+			errs = append(errs, fmt.Errorf("monitor: host_rate_limits %d: burst must not be negative", i+1))
+		}
+	}
+	for i, path := range c.Monitor.GeoIPDatabases {
+		if path == "" {
+			errs = append(errs, fmt.Errorf("monitor: geoip_databases %d: path is required", i+1))
+		}
+	}
+	if c.Monitor.CABundle != "" {
+		if _, err := ReadCertificates(c.Monitor.CABundle); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: ca_bundle: %w", err))
+		}
+	}
+
+	for _, err := range validateRequest("", c.Monitor.GlobalHeaders) {
+		errs = append(errs, fmt.Errorf("monitor: global_headers: %w", err))
+	}
+
+	errs = append(errs, validateEndpoints(c.Monitor.Endpoints)...)
+
+	if heartbeat := c.Monitor.Heartbeat; heartbeat != nil {
+		if err := validateURL(heartbeat.URL); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: heartbeat: %w", err))
+		}
+		if heartbeat.Interval < 0 || heartbeat.Timeout < 0 {
+			errs = append(errs, errors.New("monitor: heartbeat: interval and timeout must not be negative"))
+		}
+	}
+
+	if calendar := c.Monitor.MaintenanceCalendar; calendar != nil {
+		if err := validateURL(calendar.URL); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: maintenance_calendar: %w", err))
+		}
+		if calendar.Interval < 0 || calendar.Timeout < 0 {
+			errs = append(errs, errors.New("monitor: maintenance_calendar: interval and timeout must not be negative"))
+		}
+		switch calendar.Match {
+		case "", MaintenanceMatchAll, MaintenanceMatchName, MaintenanceMatchTags:
+		default:
+			errs = append(errs, fmt.Errorf("monitor: maintenance_calendar: match must be %q, %q or %q, got %q",
+				MaintenanceMatchAll, MaintenanceMatchName, MaintenanceMatchTags, calendar.Match))
+		}
+	}
+
+	if remote := c.Monitor.RemoteEndpoints; remote != nil {
+		if err := validateURL(remote.URL); err != nil {
+			errs = append(errs, fmt.Errorf("monitor: remote_endpoints: %w", err))
+		}
+		if remote.Timeout < 0 {
+			errs = append(errs, errors.New("monitor: remote_endpoints: timeout must not be negative"))
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, notifier := range c.Notifications.Notifiers {
+		switch {
+		case notifier.Name == "":
+			errs = append(errs, fmt.Errorf("notifier %d: name is required", i+1))
+		case names[notifier.Name]:
+			errs = append(errs, fmt.Errorf("notifier %q: duplicate name", notifier.Name))
+		}
+		names[notifier.Name] = true
+		if notifier.Template != "" {
+			if _, err := template.New(notifier.Name).Parse(notifier.Template); err != nil {
+				errs = append(errs, fmt.Errorf("notifier %q: invalid template: %w", notifier.Name, err))
+			}
+		}
+	}
+	for tag, route := range c.Notifications.Routes {
+		for _, name := range route {
+			if !names[name] {
+				errs = append(errs, fmt.Errorf("notifications: route %q: unknown notifier %q", tag, name))
+			}
+		}
+	}
+	for tag, steps := range c.Notifications.Escalations {
+		for i, step := range steps {
+			label := fmt.Sprintf("notifications: escalation %q step %d", tag, i+1)
+			switch {
+			case step.After <= 0:
+				errs = append(errs, fmt.Errorf("%s: after must be positive", label))
+			case i > 0 && step.After <= steps[i-1].After:
+				errs = append(errs, fmt.Errorf("%s: after must be later than the previous step", label))
+			}
+			if len(step.Notifiers) == 0 {
+				errs = append(errs, fmt.Errorf("%s: notifiers are required", label))
+			}
+			for _, name := range step.Notifiers {
+				if !names[name] {
+					errs = append(errs, fmt.Errorf("%s: unknown notifier %q", label, name))
+				}
+			}
+		}
+	}
+
+	if canaries := c.Monitor.Canaries; canaries != nil {
+		for _, err := range canaries.validate(c.Monitor, names) {
+			errs = append(errs, fmt.Errorf("monitor: canaries: %w", err))
+		}
+	}
+
+	for _, err := range c.API.validate() {
+		errs = append(errs, fmt.Errorf("api: %w", err))
+	}
+	for _, err := range c.Metrics.ServerTimeouts.validate() {
+		errs = append(errs, fmt.Errorf("metrics: %w", err))
+	}
+	for _, err := range c.Metrics.Auth.validate() {
+		errs = append(errs, fmt.Errorf("metrics: auth: %w", err))
+	}
+	if auth := c.Metrics.Auth; auth != nil && auth.ExemptHealthz {
+		errs = append(errs, errors.New("metrics: auth: exempt_healthz is only used by the api"))
+	}
+	if auth := c.Metrics.Auth; auth != nil && auth.PublicStatus {
+		errs = append(errs, errors.New("metrics: auth: public_status is only used by the api"))
+	}
+
+	if quiet := c.Notifications.QuietHours; quiet != nil {
+		for _, err := range quiet.validate() {
+			errs = append(errs, fmt.Errorf("notifications: quiet_hours: %w", err))
+		}
+	}
+
+	switch c.Metrics.UpStatus {
+	case "", UpStatusRaw, UpStatusConfirmed:
+	default:
+		errs = append(errs, fmt.Errorf("metrics: up_status must be %q or %q, got %q", UpStatusRaw, UpStatusConfirmed, c.Metrics.UpStatus))
+	}
+	for i := 1; i < len(c.Metrics.Buckets); i++ {
+		if c.Metrics.Buckets[i] <= c.Metrics.Buckets[i-1] {
+			errs = append(errs, errors.New("metrics: buckets must be in increasing order"))
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate reports quiet hours that cannot be scheduled
+func (q QuietHours) validate() []error {
+	var errs []error
+	start, err := ParseClock(q.Start)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("start: %w", err))
+	}
+	end, err := ParseClock(q.End)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("end: %w", err))
+	}
+	if len(errs) == 0 && start == end {
+		errs = append(errs, errors.New("start and end must differ"))
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("unknown timezone %q", q.Timezone))
+	}
+	return errs
+}
+
+// ParseClock parses a time of day as "HH:MM" and returns it as the time
+// since midnight
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a time of day like 22:00, got %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseFileMode parses an octal permission mode such as "0600". The mode
+// must let the owner read and write, and an empty value yields 0.
+func ParseFileMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("expected an octal mode like 0600, got %q", value)
+	}
+	if mode&0o600 != 0o600 {
+		return 0, fmt.Errorf("mode %s must allow the owner to read and write", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// validate reports malformed API addresses, an incomplete or unreadable TLS
+// key pair and negative server timeouts
+func (a APIConfig) validate() []error {
+	errs := a.ServerTimeouts.validate()
+	for _, err := range a.Auth.validate() {
+		errs = append(errs, fmt.Errorf("auth: %w", err))
+	}
+	addresses := a.Addresses
+	if a.Address != "" {
+		addresses = append([]string{a.Address}, addresses...)
+	}
+	for _, addr := range addresses {
+		if path, ok := strings.CutPrefix(addr, UnixSocketPrefix); ok {
+			if path == "" {
+				errs = append(errs, fmt.Errorf("address %q: socket path is required", addr))
+			}
+		} else if _, _, err := net.SplitHostPort(addr); err != nil {
+			errs = append(errs, fmt.Errorf("address %q must be host:port or %s/path/to/socket", addr, UnixSocketPrefix))
+		}
+	}
+	switch {
+	case a.TLSCertFile == "" && a.TLSKeyFile == "":
+	case a.TLSCertFile == "" || a.TLSKeyFile == "":
+		errs = append(errs, errors.New("tls_cert_file and tls_key_file must be set together"))
+	default:
+		if _, err := tls.LoadX509KeyPair(a.TLSCertFile, a.TLSKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("tls_cert_file: %w", err))
+		}
+	}
+	return errs
+}
+
+// validate reports server credentials that could never be given
+func (a *ServerAuth) validate() []error {
+	if a == nil {
+		return nil
+	}
+	var errs []error
+	if a.Token == "" && a.Username == "" && a.Password == "" {
+		errs = append(errs, errors.New("token or username and password are required"))
+	}
+	if (a.Username == "") != (a.Password == "") {
+		errs = append(errs, errors.New("username and password must be set together"))
+	}
+	return errs
+}
+
+// validate reports negative server timeouts
+func (t ServerTimeouts) validate() []error {
+	var errs []error
+	if t.ReadTimeout < 0 {
+		errs = append(errs, errors.New("read_timeout must not be negative"))
+	}
+	if t.WriteTimeout < 0 {
+		errs = append(errs, errors.New("write_timeout must not be negative"))
+	}
+	if t.IdleTimeout < 0 {
+		errs = append(errs, errors.New("idle_timeout must not be negative"))
+	}
+	return errs
+}
+
+// ValidateEndpoints reports every problem with a list of endpoints
+func ValidateEndpoints(endpoints []Endpoint) error {
+	return errors.Join(validateEndpoints(endpoints)...)
+}
+
+// ValidateRemoteEndpoints reports every problem with a list fetched from
+// remote_endpoints. Whoever serves the list is not trusted with the
+// monitoring host, so exec endpoints and references to local files are
+// rejected before anything else is validated.
+func ValidateRemoteEndpoints(endpoints []Endpoint) error {
+	var errs []error
+	for i, endpoint := range endpoints {
+		label := endpointLabel(i, endpoint)
+		if endpoint.Type == EndpointTypeExec {
+			errs = append(errs, fmt.Errorf("%s: exec endpoints are not allowed in remote_endpoints", label))
+		}
+		if endpoint.CAFile != "" {
+			errs = append(errs, fmt.Errorf("%s: ca_file is not allowed in remote_endpoints", label))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return ValidateEndpoints(endpoints)
+}
+
+// endpointLabel names the i-th endpoint of a list in errors
+func endpointLabel(i int, endpoint Endpoint) string {
+	if endpoint.Name != "" {
+		return fmt.Sprintf("endpoint %q", endpoint.Name)
+	}
+	return fmt.Sprintf("endpoint %d", i+1)
+}
+
+func validateEndpoints(endpoints []Endpoint) []error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, endpoint := range endpoints {
+		label := endpointLabel(i, endpoint)
+
+		for _, err := range endpoint.validate() {
+			errs = append(errs, fmt.Errorf("%s: %w", label, err))
+		}
+
+		if endpoint.URL != "" {
+			if seen[endpoint.URL] {
+				errs = append(errs, fmt.Errorf("%s: duplicate url %s", label, endpoint.URL))
+			}
+			seen[endpoint.URL] = true
+		}
+	}
+	return errs
+}
+
+// validateURL checks that a URL is absolute http or https
+func validateURL(value string) error {
+	return validateURLScheme(value, "http", "https")
+}
+
+// validateURLScheme checks that a URL is absolute and uses one of two
+// schemes
+func validateURLScheme(value, scheme, secureScheme string) error {
+	if value == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(value)
+	switch {
+	case err != nil:
+		return fmt.Errorf("invalid url: %w", err)
+	case u.Scheme != scheme && u.Scheme != secureScheme:
+		return fmt.Errorf("url scheme must be %s or %s, got %q", scheme, secureScheme, u.Scheme)
+	case u.Host == "":
+		return errors.New("url must include a host")
+	}
+	return nil
+}
+
+// validate checks a single endpoint in isolation
+func (e Endpoint) validate() []error {
+	var errs []error
+
+	switch e.Type {
+	case "", EndpointTypeHTTP:
+		if err := validateURL(e.URL); err != nil {
+			errs = append(errs, err)
+		}
+		if len(e.Command) > 0 {
+			errs = append(errs, errors.New("command is only used by exec endpoints"))
+		}
+	case EndpointTypeExec:
+		// The URL only identifies the endpoint, e.g. "exec://backup-job"
+		if e.URL == "" {
+			errs = append(errs, errors.New("url is required"))
+		}
+		if len(e.Command) == 0 || e.Command[0] == "" {
+			errs = append(errs, errors.New("exec endpoints require a command"))
+		}
+	case EndpointTypePassive:
+		// The URL only identifies the endpoint, e.g. "passive://nightly-export"
+		if e.URL == "" {
+			errs = append(errs, errors.New("url is required"))
+		}
+		if len(e.Command) > 0 {
+			errs = append(errs, errors.New("command is only used by exec endpoints"))
+		}
+	case EndpointTypeWebSocket:
+		if err := validateURLScheme(e.URL, "ws", "wss"); err != nil {
+			errs = append(errs, err)
+		}
+		if len(e.Command) > 0 {
+			errs = append(errs, errors.New("command is only used by exec endpoints"))
+		}
+		if e.HTTPVersion == HTTPVersion2 {
+			errs = append(errs, errors.New("websocket endpoints cannot require http_version 2"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("type must be %q, %q, %q or %q, got %q",
+			EndpointTypeHTTP, EndpointTypeExec, EndpointTypeWebSocket, EndpointTypePassive, e.Type))
+	}
+	if e.Grace < 0 {
+		errs = append(errs, errors.New("grace must not be negative"))
+	} else if e.Grace > 0 && e.Type != EndpointTypePassive {
+		errs = append(errs, errors.New("grace is only used by passive endpoints"))
+	}
+	if e.CAFile != "" {
+		if !e.SendsRequests() {
+			errs = append(errs, fmt.Errorf("ca_file is not used by %s endpoints", e.Type))
+		} else if _, err := ReadCertificates(e.CAFile); err != nil {
+			errs = append(errs, fmt.Errorf("ca_file: %w", err))
+		}
+	}
+	switch {
+	case e.MaxRedirects == 0:
+	case e.Type != EndpointTypeHTTP && e.Type != "":
+		errs = append(errs, errors.New("max_redirects is only used by http endpoints"))
+	case e.ExpectRedirectTo != "":
+		errs = append(errs, errors.New("max_redirects cannot be used with expect_redirect_to, which does not follow redirects"))
+	case e.MaxRedirects < 0 || e.MaxRedirects >= RedirectLimit:
+		errs = append(errs, fmt.Errorf("max_redirects must be between 0 and %d, as checks fail after %d redirects",
+			RedirectLimit-1, RedirectLimit))
+	}
+	if e.DebugTrace && e.Type != EndpointTypeHTTP && e.Type != "" {
+		errs = append(errs, errors.New("debug_trace is only used by http endpoints"))
+	}
+	if e.WebSocketPing && e.Type != EndpointTypeWebSocket {
+		errs = append(errs, errors.New("websocket_ping is only used by websocket endpoints"))
+	}
+
+	if e.Interval <= 0 {
+		errs = append(errs, errors.New("interval must be positive"))
+	}
+	if e.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must not be negative"))
+	}
+	if e.ConnectTimeout < 0 {
+		errs = append(errs, errors.New("connect_timeout must not be negative"))
+	}
+	if e.Timeout > 0 && e.ConnectTimeout > e.Timeout {
+		errs = append(errs, errors.New("connect_timeout must not exceed timeout"))
+	}
+	if e.DNSCacheTTL < 0 {
+		errs = append(errs, errors.New("dns_cache_ttl must not be negative"))
+	}
+	if err := e.CircuitBreaker.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if e.ResponseBaseline != nil && !e.SendsRequests() {
+		errs = append(errs, fmt.Errorf("response_baseline is not used by %s endpoints", e.Type))
+	} else if err := e.ResponseBaseline.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if e.AlertCooldown < 0 {
+		errs = append(errs, errors.New("alert_cooldown must not be negative"))
+	}
+	if e.FailureThreshold < 0 {
+		errs = append(errs, errors.New("failure_threshold must not be negative"))
+	}
+	if e.DegradedThreshold < 0 {
+		errs = append(errs, errors.New("degraded_threshold must not be negative"))
+	}
+	if err := e.Relax.validate(e); err != nil {
+		errs = append(errs, err)
+	}
+	if e.CaptureBodyLimit < 0 || e.CaptureBodyLimit > MaxCaptureBodyLimit {
+		errs = append(errs, fmt.Errorf("capture_body_limit must be between 0 and %d bytes", MaxCaptureBodyLimit))
+	}
+	switch e.DetailLevel {
+	case "", DetailMinimal, DetailNormal, DetailVerboseOnFailure:
+	default:
+		errs = append(errs, fmt.Errorf("detail_level must be %q, %q or %q, got %q",
+			DetailMinimal, DetailNormal, DetailVerboseOnFailure, e.DetailLevel))
+	}
+	if e.DecodedBodyLimit < 0 {
+		errs = append(errs, errors.New("decoded_body_limit must not be negative"))
+	}
+	if e.MinSuccessRate < 0 || e.MinSuccessRate > 100 {
+		errs = append(errs, errors.New("min_success_rate must be a percentage between 0 and 100"))
+	}
+	if e.SuccessRateWindow < 0 {
+		errs = append(errs, errors.New("success_rate_window must not be negative"))
+	}
+
+	switch e.HTTPVersion {
+	case "", HTTPVersion1:
+	case HTTPVersion2:
+		if strings.HasPrefix(e.URL, "http:") {
+			errs = append(errs, errors.New("http_version 2 requires an https url"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("http_version must be %q or %q, got %q", HTTPVersion1, HTTPVersion2, e.HTTPVersion))
+	}
+	errs = append(errs, validateRequest(e.Method, e.Headers)...)
+	if e.ExpectContinueTimeout < 0 {
+		errs = append(errs, errors.New("expect_continue_timeout must not be negative"))
+	}
+	if e.ExpectContinueTimeout > 0 && e.Body == "" {
+		errs = append(errs, errors.New("expect_continue_timeout requires a body"))
+	}
+	if e.ChunkedBody && e.Body == "" {
+		errs = append(errs, errors.New("chunked_body requires a body"))
+	}
+	if pre := e.PreRequest; pre != nil {
+		if err := validateURL(pre.URL); err != nil {
+			errs = append(errs, fmt.Errorf("pre_request: %w", err))
+		}
+		for _, err := range validateRequest(pre.Method, pre.Headers) {
+			errs = append(errs, fmt.Errorf("pre_request: %w", err))
+		}
+	}
+	for _, err := range e.validateSteps() {
+		errs = append(errs, fmt.Errorf("steps: %w", err))
+	}
+	switch e.IPVersion {
+	case "", IPVersionAuto, IPVersion4, IPVersion6, IPVersionBoth:
+	default:
+		errs = append(errs, fmt.Errorf("ip_version must be %q, %q, %q or %q, got %q", IPVersionAuto, IPVersion4, IPVersion6, IPVersionBoth, e.IPVersion))
+	}
+	if e.MinTLSVersion != "" {
+		if _, ok := ParseTLSVersion(e.MinTLSVersion); !ok {
+			errs = append(errs, fmt.Errorf("min_tls_version must be 1.0, 1.1, 1.2 or 1.3, got %q", e.MinTLSVersion))
+		}
+	}
+	if v, ok := strings.CutPrefix(e.ExpectRedirectTo, "prefix:"); ok && v == "" {
+		errs = append(errs, errors.New("expect_redirect_to prefix must not be empty"))
+	} else if v, ok := strings.CutPrefix(e.ExpectRedirectTo, "glob:"); ok && v == "" {
+		errs = append(errs, errors.New("expect_redirect_to glob must not be empty"))
+	}
+	if e.ExpectProtocol != "" {
+		if _, ok := NormalizeProtocol(e.ExpectProtocol); !ok {
+			errs = append(errs, fmt.Errorf("expect_protocol %q is not an HTTP version", e.ExpectProtocol))
+		}
+	}
+
+	for name, value := range e.ExpectHeaders {
+		if pattern, ok := strings.CutPrefix(value, "regex:"); ok {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("expect_headers %s: %w", name, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateRequest checks the method and header names of a request
+func validateRequest(method string, headers map[string]string) []error {
+	var errs []error
+	if method != "" && !validToken(method) {
+		errs = append(errs, fmt.Errorf("method %q is not a valid HTTP method", method))
+	}
+	for name := range headers {
+		if !validToken(name) {
+			errs = append(errs, fmt.Errorf("header name %q is invalid", name))
+		}
+	}
+	return errs
+}
+
+// validToken reports whether s is an HTTP token, as required of methods and
+// header names
+func validToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDatePlaceholder reports whether a database path names one file per
+// period
+func hasDatePlaceholder(path string) bool {
+	return strings.Contains(path, "%Y") || strings.Contains(path, "%m") || strings.Contains(path, "%d")
+}
+
+// validate checks a circuit breaker's settings, if one is set
+func (b *CircuitBreaker) validate() error {
+	switch {
+	case b == nil:
+		return nil
+	case b.FailureThreshold < 0 || b.SuccessThreshold < 0:
+		return errors.New("circuit_breaker: failure_threshold and success_threshold must not be negative")
+	case b.Multiplier != 0 && b.Multiplier <= 1:
+		return errors.New("circuit_breaker: multiplier must be greater than 1")
+	case b.MaxInterval < 0:
+		return errors.New("circuit_breaker: max_interval must not be negative")
+	}
+	return nil
+}
+
+// validate checks an endpoint's relax settings, if set, which may not
+// tighten the endpoint's own
+func (r *RelaxSettings) validate(e Endpoint) error {
+	switch {
+	case r == nil:
+		return nil
+	case r.Timeout < 0 || r.FailureThreshold < 0 || r.DegradedThreshold < 0:
+		return errors.New("relax: timeout, failure_threshold and degraded_threshold must not be negative")
+	case r.Timeout != 0 && r.Timeout < e.Timeout:
+		return errors.New("relax: timeout must not be shorter than the endpoint's timeout")
+	case r.FailureThreshold != 0 && r.FailureThreshold < e.FailureThreshold,
+		r.DegradedThreshold != 0 && r.DegradedThreshold < e.DegradedThreshold:
+		return errors.New("relax: failure_threshold and degraded_threshold must not be lower than the endpoint's")
+	}
+	return nil
+}
+
+// validate checks a response baseline's settings, if one is set
+func (b *ResponseBaseline) validate() error {
+	switch {
+	case b == nil:
+		return nil
+	case b.Multiplier != 0 && b.Multiplier <= 1:
+		return errors.New("response_baseline: multiplier must be greater than 1")
+	case b.Window < 0 || b.Refresh < 0:
+		return errors.New("response_baseline: window and refresh must not be negative")
+	case b.MinSamples < 0:
+		return errors.New("response_baseline: min_samples must not be negative")
+	}
+	return nil
+}
+
+// validate checks the canary settings. Canary URLs must name configured
+// endpoints unless endpoints are also fetched remotely.
+func (c *CanaryConfig) validate(monitor MonitorConfig, notifiers map[string]bool) []error {
+	var errs []error
+	if len(c.URLs) == 0 {
+		errs = append(errs, errors.New("urls are required"))
+	}
+	if monitor.RemoteEndpoints == nil {
+		for _, canary := range c.URLs {
+			if !slices.ContainsFunc(monitor.Endpoints, func(e Endpoint) bool { return e.URL == canary }) {
+				errs = append(errs, fmt.Errorf("%s is not a configured endpoint", canary))
+			}
+		}
+	}
+	switch c.Suppress {
+	case "", CanarySuppressAll, CanarySuppressFailures, CanarySuppressNone:
+	default:
+		errs = append(errs, fmt.Errorf("suppress must be %q, %q or %q, got %q",
+			CanarySuppressAll, CanarySuppressFailures, CanarySuppressNone, c.Suppress))
+	}
+	for _, name := range c.Notifiers {
+		if !notifiers[name] {
+			errs = append(errs, fmt.Errorf("unknown notifier %q", name))
+		}
+	}
+	return errs
+}
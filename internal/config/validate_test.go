@@ -0,0 +1,97 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRemoteEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		wantErr  string
+	}{
+		{
+			name:     "http endpoint",
+			endpoint: Endpoint{Name: "api", URL: "https://example.com/health", Interval: Duration(time.Minute)},
+		},
+		{
+			name:     "exec endpoint",
+			endpoint: Endpoint{Name: "job", Type: EndpointTypeExec, URL: "exec://job", Command: []string{"/bin/sh", "-c", "id"}},
+			wantErr:  "exec endpoints are not allowed in remote_endpoints",
+		},
+		{
+			name:     "ca_file",
+			endpoint: Endpoint{Name: "api", URL: "https://example.com/health", CAFile: "/etc/ssl/private/ca.pem"},
+			wantErr:  "ca_file is not allowed in remote_endpoints",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRemoteEndpoints([]Endpoint{tt.endpoint})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateEndpointsAllowsExec(t *testing.T) {
+	endpoints := []Endpoint{{Type: EndpointTypeExec, URL: "exec://job", Command: []string{"true"}, Interval: Duration(time.Minute)}}
+	if err := ValidateEndpoints(endpoints); err != nil {
+		t.Fatalf("endpoints from the config file may use exec: %v", err)
+	}
+}
+
+func TestValidateRejectsServerDatabaseBackends(t *testing.T) {
+	cfg := Config{
+		Database: DatabaseConfig{
+			Path:     "/var/lib/monitord/monitord.db",
+			Backends: []DatabaseBackend{{Path: "postgres://monitord@db.internal/monitord"}},
+		},
+		Monitor: MonitorConfig{ConfigCheck: Duration(time.Minute)},
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "only SQLite files are supported") {
+		t.Fatalf("Validate() = %v, want the postgres backend rejected", err)
+	}
+}
+
+func TestPrepareCompilesHeaderPatterns(t *testing.T) {
+	endpoints := []Endpoint{{
+		URL:           "https://example.com/health",
+		Interval:      Duration(time.Minute),
+		ExpectHeaders: map[string]string{"Server": "regex:^nginx/", "Cache-Control": "no-store"},
+	}}
+	if err := ValidateEndpoints(endpoints); err != nil {
+		t.Fatalf("ValidateEndpoints: %v", err)
+	}
+	if endpoints[0].headerPatterns != nil {
+		t.Fatal("ValidateEndpoints changed the endpoint")
+	}
+	endpoints[0].Prepare()
+
+	// Checks reuse the pattern compiled by Prepare
+	first, err := endpoints[0].HeaderPattern("Server")
+	if err != nil {
+		t.Fatalf("HeaderPattern: %v", err)
+	}
+	if second, _ := endpoints[0].HeaderPattern("Server"); second != first {
+		t.Error("HeaderPattern compiled the pattern again")
+	}
+	if !first.MatchString("nginx/1.25") {
+		t.Errorf("pattern %s does not match nginx/1.25", first)
+	}
+
+	endpoints[0].ExpectHeaders["Server"] = "regex:("
+	if err := ValidateEndpoints(endpoints); err == nil || !strings.Contains(err.Error(), "expect_headers Server") {
+		t.Fatalf("ValidateEndpoints() = %v, want the invalid pattern rejected", err)
+	}
+}
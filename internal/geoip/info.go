@@ -0,0 +1,35 @@
+package geoip
+
+import "net"
+
+// Info is the network and location of an address, as far as the database
+// records them. ASN databases fill the first two fields and country or city
+// databases the others.
+type Info struct {
+	ASN          uint64
+	Organization string
+	Country      string
+	City         string
+}
+
+// Info looks up ip and extracts the fields monitord keeps. An address the
+// database has no entry for yields an empty Info.
+func (r *Reader) Info(ip net.IP) (Info, error) {
+	fields, err := r.Lookup(ip)
+	if err != nil || fields == nil {
+		return Info{}, err
+	}
+
+	var info Info
+	info.ASN = toUint(fields["autonomous_system_number"])
+	info.Organization, _ = fields["autonomous_system_organization"].(string)
+	if country, ok := fields["country"].(map[string]any); ok {
+		info.Country, _ = country["iso_code"].(string)
+	}
+	if city, ok := fields["city"].(map[string]any); ok {
+		if names, ok := city["names"].(map[string]any); ok {
+			info.City, _ = names["en"].(string)
+		}
+	}
+	return info, nil
+}
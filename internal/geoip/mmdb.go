@@ -0,0 +1,351 @@
+// Package geoip looks up the network and location of IP addresses in MaxMind
+// DB files, such as GeoLite2-ASN and GeoLite2-City. Only the parts of the
+// format needed for lookups are implemented.
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of the file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the number of zero bytes between the search tree and the
+// data section
+const dataSeparator = 16
+
+// maxDepth bounds how deeply maps, arrays and pointers may nest, so a
+// malformed or hostile file cannot exhaust the stack
+const maxDepth = 64
+
+// errTruncated is returned for a field that runs past the end of the data
+var errTruncated = errors.New("unexpected end of data")
+
+// Data section field types
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+// Reader looks up addresses in a MaxMind DB file held in memory
+type Reader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint
+	dbType     string
+}
+
+// Open reads a MaxMind DB file
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func newReader(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, errors.New("not a MaxMind DB file")
+	}
+	meta, _, err := decode(buf[start+len(metadataMarker):], 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	fields, ok := meta.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid metadata")
+	}
+
+	r := &Reader{
+		buf:        buf,
+		nodeCount:  uint(toUint(fields["node_count"])),
+		recordSize: uint(toUint(fields["record_size"])),
+		ipVersion:  uint(toUint(fields["ip_version"])),
+	}
+	r.dbType, _ = fields["database_type"].(string)
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", r.recordSize)
+	}
+	switch r.ipVersion {
+	case 4, 6:
+	default:
+		return nil, fmt.Errorf("unsupported IP version %d", r.ipVersion)
+	}
+	// Checked before multiplying so a huge node count cannot overflow
+	if r.nodeCount > uint(start) {
+		return nil, errors.New("search tree is larger than the file")
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSeparator > uint(start) {
+		return nil, errors.New("search tree is larger than the file")
+	}
+	r.data = buf[treeSize+dataSeparator : start]
+
+	// IPv4 addresses live under ::/96 in IPv6 databases
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Type returns the database type from the metadata, e.g. "GeoLite2-ASN"
+func (r *Reader) Type() string {
+	return r.dbType
+}
+
+// Lookup returns the data recorded for ip, or nil when the database has no
+// entry for it
+func (r *Reader) Lookup(ip net.IP) (map[string]any, error) {
+	node := uint(0)
+	bits := ip.To16()
+	bitCount := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		bits, bitCount = ip4, 32
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+	if bits == nil {
+		return nil, fmt.Errorf("invalid IP address %v", ip)
+	}
+
+	for i := 0; i < bitCount && node < r.nodeCount; i++ {
+		bit := uint(bits[i>>3]>>(7-uint(i&7))) & 1
+		node = r.record(node, bit)
+	}
+	switch {
+	case node == r.nodeCount:
+		return nil, nil
+	case node < r.nodeCount:
+		return nil, errors.New("invalid search tree")
+	}
+
+	offset := node - r.nodeCount - dataSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, errors.New("invalid data pointer")
+	}
+	value, _, err := decode(r.data, offset)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("record is not a map")
+	}
+	return fields, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a node
+func (r *Reader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.buf[node*8+bit*4:]))
+	}
+}
+
+// decode reads the field at offset in a data section, returning its value
+// and the offset after it
+func decode(data []byte, offset uint) (any, uint, error) {
+	return decodeField(data, offset, 0)
+}
+
+// decodeField decodes the field at offset, nested depth maps, arrays and
+// pointers deep
+func decodeField(data []byte, offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+	if offset >= uint(len(data)) {
+		return nil, 0, errTruncated
+	}
+	ctrl := data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == typePointer {
+		size := uint(ctrl>>3) & 3
+		if offset+size+1 > uint(len(data)) {
+			return nil, 0, errTruncated
+		}
+		b := data[offset : offset+size+1]
+		var target uint
+		switch size {
+		case 0:
+			target = uint(ctrl&7)<<8 | uint(b[0])
+		case 1:
+			target = 2048 + (uint(ctrl&7)<<16 | uint(b[0])<<8 | uint(b[1]))
+		case 2:
+			target = 526336 + (uint(ctrl&7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		default:
+			target = uint(binary.BigEndian.Uint32(b))
+		}
+		// The format does not allow a pointer to point at another one
+		if target < uint(len(data)) && data[target]>>5 == typePointer {
+			return nil, 0, errors.New("pointer to a pointer")
+		}
+		value, _, err := decodeField(data, target, depth+1)
+		return value, offset + size + 1, err
+	}
+
+	if kind == typeExtended {
+		if offset >= uint(len(data)) {
+			return nil, 0, errTruncated
+		}
+		kind = 7 + uint(data[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(data)) {
+			return nil, 0, errTruncated
+		}
+		var n uint
+		for _, b := range data[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		offset += extra
+		switch size {
+		case 29:
+			size = 29 + n
+		case 30:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+
+	// Every map entry takes at least two bytes and every array element one,
+	// so a count larger than the data left is bogus; checking it first
+	// keeps a forged count from allocating
+	remaining := uint(len(data)) - offset
+	switch kind {
+	case typeMap:
+		if size > remaining/2 {
+			return nil, 0, errTruncated
+		}
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decodeField(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			value, next, err := decodeField(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		if size > remaining {
+			return nil, 0, errTruncated
+		}
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := decodeField(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if size > remaining {
+		return nil, 0, errTruncated
+	}
+	b := data[offset : offset+size]
+	offset += size
+	switch kind {
+	case typeString:
+		return string(b), offset, nil
+	case typeBytes:
+		return bytes.Clone(b), offset, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case typeUint16, typeUint32, typeUint64, typeUint128:
+		// 128-bit values are truncated; no lookup field uses them
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case typeInt32:
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		if size == 4 {
+			return int64(int32(n)), offset, nil
+		}
+		return int64(n), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported field type %d", kind)
+}
+
+// toUint converts a decoded unsigned integer field
+func toUint(value any) uint64 {
+	n, _ := value.(uint64)
+	return n
+}
@@ -0,0 +1,201 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// field encodes a data section field of a type and payload of up to 284
+// bytes
+func field(kind int, payload []byte) []byte {
+	return append(header(kind, len(payload)), payload...)
+}
+
+// header encodes the control byte, and the bytes extending it, of a field
+// of a type and size up to 284
+func header(kind, size int) []byte {
+	var out []byte
+	if kind > typeMap {
+		out = []byte{0, byte(kind - typeMap)}
+	} else {
+		out = []byte{byte(kind << 5)}
+	}
+	if size < 29 {
+		out[0] |= byte(size)
+		return out
+	}
+	out[0] |= 29
+	return append(out, byte(size-29))
+}
+
+func str(s string) []byte { return field(typeString, []byte(s)) }
+
+func uint32Field(n uint32) []byte {
+	b := binary.BigEndian.AppendUint32(nil, n)
+	return field(typeUint32, bytes.TrimLeft(b, "\x00"))
+}
+
+// mapField encodes a map with its keys sorted
+func mapField(entries map[string][]byte) []byte {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	out := header(typeMap, len(entries))
+	for _, key := range keys {
+		out = append(out, str(key)...)
+		out = append(out, entries[key]...)
+	}
+	return out
+}
+
+// pointer encodes a pointer to an offset below 2048
+func pointer(target int) []byte {
+	return []byte{byte(typePointer<<5 | target>>8), byte(target)}
+}
+
+// buildDB lays out an IPv4 database with record size 24 and a single node:
+// addresses whose first bit is 0 lead to the record at offset 0 of data,
+// the others have no entry
+func buildDB(data []byte) []byte {
+	const nodeCount = 1
+	var buf []byte
+	left := nodeCount + dataSeparator
+	buf = append(buf, byte(left>>16), byte(left>>8), byte(left))
+	buf = append(buf, 0, 0, nodeCount)
+	buf = append(buf, make([]byte, dataSeparator)...)
+	buf = append(buf, data...)
+	buf = append(buf, metadataMarker...)
+	buf = append(buf, mapField(map[string][]byte{
+		"node_count":    uint32Field(nodeCount),
+		"record_size":   uint32Field(24),
+		"ip_version":    uint32Field(4),
+		"database_type": str("Test-ASN"),
+	})...)
+	return buf
+}
+
+// asnRecord is the data section of a valid database
+func asnRecord() []byte {
+	return mapField(map[string][]byte{
+		"autonomous_system_number":       uint32Field(64500),
+		"autonomous_system_organization": str("Example Networks"),
+		"country":                        mapField(map[string][]byte{"iso_code": str("NL")}),
+	})
+}
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buildDB(asnRecord()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if r.Type() != "Test-ASN" {
+		t.Errorf("Type() = %q", r.Type())
+	}
+
+	info, err := r.Info(net.ParseIP("10.1.2.3"))
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	want := Info{ASN: 64500, Organization: "Example Networks", Country: "NL"}
+	if info != want {
+		t.Errorf("Info = %+v, want %+v", info, want)
+	}
+
+	info, err = r.Info(net.ParseIP("192.0.2.1"))
+	if err != nil || info != (Info{}) {
+		t.Errorf("address without an entry: %+v, %v", info, err)
+	}
+	info, err = r.Info(net.ParseIP("2001:db8::1"))
+	if err != nil || info != (Info{}) {
+		t.Errorf("IPv6 address in an IPv4 database: %+v, %v", info, err)
+	}
+}
+
+func TestLookupFollowsPointers(t *testing.T) {
+	// The record points at a string stored after it
+	name := str("Shared Org")
+	record := mapField(map[string][]byte{"autonomous_system_organization": pointer(0)})
+	record = mapField(map[string][]byte{"autonomous_system_organization": pointer(len(record))})
+	r, err := newReader(buildDB(append(record, name...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := r.Info(net.ParseIP("10.0.0.1"))
+	if err != nil || info.Organization != "Shared Org" {
+		t.Errorf("Info = %+v, %v", info, err)
+	}
+}
+
+func TestMalformedDatabases(t *testing.T) {
+	valid := buildDB(asnRecord())
+	marker := bytes.LastIndex(valid, metadataMarker)
+
+	tests := []struct {
+		name    string
+		buf     []byte
+		openErr bool
+		wantErr string
+	}{
+		{name: "not a database", buf: []byte("hello"), openErr: true, wantErr: "not a MaxMind DB file"},
+		{name: "truncated metadata", buf: valid[:len(valid)-3], openErr: true, wantErr: "invalid metadata"},
+		{name: "truncated data", buf: append(append([]byte(nil), valid[:marker-10]...), valid[marker:]...), wantErr: "unexpected end of data"},
+		{
+			// A map whose value points back at the map itself
+			name:    "cyclic pointer",
+			buf:     buildDB(mapField(map[string][]byte{"autonomous_system_organization": pointer(0)})),
+			wantErr: "nested too deeply",
+		},
+		{
+			name:    "pointer to a pointer",
+			buf:     buildDB(append(pointer(2), pointer(0)...)),
+			wantErr: "pointer to a pointer",
+		},
+		{
+			// A map claiming millions of entries in a few bytes
+			name:    "oversized map",
+			buf:     buildDB([]byte{typeMap<<5 | 31, 0xff, 0xff, 0xff}),
+			wantErr: "unexpected end of data",
+		},
+		{
+			name:    "oversized string",
+			buf:     buildDB([]byte{typeString<<5 | 30, 0xff, 0xff, 'a'}),
+			wantErr: "unexpected end of data",
+		},
+		{
+			name: "huge node count",
+			buf: append(append(append([]byte(nil), valid[:marker]...), metadataMarker...), mapField(map[string][]byte{
+				"node_count":  field(typeUint64, bytes.Repeat([]byte{0xff}, 8)),
+				"record_size": uint32Field(24),
+				"ip_version":  uint32Field(4),
+			})...),
+			openErr: true,
+			wantErr: "search tree is larger than the file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReader(tt.buf)
+			if !tt.openErr {
+				if err != nil {
+					t.Fatalf("newReader: %v", err)
+				}
+				_, err = r.Lookup(net.ParseIP("10.0.0.1"))
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
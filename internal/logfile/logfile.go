@@ -0,0 +1,192 @@
+// Package logfile writes the daemon's log to a file, rotating it by size and
+// pruning old rotated files by count and age
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modes used when none is configured. Log files may contain internal URLs.
+const (
+	defaultFileMode = 0o600
+	defaultDirMode  = 0o755
+)
+
+// backupTimeFormat is inserted into a rotated file's name, e.g.
+// monitord-2024-11-01T09-30-00.000.log. It sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Writer appends to a log file, rotating it before a write would take it past
+// MaxSize. It is safe for concurrent use.
+type Writer struct {
+	path       string
+	mode       os.FileMode
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens or creates the log file at path. Files it creates get exactly
+// mode, or 0600 when mode is 0, and new directories the matching mode with
+// execute bits added. A maxSize of 0 never rotates; maxBackups and maxAge of
+// 0 keep rotated files regardless of count or age.
+func Open(path string, mode os.FileMode, maxSize int64, maxBackups int, maxAge time.Duration) (*Writer, error) {
+	dirMode := os.FileMode(defaultDirMode)
+	if mode == 0 {
+		mode = defaultFileMode
+	} else {
+		dirMode = mode | (mode&0o444)>>2
+	}
+	w := &Writer{path: path, mode: mode, maxSize: maxSize, maxBackups: maxBackups, maxAge: maxAge}
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the log file for appending and records its size. A file it
+// creates gets the writer's mode whatever the umask.
+func (w *Writer) open() error {
+	_, err := os.Stat(w.path)
+	created := errors.Is(err, fs.ErrNotExist)
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.mode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if created {
+		if err := file.Chmod(w.mode); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	w.file, w.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if it would not fit. A single write larger
+// than the limit is written whole to a fresh file.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(time.Now()); err != nil {
+			return 0, fmt.Errorf("rotating log file: %w", err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// rotate renames the current file with a timestamp, starts a new one and
+// prunes old backups. The caller holds w.mu.
+func (w *Writer) rotate(now time.Time) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, w.backupName(now)); err != nil {
+		// Keep logging to the current file rather than losing lines
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune(now)
+}
+
+// backupName returns the name a file rotated at t is renamed to
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(w.path, ext), t.UTC().Format(backupTimeFormat), ext)
+}
+
+// prune removes the rotated files beyond maxBackups, newest kept first, and
+// those older than maxAge
+func (w *Writer) prune(now time.Time) error {
+	if w.maxBackups == 0 && w.maxAge == 0 {
+		return nil
+	}
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+	dir := filepath.Dir(w.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	type backup struct {
+		name    string
+		rotated time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ext)
+		if !ok {
+			continue
+		}
+		rotated, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{entry.Name(), rotated})
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.rotated.Compare(a.rotated) })
+
+	var errs []string
+	for i, b := range backups {
+		tooMany := w.maxBackups > 0 && i >= w.maxBackups
+		tooOld := w.maxAge > 0 && now.Sub(b.rotated) > w.maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, b.name)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("pruning rotated logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenAppliesFileMode(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "monitord.log")
+	w, err := Open(path, 0o660, 16, 0, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer w.Close()
+
+	// The second write rotates, so the new file is created with the mode too
+	for range 2 {
+		if _, err := w.Write([]byte("0123456789\n")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("log files = %v, %v; want the current and a rotated one", files, err)
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0o660 {
+			t.Errorf("%s mode = %o, want 660", filepath.Base(file), mode)
+		}
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode&0o110 != 0o110 {
+		t.Errorf("directory mode = %o, want the owner and group able to enter it", mode)
+	}
+}
@@ -0,0 +1,302 @@
+// Package metrics implements the small subset of Prometheus metric types that
+// monitord exports, rendered in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram bounds in seconds suited to typical web
+// latencies, from 5ms to 10s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector is a metric family that can render itself
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metric families and renders them for scraping
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write renders every registered metric family
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// family is the state shared by every labelled metric type
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one labelled time series within a family
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64 // histogram bucket counts, not cumulative
+	count       uint64
+	sum         float64
+}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	return &family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+// update runs fn against the series for the label values, creating it if needed
+func (f *family) update(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// Delete removes the series for the label values
+func (f *family) Delete(labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series, strings.Join(labelValues, "\xff"))
+}
+
+// sorted returns the family's series ordered by label values
+func (f *family) sorted() []*series {
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+	return all
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeHeader(w)
+	for _, s := range f.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	*family
+}
+
+// NewCounterVec registers a counter family
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newFamily(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter for the label values
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Inc increments the counter for the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	*family
+}
+
+// NewGaugeVec registers a gauge family
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newFamily(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge for the label values
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *series) { s.value = v })
+}
+
+// valueFunc is an unlabelled gauge or counter whose value is read at scrape
+// time
+type valueFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge that calls fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter that calls fn on every scrape, for
+// counts kept elsewhere
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&valueFunc{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (v *valueFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+	fmt.Fprintf(w, "%s %s\n", v.name, formatValue(v.fn()))
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	*family
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram family. Buckets are upper bounds in
+// increasing order; DefaultBuckets is used when none are given.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		family:  newFamily(name, help, "histogram", labels),
+		buckets: append([]float64(nil), buckets...),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records a value for the label values
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.buckets))
+		}
+		for i, bound := range h.buckets {
+			if v <= bound {
+				s.counts[i]++
+				break
+			}
+		}
+		s.count++
+		s.sum += v
+	})
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+				formatLabels(h.labels, s.labelValues, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.labelValues, "", ""), s.count)
+	}
+}
+
+// formatLabels renders a label set, optionally with one extra label appended
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extraName, labelEscaper.Replace(extraValue))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
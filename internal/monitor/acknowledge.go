@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotDown is returned when acknowledging an endpoint that is not down
+var ErrNotDown = errors.New("endpoint is not down")
+
+// Acknowledgement is an operator taking ownership of an endpoint's ongoing
+// incident, which pauses its escalation until it recovers or Expires
+type Acknowledgement struct {
+	URL            string    `json:"url"`
+	By             string    `json:"by"`
+	Note           string    `json:"note,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+	// Expires is when escalation resumes if the endpoint is still down; nil
+	// waits for recovery
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// AcknowledgementStore keeps acknowledgements across restarts
+type AcknowledgementStore interface {
+	Acknowledgements() ([]Acknowledgement, error)
+	SaveAcknowledgement(ack Acknowledgement) error
+	DeleteAcknowledgement(url string) error
+}
+
+// acknowledgements holds the acknowledged incidents by URL
+type acknowledgements struct {
+	store   AcknowledgementStore
+	entries map[string]Acknowledgement
+}
+
+// active reports whether the acknowledgement still pauses escalation at now
+func (a Acknowledgement) active(now time.Time) bool {
+	return a.Expires == nil || now.Before(*a.Expires)
+}
+
+// SetAcknowledgementStore loads the acknowledgements saved by a previous run
+// and saves new ones to store. Without one, acknowledgements last until
+// monitord stops. Called once running, acknowledgements made since win over
+// saved ones.
+func (s *Service) SetAcknowledgementStore(store AcknowledgementStore) error {
+	saved, err := store.Acknowledgements()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acks.store = store
+	for _, ack := range saved {
+		if _, ok := s.acks.entries[ack.URL]; !ok {
+			s.acks.entries[ack.URL] = ack
+		}
+	}
+	return nil
+}
+
+// Acknowledge records that by is handling the incident of a down endpoint,
+// with an optional note. Its escalation pauses until it recovers or, when d
+// is positive, for d.
+func (s *Service) Acknowledge(url, by, note string, d time.Duration) (Acknowledgement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	monitor, ok := s.endpoints[url]
+	if !ok {
+		return Acknowledgement{}, ErrUnknownEndpoint
+	}
+	monitor.mu.Lock()
+	down := isDown(monitor.state.Status, PolicyFor(monitor.endpoint))
+	monitor.mu.Unlock()
+	if !down {
+		return Acknowledgement{}, ErrNotDown
+	}
+
+	ack := Acknowledgement{
+		URL:            url,
+		By:             by,
+		Note:           note,
+		AcknowledgedAt: s.clock.Now(),
+	}
+	if d > 0 {
+		expires := ack.AcknowledgedAt.Add(d)
+		ack.Expires = &expires
+	}
+	if store := s.acks.store; store != nil {
+		if err := store.SaveAcknowledgement(ack); err != nil {
+			return Acknowledgement{}, fmt.Errorf("failed to save acknowledgement: %w", err)
+		}
+	}
+	s.acks.entries[url] = ack
+
+	message := "acknowledged by " + by
+	if note != "" {
+		message += ": " + note
+	}
+	s.logger.Printf("Incident of %s %s", url, message)
+	s.recordEndpointEvent(EventAcknowledgement, url, message)
+	return ack, nil
+}
+
+// Unacknowledge drops an endpoint's acknowledgement, so its escalation
+// resumes if it is still down
+func (s *Service) Unacknowledge(url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.endpoints[url]; !ok {
+		return ErrUnknownEndpoint
+	}
+	return s.clearAcknowledgement(url, "acknowledgement cleared")
+}
+
+// acknowledgement returns the endpoint's acknowledgement while it pauses
+// escalation
+func (s *Service) acknowledgement(url string) (Acknowledgement, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ack, ok := s.acks.entries[url]
+	if !ok || !ack.active(s.clock.Now()) {
+		return Acknowledgement{}, false
+	}
+	return ack, true
+}
+
+// endIncident drops the acknowledgement of an endpoint that recovered
+func (s *Service) endIncident(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.clearAcknowledgement(url, "acknowledgement ended, endpoint recovered"); err != nil {
+		s.logger.Printf("Error deleting acknowledgement for %s: %v", url, err)
+	}
+}
+
+// clearAcknowledgement drops an endpoint's acknowledgement, if any, and
+// records why. The caller holds s.mu.
+func (s *Service) clearAcknowledgement(url, reason string) error {
+	if _, ok := s.acks.entries[url]; !ok {
+		return nil
+	}
+	if store := s.acks.store; store != nil {
+		if err := store.DeleteAcknowledgement(url); err != nil {
+			return fmt.Errorf("failed to delete acknowledgement: %w", err)
+		}
+	}
+	delete(s.acks.entries, url)
+	s.recordEndpointEvent(EventAcknowledgement, url, reason)
+	if monitor, ok := s.endpoints[url]; ok {
+		s.resumeEscalation(monitor)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"time"
+)
+
+// alert sends a transition to the notifier unless notifications are muted,
+// the endpoint is under maintenance or within its alert cooldown. During a
+// cooldown the latest transition is held and sent when the cooldown ends,
+// if the endpoint's status still differs from the one last notified. During
+// the startup grace period it is held until the period ends.
+func (s *Service) alert(monitor *EndpointMonitor, t Transition) {
+	if s.holdOffline(monitor, &t) || s.holdForGrace(monitor, t) ||
+		s.suppressed(monitor.endpoint.URL) || s.inMaintenance(monitor.endpoint) {
+		return
+	}
+
+	monitor.mu.Lock()
+	cooldown := monitor.endpoint.AlertCooldown.ToDuration()
+	if cooldown > 0 && !monitor.lastNotified.IsZero() {
+		if remaining := cooldown - s.since(monitor.lastNotified); remaining > 0 {
+			monitor.deferred = &t
+			if monitor.cooldownTimer == nil {
+				monitor.cooldownTimer = s.clock.AfterFunc(remaining, func() {
+					s.endCooldown(monitor)
+				})
+			}
+			monitor.mu.Unlock()
+			s.logger.Printf("Notification for %s deferred, alert cooldown ends in %s",
+				monitor.endpoint.URL, remaining.Round(time.Second))
+			return
+		}
+	}
+	monitor.lastNotified = s.clock.Now()
+	monitor.notifiedStatus = t.Current
+	monitor.deferred = nil
+	monitor.mu.Unlock()
+
+	if s.notifier != nil {
+		s.notifier.Notify(t)
+	}
+}
+
+// endCooldown sends the transition held during an alert cooldown if the
+// endpoint did not return to the last notified status in the meantime
+func (s *Service) endCooldown(monitor *EndpointMonitor) {
+	s.mu.RLock()
+	current := s.endpoints[monitor.endpoint.URL] == monitor
+	s.mu.RUnlock()
+
+	monitor.mu.Lock()
+	t := monitor.deferred
+	monitor.deferred = nil
+	monitor.cooldownTimer = nil
+	if !current || t == nil || t.Current == monitor.notifiedStatus {
+		monitor.mu.Unlock()
+		return
+	}
+	// Report the change relative to what was last sent
+	t.Previous = monitor.notifiedStatus
+	monitor.mu.Unlock()
+
+	s.logger.Printf("Alert cooldown ended for %s, sending deferred notification", monitor.endpoint.URL)
+	s.alert(monitor, *t)
+}
+
+// holdForGrace holds a transition made during the startup grace period,
+// reporting whether it did
+func (s *Service) holdForGrace(monitor *EndpointMonitor, t Transition) bool {
+	s.mu.RLock()
+	graceEnds := s.startedAt.Add(s.config.Monitor.StartupGrace.ToDuration())
+	s.mu.RUnlock()
+	if !s.clock.Now().Before(graceEnds) {
+		return false
+	}
+
+	monitor.mu.Lock()
+	monitor.graceHeld = &t
+	monitor.mu.Unlock()
+	s.logger.Printf("Notification for %s held, startup grace period ends at %s",
+		monitor.endpoint.URL, graceEnds.Format(time.RFC3339))
+	return true
+}
+
+// endStartupGrace sends the transitions held during the startup grace
+// period for endpoints whose confirmed status is not UP when it ends
+func (s *Service) endStartupGrace() {
+	s.mu.RLock()
+	monitors := make([]*EndpointMonitor, 0, len(s.endpoints))
+	for _, monitor := range s.endpoints {
+		monitors = append(monitors, monitor)
+	}
+	s.mu.RUnlock()
+
+	for _, monitor := range monitors {
+		monitor.mu.Lock()
+		t := monitor.graceHeld
+		monitor.graceHeld = nil
+		if t == nil || t.Current == StatusUp || t.Current == monitor.notifiedStatus {
+			monitor.mu.Unlock()
+			continue
+		}
+		// Report the change relative to what was last sent
+		t.Previous = monitor.notifiedStatus
+		monitor.mu.Unlock()
+
+		s.logger.Printf("Startup grace period ended with %s %s, sending held notification",
+			monitor.endpoint.URL, t.Current)
+		s.alert(monitor, *t)
+	}
+}
+
+// suppressed reports, and logs, whether notifications are currently muted or
+// the service is within its startup grace period
+func (s *Service) suppressed(url string) bool {
+	s.mu.RLock()
+	graceEnds := s.startedAt.Add(s.config.Monitor.StartupGrace.ToDuration())
+	s.mu.RUnlock()
+	if s.clock.Now().Before(graceEnds) {
+		s.logger.Printf("Notification for %s suppressed, startup grace period ends at %s",
+			url, graceEnds.Format(time.RFC3339))
+		return true
+	}
+
+	until := s.MutedUntil()
+	if until.IsZero() {
+		return false
+	}
+	s.logger.Printf("Notification for %s suppressed, muted until %s", url, until.Format(time.RFC3339))
+	return true
+}
@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Response baseline defaults for settings left empty
+const (
+	defaultBaselineWindow     = time.Hour
+	defaultBaselineMinSamples = 20
+	defaultBaselineRefresh    = 5 * time.Minute
+)
+
+// BaselineSource returns the median response time, in milliseconds, of the
+// responses from an endpoint seen by a probe since a time, and how many
+// responses it covers
+type BaselineSource func(url, probe string, since time.Time) (median float64, samples int64, err error)
+
+// baselines caches each endpoint's response-time median between refreshes
+type baselines struct {
+	mu      sync.Mutex
+	source  BaselineSource
+	entries map[string]baseline
+}
+
+// baseline is an endpoint's median as last read from the source
+type baseline struct {
+	median  float64
+	samples int64
+	window  time.Duration
+	fetched time.Time
+}
+
+// SetBaselineSource provides the response-time medians that response_baseline
+// compares checks against. Without one, response baselines are not applied.
+// Call it before Start.
+func (s *Service) SetBaselineSource(source BaselineSource) {
+	s.baselines.mu.Lock()
+	defer s.baselines.mu.Unlock()
+	s.baselines.source = source
+	s.baselines.entries = make(map[string]baseline)
+}
+
+// applyBaseline marks a successful check DEGRADED when its response time is
+// more than the endpoint's response_baseline multiplier times its median
+func (s *Service) applyBaseline(check *HealthCheck, endpoint config.Endpoint) {
+	cfg := endpoint.ResponseBaseline
+	if cfg == nil || cfg.Multiplier == 0 || check.Status != StatusUp || check.StatusCode == 0 {
+		return
+	}
+	b, ok := s.baseline(endpoint.URL, cfg)
+	if !ok {
+		return
+	}
+	// A sub-millisecond median would flag any measurable response
+	limit := max(b.median, 1) * cfg.Multiplier
+	if float64(check.ResponseTime) > limit {
+		check.Status = StatusDegraded
+		check.Reason = fmt.Sprintf("response time %dms is more than %gx the %s median of %.0fms",
+			check.ResponseTime, cfg.Multiplier, shortDuration(b.window), b.median)
+	}
+}
+
+// baseline returns an endpoint's median, reading it again from the source
+// when it is older than the refresh interval. It reports false while there
+// is no source or the median covers fewer than min_samples responses.
+func (s *Service) baseline(url string, cfg *config.ResponseBaseline) (baseline, bool) {
+	window := cfg.Window.ToDuration()
+	if window == 0 {
+		window = defaultBaselineWindow
+	}
+	refresh := cfg.Refresh.ToDuration()
+	if refresh == 0 {
+		refresh = defaultBaselineRefresh
+	}
+	minSamples := int64(cfg.MinSamples)
+	if minSamples == 0 {
+		minSamples = defaultBaselineMinSamples
+	}
+	now := s.clock.Now()
+
+	s.baselines.mu.Lock()
+	source := s.baselines.source
+	b, cached := s.baselines.entries[url]
+	s.baselines.mu.Unlock()
+	if source == nil {
+		return baseline{}, false
+	}
+
+	if !cached || b.window != window || now.Sub(b.fetched) >= refresh {
+		median, samples, err := source(url, s.probeName(), now.Add(-window))
+		if err != nil {
+			// Keep the previous median and try again after the next refresh
+			s.logger.Printf("Error reading response-time baseline for %s: %v", url, err)
+		} else {
+			b.median, b.samples = median, samples
+		}
+		b.window, b.fetched = window, now
+		s.baselines.mu.Lock()
+		s.baselines.entries[url] = b
+		s.baselines.mu.Unlock()
+	}
+	return b, b.samples >= minSamples
+}
+
+// shortDuration formats a whole-minute duration without its zero units,
+// e.g. "1h" rather than "1h0m0s"
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package monitor
+
+import (
+	"io"
+	"time"
+)
+
+// defaultCaptureBodyLimit is used when capture_body is set without a limit
+const defaultCaptureBodyLimit = 4096
+
+// CapturedBody is the last response body kept for an endpoint
+type CapturedBody struct {
+	URL        string    `json:"url"`
+	Timestamp  time.Time `json:"timestamp"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code"`
+	Truncated  bool      `json:"truncated"`
+	Body       string    `json:"body"`
+}
+
+// captureBody reads up to limit bytes of a response body
+func captureBody(check HealthCheck, body io.Reader, limit int) *CapturedBody {
+	if limit <= 0 {
+		limit = defaultCaptureBodyLimit
+	}
+	data, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	truncated := len(data) > limit
+	if truncated {
+		data = data[:limit]
+	}
+	return &CapturedBody{
+		URL:        check.URL,
+		Timestamp:  check.Timestamp,
+		Status:     check.Status,
+		StatusCode: check.StatusCode,
+		Truncated:  truncated,
+		Body:       string(data),
+	}
+}
+
+// LastBody returns the most recent response body captured for an endpoint.
+// It returns ErrUnknownEndpoint for a URL that is not monitored and nil when
+// no body has been captured.
+func (s *Service) LastBody(url string) (*CapturedBody, error) {
+	s.mu.RLock()
+	monitor, ok := s.endpoints[url]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownEndpoint
+	}
+
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	return monitor.lastBody, nil
+}
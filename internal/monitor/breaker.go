@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Circuit breaker states reported in EndpointStatus
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// Circuit breaker defaults for settings left empty
+const (
+	defaultBreakerMultiplier  = 2
+	defaultBreakerMaxInterval = time.Hour
+)
+
+// circuitBreaker is an endpoint's breaker state. The zero value is closed.
+type circuitBreaker struct {
+	state     string
+	failures  int           // consecutive ERROR checks
+	successes int           // consecutive successes while half-open
+	interval  time.Duration // backed-off interval, kept while half-open
+}
+
+// BreakerStatus describes an endpoint's circuit breaker
+type BreakerStatus struct {
+	State    string `json:"state"`
+	Failures int    `json:"consecutive_failures"`
+	// Interval is the backed-off check interval while the breaker is open
+	Interval string `json:"interval,omitempty"`
+}
+
+// updateBreaker feeds a check result to the endpoint's circuit breaker and
+// returns how long until the next check when the breaker is open, or 0 to
+// keep the normal interval
+func (s *Service) updateBreaker(monitor *EndpointMonitor, check HealthCheck) time.Duration {
+	cfg := monitor.endpoint.CircuitBreaker
+	if cfg == nil || cfg.FailureThreshold == 0 {
+		return 0
+	}
+
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	b := &monitor.breaker
+	interval := monitor.endpoint.Interval.ToDuration()
+
+	if check.Status != StatusError {
+		b.failures = 0
+		switch b.state {
+		case BreakerOpen:
+			b.state, b.successes = BreakerHalfOpen, 0
+			fallthrough
+		case BreakerHalfOpen:
+			b.successes++
+			if b.successes >= max(cfg.SuccessThreshold, 1) {
+				*b = circuitBreaker{}
+				s.logger.Printf("Circuit breaker closed for %s, checking every %s", monitor.endpoint.URL, interval)
+			}
+		}
+		return 0
+	}
+
+	b.failures++
+	switch b.state {
+	case BreakerOpen, BreakerHalfOpen:
+		b.state, b.interval = BreakerOpen, backoffInterval(cfg, interval, b.interval)
+	default:
+		if b.failures < cfg.FailureThreshold {
+			return 0
+		}
+		b.state, b.interval = BreakerOpen, backoffInterval(cfg, interval, interval)
+		s.logger.Printf("Circuit breaker opened for %s after %d consecutive failures, backing off to %s",
+			monitor.endpoint.URL, b.failures, b.interval)
+	}
+	return b.interval
+}
+
+// backoffInterval multiplies the previous interval, keeping it between the
+// endpoint's interval and the breaker's maximum
+func backoffInterval(cfg *config.CircuitBreaker, interval, previous time.Duration) time.Duration {
+	multiplier := cfg.Multiplier
+	if multiplier == 0 {
+		multiplier = defaultBreakerMultiplier
+	}
+	maxInterval := cfg.MaxInterval.ToDuration()
+	if maxInterval == 0 {
+		maxInterval = defaultBreakerMaxInterval
+	}
+	next := time.Duration(float64(previous) * multiplier)
+	return max(min(next, maxInterval), interval)
+}
+
+// breakerStatus reports the breaker of an endpoint that has one. The caller
+// holds monitor.mu.
+func (m *EndpointMonitor) breakerStatus() *BreakerStatus {
+	cfg := m.endpoint.CircuitBreaker
+	if cfg == nil || cfg.FailureThreshold == 0 {
+		return nil
+	}
+	status := &BreakerStatus{State: m.breaker.state, Failures: m.breaker.failures}
+	if status.State == "" {
+		status.State = BreakerClosed
+	}
+	if status.State == BreakerOpen {
+		status.Interval = m.breaker.interval.String()
+	}
+	return status
+}
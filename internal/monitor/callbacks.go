@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+)
+
+// Check callback pool settings
+const (
+	checkCallbackWorkers = 4
+	// checkCallbackQueue is the number of results waiting for the workers.
+	// Results arriving while it is full are dropped for the callbacks.
+	checkCallbackQueue = 256
+)
+
+// checkCallbacks runs the functions registered with OnCheck on a bounded
+// pool of workers, so slow callbacks never delay checks
+type checkCallbacks struct {
+	mu    sync.RWMutex
+	fns   []func(HealthCheck)
+	queue chan HealthCheck
+	stop  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+func newCheckCallbacks() *checkCallbacks {
+	return &checkCallbacks{
+		queue: make(chan HealthCheck, checkCallbackQueue),
+		stop:  make(chan struct{}),
+	}
+}
+
+// OnCheck registers fn to be called with the result of every check after it
+// is stored, or queued to be stored when database.save_queue is set.
+// Callbacks run on a small shared worker pool, in registration order for
+// each result, and results from different checks may be handled
+// concurrently. Callbacks must be quick and must not modify the check; if
+// they fall behind, results are dropped for them and logged. A panicking
+// callback is recovered and logged.
+func (s *Service) OnCheck(fn func(HealthCheck)) {
+	s.callbacks.mu.Lock()
+	s.callbacks.fns = append(s.callbacks.fns, fn)
+	s.callbacks.mu.Unlock()
+}
+
+// start launches the workers
+func (c *checkCallbacks) start(logger *log.Logger) {
+	for i := 0; i < checkCallbackWorkers; i++ {
+		c.wg.Add(1)
+		go c.work(logger)
+	}
+}
+
+// dispatch queues a check for the callbacks without blocking
+func (c *checkCallbacks) dispatch(check HealthCheck, logger *log.Logger) {
+	c.mu.RLock()
+	registered := len(c.fns) > 0
+	c.mu.RUnlock()
+	if !registered {
+		return
+	}
+
+	select {
+	case c.queue <- check:
+	default:
+		logger.Printf("Check callbacks are falling behind, dropping result for %s", check.URL)
+	}
+}
+
+// work runs callbacks until shutdown, then finishes the queued results
+func (c *checkCallbacks) work(logger *log.Logger) {
+	defer c.wg.Done()
+	for {
+		select {
+		case check := <-c.queue:
+			c.run(check, logger)
+		case <-c.stop:
+			for {
+				select {
+				case check := <-c.queue:
+					c.run(check, logger)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run calls every callback with a check
+func (c *checkCallbacks) run(check HealthCheck, logger *log.Logger) {
+	c.mu.RLock()
+	fns := c.fns
+	c.mu.RUnlock()
+
+	for _, fn := range fns {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf("Check callback panicked for %s: %v", check.URL, r)
+				}
+			}()
+			fn(check)
+		}()
+	}
+}
+
+// shutdown stops the workers once the queued results are handled
+func (c *checkCallbacks) shutdown() {
+	c.once.Do(func() { close(c.stop) })
+	c.wg.Wait()
+}
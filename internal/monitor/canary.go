@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Statuses of the notifications sent when monitord loses and regains
+// connectivity, as judged by its canaries
+const (
+	StatusConnectivityLost     = "CONNECTIVITY_LOST"
+	StatusConnectivityRestored = "CONNECTIVITY_RESTORED"
+)
+
+// connectivity records whether every canary is down
+type connectivity struct {
+	mu    sync.Mutex
+	lost  bool
+	since time.Time
+}
+
+// canaries returns the canary settings, or nil when none are configured
+func (s *Service) canaries() *config.CanaryConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Monitor.Canaries
+}
+
+// isCanary reports whether an endpoint is one of the canaries
+func (s *Service) isCanary(url string) bool {
+	canaries := s.canaries()
+	return canaries != nil && slices.Contains(canaries.URLs, url)
+}
+
+// ConnectivityLost reports whether every canary is down, in which case
+// monitord assumes it has lost connectivity itself
+func (s *Service) ConnectivityLost() bool {
+	s.connectivity.mu.Lock()
+	defer s.connectivity.mu.Unlock()
+	return s.connectivity.lost
+}
+
+// offlineSuppresses reports whether a notification of the given status is
+// held back while connectivity is lost
+func (s *Service) offlineSuppresses(status string) bool {
+	canaries := s.canaries()
+	if canaries == nil || !s.ConnectivityLost() {
+		return false
+	}
+	switch canaries.Suppress {
+	case config.CanarySuppressNone:
+		return false
+	case config.CanarySuppressFailures:
+		return status == StatusError || status == StatusDegraded || status == StatusSuccessRateLow
+	default:
+		return true
+	}
+}
+
+// updateConnectivity re-evaluates the canaries after one of them changed
+// status or the configuration was reloaded, and notifies when connectivity
+// is lost or restored
+func (s *Service) updateConnectivity() {
+	s.mu.RLock()
+	canaries := s.config.Monitor.Canaries
+	var down, total int
+	var reachable string
+	if canaries != nil {
+		for _, url := range canaries.URLs {
+			monitor, ok := s.endpoints[url]
+			if !ok {
+				continue
+			}
+			total++
+			monitor.mu.Lock()
+			if monitor.state.Status == StatusError {
+				down++
+			} else if reachable == "" && monitor.state.Status != "" {
+				reachable = url
+			}
+			monitor.mu.Unlock()
+		}
+	}
+	s.mu.RUnlock()
+	lost := total > 0 && down == total
+
+	s.connectivity.mu.Lock()
+	if lost == s.connectivity.lost {
+		s.connectivity.mu.Unlock()
+		return
+	}
+	now := s.clock.Now()
+	duration := now.Sub(s.connectivity.since)
+	s.connectivity.lost, s.connectivity.since = lost, now
+	s.connectivity.mu.Unlock()
+
+	t := Transition{
+		Check: HealthCheck{Name: "monitord", Probe: s.probeName(), Timestamp: now},
+	}
+	if canaries != nil {
+		t.Notifiers = canaries.Notifiers
+	}
+	switch {
+	case lost:
+		t.Current = StatusConnectivityLost
+		t.Detail = fmt.Sprintf("all %d canaries are down, monitord appears to have lost connectivity", total)
+		s.logger.Printf("WARN all %d canaries are down, monitord appears to have lost connectivity", total)
+	case reachable != "":
+		t.Previous, t.Current, t.Duration = StatusConnectivityLost, StatusConnectivityRestored, duration
+		t.Detail = fmt.Sprintf("%s is reachable", reachable)
+		s.logger.Printf("Connectivity restored, %s is reachable", reachable)
+	default:
+		// The canaries were removed or stopped while down
+		t.Previous, t.Current, t.Duration = StatusConnectivityLost, StatusConnectivityRestored, duration
+		t.Detail = "no canaries are down any more"
+		s.logger.Printf("Connectivity no longer considered lost, no canaries are down")
+	}
+	s.recordEvent(EventConnectivity, t.Detail)
+
+	if s.notifier != nil && !s.suppressed("monitord connectivity") {
+		s.notifier.Notify(t)
+	}
+}
+
+// suppressedOffline reports, and logs, whether a notification for an
+// endpoint is held back because connectivity is lost
+func (s *Service) suppressedOffline(url, status string) bool {
+	if !s.offlineSuppresses(status) {
+		return false
+	}
+	s.logger.Printf("Notification for %s suppressed, monitord appears to have lost connectivity", url)
+	return true
+}
+
+// holdOffline drops a notification while connectivity is lost and, once it
+// is restored, one that only returns the endpoint to the status it had when
+// its notifications started being held. It reports whether t should not be
+// sent; otherwise t is rewritten relative to that earlier status.
+func (s *Service) holdOffline(monitor *EndpointMonitor, t *Transition) bool {
+	suppress := s.suppressedOffline(monitor.endpoint.URL, t.Current)
+
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	if suppress {
+		if !monitor.offlineHeld {
+			monitor.offlineHeld, monitor.offlineFrom = true, t.Previous
+		}
+		return true
+	}
+	if !monitor.offlineHeld {
+		return false
+	}
+	monitor.offlineHeld = false
+	if t.Current == monitor.offlineFrom {
+		s.logger.Printf("Not notifying for %s, back to %s after connectivity was lost",
+			monitor.endpoint.URL, statusLabel(t.Current))
+		return true
+	}
+	t.Previous = monitor.offlineFrom
+	return false
+}
@@ -0,0 +1,41 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownEndpoint is returned for a URL that is not being monitored
+var ErrUnknownEndpoint = errors.New("endpoint is not monitored")
+
+// ErrPassive is returned when an immediate check is asked of a passive
+// endpoint, which monitord does not check itself
+var ErrPassive = errors.New("passive endpoints are only checked by reports")
+
+// CheckNow runs a health check of a monitored endpoint immediately and
+// returns the result. The endpoint's regular schedule is not affected. When
+// record is true the result is saved and evaluated for alerting like a
+// scheduled check; otherwise it is only returned. Cancelling ctx abandons the
+// check.
+func (s *Service) CheckNow(ctx context.Context, url string, record bool) (HealthCheck, error) {
+	s.mu.RLock()
+	monitor, ok := s.endpoints[url]
+	s.mu.RUnlock()
+	if !ok {
+		return HealthCheck{}, ErrUnknownEndpoint
+	}
+	if monitor.reported != nil {
+		return HealthCheck{}, ErrPassive
+	}
+
+	s.logger.Printf("Running on-demand check for %s", url)
+	endpoint := s.checkedEndpoint(monitor.endpoint)
+	check := s.performHealthCheck(ctx, newClient(endpoint, s.dns, s.trust.pool(endpoint.CAFile)), endpoint)
+	if err := ctx.Err(); err != nil {
+		return HealthCheck{}, err
+	}
+	if record {
+		s.recordCheck(monitor, check)
+	}
+	return check, nil
+}
@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// classify assigns a status to the outcome of a check request. It returns the
+// status and, for results other than UP, the reason the response was given
+// it. Transport errors are reported separately, by describeError.
+func classify(endpoint config.Endpoint, resp *http.Response, err error) (string, string) {
+	if endpoint.ExpectInaccessible {
+		return classifyInaccessible(resp, err)
+	}
+
+	if err != nil {
+		if isTLSVersionError(err) {
+			return StatusDegraded, fmt.Sprintf("server does not support TLS %s or later", endpoint.MinTLSVersion)
+		}
+		return StatusError, ""
+	}
+	if endpoint.ExpectRedirectTo != "" {
+		if detail := matchRedirect(endpoint.ExpectRedirectTo, resp); detail != "" {
+			return StatusDegraded, detail
+		}
+	} else if resp.StatusCode != http.StatusOK {
+		return StatusDegraded, fmt.Sprintf("expected status code 200, got %d", resp.StatusCode)
+	}
+	if detail := matchHeaders(endpoint, resp.Header); detail != "" {
+		return StatusDegraded, detail
+	}
+	if detail := matchProtocol(expectedProtocol(endpoint), resp.Proto); detail != "" {
+		return StatusDegraded, detail
+	}
+	return StatusUp, ""
+}
+
+// expectedProtocol returns the protocol a response must use. Requiring
+// http_version 2 implies expecting it unless expect_protocol says otherwise.
+func expectedProtocol(endpoint config.Endpoint) string {
+	if endpoint.ExpectProtocol == "" && endpoint.HTTPVersion == config.HTTPVersion2 {
+		return "HTTP/2.0"
+	}
+	return endpoint.ExpectProtocol
+}
+
+// matchProtocol checks the negotiated protocol against the expected one
+func matchProtocol(expected, proto string) string {
+	if expected == "" {
+		return ""
+	}
+	want, ok := config.NormalizeProtocol(expected)
+	if !ok {
+		return fmt.Sprintf("invalid expected protocol %q", expected)
+	}
+	if proto != want {
+		return fmt.Sprintf("expected protocol %s, got %s", want, proto)
+	}
+	return ""
+}
+
+// matchHeaders checks the response headers against the endpoint's
+// expect_headers and describes the first mismatch. An empty expected value
+// only requires the header to be present; a "regex:" prefix matches the rest
+// as a regular expression; anything else must match a header value exactly.
+func matchHeaders(endpoint config.Endpoint, header http.Header) string {
+	expected := endpoint.ExpectHeaders
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := expected[name]
+		values := header.Values(name)
+		if len(values) == 0 {
+			return fmt.Sprintf("missing expected header %s", name)
+		}
+		if want == "" {
+			continue
+		}
+
+		match := func(v string) bool { return v == want }
+		if strings.HasPrefix(want, "regex:") {
+			re, err := endpoint.HeaderPattern(name)
+			if err != nil {
+				return fmt.Sprintf("invalid pattern for header %s: %v", name, err)
+			}
+			match = re.MatchString
+		}
+
+		if !slices.ContainsFunc(values, match) {
+			return fmt.Sprintf("header %s is %q, expected %q", name, strings.Join(values, ", "), want)
+		}
+	}
+	return ""
+}
+
+// classifyInaccessible inverts the usual semantics for endpoints that are
+// expected to stay down: failing to connect, or a 404/410, is healthy while a
+// successful response is a problem
+func classifyInaccessible(resp *http.Response, err error) (string, string) {
+	if err != nil {
+		return StatusUp, ""
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return StatusUp, ""
+	default:
+		return StatusError, fmt.Sprintf("endpoint expected to be inaccessible but responded with %d", resp.StatusCode)
+	}
+}
+
+// sensitiveHeaders are never stored with a check
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// redactHeaders returns a copy of the headers with sensitive values masked
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = []string{"[REDACTED]"}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"time"
+)
+
+// Clock is the service's source of time. Tests can substitute a fake clock
+// with SetClock to drive the monitor loop, cooldowns and mutes
+// deterministically.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Ticker delivers ticks on a channel, like time.Ticker
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// Timer is a pending AfterFunc call, like time.Timer
+type Timer interface {
+	Stop() bool
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) Chan() <-chan time.Time {
+	return t.C
+}
+
+// SetClock replaces the service's clock. It must be called before Start.
+func (s *Service) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+// since returns the time elapsed on the service clock
+func (s *Service) since(t time.Time) time.Duration {
+	return s.clock.Now().Sub(t)
+}
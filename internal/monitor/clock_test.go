@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when Advance is called. Timers and
+// tickers fire in order of their due time as the clock passes it.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+	set     int // AfterFunc calls so far
+	waited  int // the last call waitForTimer returned for
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// fakeTimer is a pending AfterFunc call, or a ticker's schedule when period
+// is set
+type fakeTimer struct {
+	clock  *fakeClock
+	seq    int
+	when   time.Time
+	period time.Duration
+	fn     func()
+	ch     chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, when: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, t)
+	return fakeTicker{t}
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	c.set++
+	t := &fakeTimer{clock: c, seq: c.set, when: c.now.Add(d), fn: f}
+	if d > 0 {
+		c.waiters = append(c.waiters, t)
+		c.mu.Unlock()
+		return t
+	}
+	c.mu.Unlock()
+	go f()
+	return t
+}
+
+// Advance moves the clock forward by d, firing everything due on the way
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].when.Before(c.waiters[j].when) })
+		if len(c.waiters) == 0 || c.waiters[0].when.After(target) {
+			break
+		}
+		t := c.waiters[0]
+		c.now = t.when
+		if t.period > 0 {
+			select {
+			case t.ch <- c.now:
+			default:
+			}
+			t.when = t.when.Add(t.period)
+			continue
+		}
+		c.waiters = c.waiters[1:]
+		c.mu.Unlock()
+		t.fn()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// waitForTimer waits for an AfterFunc call due d from now, made since the
+// last one waited for, so the test does not advance past it before a
+// goroutine has set it
+func (c *fakeClock) waitForTimer(t testing.TB, d time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		when := c.now.Add(d)
+		for _, waiter := range c.waiters {
+			if waiter.period == 0 && waiter.seq > c.waited && waiter.when.Equal(when) {
+				c.waited = waiter.seq
+				c.mu.Unlock()
+				return
+			}
+		}
+		c.mu.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatalf("no timer was set for %s", d)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitForTimers waits until n AfterFunc calls are pending
+func (c *fakeClock) waitForTimers(t testing.TB, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.timers() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d timers were set, want %d", c.timers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// timers returns the number of pending AfterFunc calls
+func (c *fakeClock) timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, waiter := range c.waiters {
+		if waiter.period == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// remove drops t from the pending timers, reporting whether it was there
+func (c *fakeClock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, waiter := range c.waiters {
+		if waiter == t {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+type fakeTicker struct {
+	timer *fakeTimer
+}
+
+func (t fakeTicker) Chan() <-chan time.Time {
+	return t.timer.ch
+}
+
+func (t fakeTicker) Stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugTrace records the timeline of a request for endpoints with
+// debug_trace, to be logged when the check fails. Its hooks may run on other
+// goroutines.
+type debugTrace struct {
+	mu    sync.Mutex
+	clock Clock
+	start time.Time
+	lines []string
+}
+
+func newDebugTrace(clock Clock) *debugTrace {
+	return &debugTrace{clock: clock, start: clock.Now()}
+}
+
+// add appends a timeline entry stamped with the time since the start
+func (t *debugTrace) add(format string, args ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	elapsed := t.clock.Now().Sub(t.start).Round(time.Microsecond)
+	t.lines = append(t.lines, fmt.Sprintf("  %10s  %s", elapsed, fmt.Sprintf(format, args...)))
+}
+
+// hooks returns a trace that records every phase of the request. It is added
+// to the request's context on its own, so the other traces still run.
+func (t *debugTrace) hooks() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) { t.add("get connection to %s", hostPort) },
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.add("dns lookup of %s", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			addrs := make([]string, len(info.Addrs))
+			for i, addr := range info.Addrs {
+				addrs[i] = addr.String()
+			}
+			t.add("dns done: %s%s", strings.Join(addrs, ", "), traceError(info.Err))
+		},
+		ConnectStart: func(network, addr string) { t.add("connect %s %s", network, addr) },
+		ConnectDone: func(network, addr string, err error) {
+			t.add("connected %s %s%s", network, addr, traceError(err))
+		},
+		TLSHandshakeStart: func() { t.add("tls handshake") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.add("tls done: %s %s%s", tls.VersionName(state.Version),
+				tls.CipherSuiteName(state.CipherSuite), traceError(err))
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.add("got connection %s -> %s (reused %t, idle %s)", info.Conn.LocalAddr(),
+				info.Conn.RemoteAddr(), info.Reused, info.IdleTime)
+		},
+		WroteHeaderField: func(key string, value []string) {
+			if isSensitiveHeader(key) {
+				value = []string{"[REDACTED]"}
+			}
+			t.add("> %s: %s", key, strings.Join(value, ", "))
+		},
+		WroteHeaders:         func() { t.add("wrote headers") },
+		Wait100Continue:      func() { t.add("waiting for 100 Continue") },
+		Got100Continue:       func() { t.add("got 100 Continue") },
+		WroteRequest:         func(info httptrace.WroteRequestInfo) { t.add("wrote request%s", traceError(info.Err)) },
+		GotFirstResponseByte: func() { t.add("first response byte") },
+	}
+}
+
+// dump renders the request line, the timeline and the response for the log.
+// req and resp may be nil when the request was never built or answered.
+func (t *debugTrace) dump(req *http.Request, resp *http.Response, err error) string {
+	var b strings.Builder
+	if req != nil {
+		fmt.Fprintf(&b, "  %s %s\n", req.Method, req.URL.Redacted())
+	}
+	t.mu.Lock()
+	for _, line := range t.lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	t.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(&b, "  error: %v\n", err)
+	}
+	if resp != nil {
+		fmt.Fprintf(&b, "  < %s %s\n", resp.Proto, resp.Status)
+		header := redactDebugHeaders(resp.Header)
+		keys := make([]string, 0, len(header))
+		for key := range header {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "  < %s: %s\n", key, strings.Join(header[key], ", "))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// traceError formats an optional error of a trace hook
+func traceError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return " (" + err.Error() + ")"
+}
+
+// sensitiveHeaderWords mark header names whose values debug traces never log,
+// beyond the always sensitive ones, such as X-Api-Key or X-Auth-Token
+var sensitiveHeaderWords = []string{"auth", "token", "api-key", "apikey", "secret", "password", "session", "cookie", "signature"}
+
+// isSensitiveHeader reports whether a header's value is left out of debug
+// traces
+func isSensitiveHeader(name string) bool {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	if slices.Contains(sensitiveHeaders, name) {
+		return true
+	}
+	lower := strings.ToLower(name)
+	return slices.ContainsFunc(sensitiveHeaderWords, func(word string) bool {
+		return strings.Contains(lower, word)
+	})
+}
+
+// redactDebugHeaders returns a copy of the headers with the values debug
+// traces leave out masked
+func redactDebugHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if isSensitiveHeader(name) {
+			redacted[name] = []string{"[REDACTED]"}
+		}
+	}
+	return redacted
+}
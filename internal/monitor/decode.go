@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultDecodedBodyLimit bounds decoded bodies when decoded_body_limit is
+// not set, so a small compressed response cannot expand without limit
+const defaultDecodedBodyLimit = 10 << 20
+
+// acceptEncoding is requested by endpoints with decode_body set
+const acceptEncoding = "gzip, deflate"
+
+// decodedBody is a response body read and decompressed by decodeBody
+type decodedBody struct {
+	data    []byte
+	raw     int64 // bytes received, as encoded
+	decoded int64 // bytes after decoding
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodeBody reads a response body, decompressing gzip or deflate content,
+// and returns it with its size before and after decoding. A body that
+// decodes to more than limit bytes is rejected.
+func decodeBody(resp *http.Response, limit int) (decodedBody, error) {
+	if limit <= 0 {
+		limit = defaultDecodedBodyLimit
+	}
+	counter := &countingReader{r: resp.Body}
+
+	var body io.Reader = counter
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return decodedBody{raw: counter.n}, fmt.Errorf("failed to decode gzip body: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	case "deflate":
+		deflate, err := newDeflateReader(counter)
+		if err != nil {
+			return decodedBody{raw: counter.n}, fmt.Errorf("failed to decode deflate body: %w", err)
+		}
+		defer deflate.Close()
+		body = deflate
+	default:
+		return decodedBody{}, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	result := decodedBody{data: data, raw: counter.n, decoded: int64(len(data))}
+	if err != nil {
+		return result, fmt.Errorf("failed to decode %s body: %w", encodingLabel(encoding), err)
+	}
+	if len(data) > limit {
+		result.data = data[:limit]
+		return result, fmt.Errorf("decoded body exceeds %d bytes", limit)
+	}
+	return result, nil
+}
+
+// newDeflateReader reads "deflate" content, which servers send either
+// zlib-wrapped, as specified, or as a raw deflate stream
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// encodingLabel names an encoding in error messages
+func encodingLabel(encoding string) string {
+	if encoding == "" || encoding == "identity" {
+		return "response"
+	}
+	return encoding
+}
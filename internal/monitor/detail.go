@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// bodySnippetLimit is how much of a failed response's body is kept at the
+// verbose-on-failure detail level
+const bodySnippetLimit = 1024
+
+// Timing breaks down where a check request spent its time, in milliseconds.
+// FirstByte is measured from the start of the request.
+type Timing struct {
+	DNS       int64 `json:"dns_ms"`
+	Connect   int64 `json:"connect_ms"`
+	TLS       int64 `json:"tls_ms"`
+	FirstByte int64 `json:"first_byte_ms"`
+}
+
+// timingTrace records the phases of a request through httptrace hooks,
+// which may run on other goroutines
+type timingTrace struct {
+	mu       sync.Mutex
+	clock    Clock
+	start    time.Time
+	phases   map[string]time.Time
+	timing   Timing
+	recorded bool
+}
+
+func newTimingTrace(clock Clock) *timingTrace {
+	return &timingTrace{clock: clock, start: clock.Now(), phases: make(map[string]time.Time)}
+}
+
+// begin marks the start of a phase
+func (t *timingTrace) begin(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases[phase] = t.clock.Now()
+}
+
+// end records the duration of a phase into field
+func (t *timingTrace) end(phase string, field *int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if started, ok := t.phases[phase]; ok {
+		*field = t.clock.Now().Sub(started).Milliseconds()
+		t.recorded = true
+	}
+}
+
+// hook adds the timing hooks to a trace
+func (t *timingTrace) hook(trace *httptrace.ClientTrace) {
+	trace.DNSStart = func(httptrace.DNSStartInfo) { t.begin("dns") }
+	trace.DNSDone = func(httptrace.DNSDoneInfo) { t.end("dns", &t.timing.DNS) }
+	trace.ConnectStart = func(string, string) { t.begin("connect") }
+	trace.ConnectDone = func(string, string, error) { t.end("connect", &t.timing.Connect) }
+	trace.TLSHandshakeStart = func() { t.begin("tls") }
+	trace.TLSHandshakeDone = func(tls.ConnectionState, error) { t.end("tls", &t.timing.TLS) }
+	trace.GotFirstResponseByte = func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.timing.FirstByte = t.clock.Now().Sub(t.start).Milliseconds()
+		t.recorded = true
+	}
+}
+
+// result returns the recorded timing, or nil when no phase was seen
+func (t *timingTrace) result() *Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.recorded {
+		return nil
+	}
+	timing := t.timing
+	return &timing
+}
+
+// bodySnippet reads the start of a response body
+func bodySnippet(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, bodySnippetLimit))
+	return string(data)
+}
+
+// verboseOnFailure reports whether an endpoint collects extra detail for
+// failed checks
+func verboseOnFailure(endpoint config.Endpoint) bool {
+	return endpoint.DetailLevel == config.DetailVerboseOnFailure
+}
+
+// DetailLevel returns the detail level configured for a monitored endpoint,
+// or "" for a URL that is not monitored
+func (s *Service) DetailLevel(url string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if monitor, ok := s.endpoints[url]; ok {
+		return monitor.endpoint.DetailLevel
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache keeps resolved addresses for endpoints that set a DNS cache TTL.
+// When an expired entry cannot be refreshed, the old addresses are used and
+// the check is marked as having used a stale entry.
+type dnsCache struct {
+	mu       sync.Mutex
+	entries  map[string]dnsEntry
+	resolver *net.Resolver
+	now      func() time.Time
+}
+
+// dnsEntry is a successful lookup and when it was made
+type dnsEntry struct {
+	addrs    []string
+	resolved time.Time
+}
+
+// dnsLookup records how a check's connections were resolved. It travels in
+// the request context so the dialer can report back to the check.
+type dnsLookup struct {
+	mu       sync.Mutex
+	staleFor string
+	err      error
+}
+
+type dnsLookupKey struct{}
+
+func newDNSCache(now func() time.Time) *dnsCache {
+	return &dnsCache{
+		entries:  make(map[string]dnsEntry),
+		resolver: net.DefaultResolver,
+		now:      now,
+	}
+}
+
+// withDNSLookup returns a context the cache reports stale entries through
+func withDNSLookup(ctx context.Context) (context.Context, *dnsLookup) {
+	lookup := &dnsLookup{}
+	return context.WithValue(ctx, dnsLookupKey{}, lookup), lookup
+}
+
+// stale describes the stale entry used by the check, or "" when none was
+func (l *dnsLookup) stale() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.staleFor == "" {
+		return ""
+	}
+	return fmt.Sprintf("used stale DNS cache entry for %s: %v", l.staleFor, l.err)
+}
+
+// lookup returns the addresses for host, resolving it again once the cached
+// entry is older than ttl
+func (c *dnsCache) lookup(ctx context.Context, host string, ttl time.Duration) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.resolved) < ttl {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) > 0 {
+		c.mu.Lock()
+		c.entries[host] = dnsEntry{addrs: addrs, resolved: c.now()}
+		c.mu.Unlock()
+		return addrs, nil
+	}
+	if !ok || ctx.Err() != nil {
+		return nil, err
+	}
+
+	if lookup, _ := ctx.Value(dnsLookupKey{}).(*dnsLookup); lookup != nil {
+		lookup.mu.Lock()
+		lookup.staleFor, lookup.err = host, err
+		lookup.mu.Unlock()
+	}
+	return entry.addrs, nil
+}
+
+// dialContext returns a dial function that resolves through the cache and
+// tries each address in turn
+func (c *dnsCache) dialContext(dialer *net.Dialer, network string, ttl time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, _, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := c.lookup(ctx, host, ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for _, ip := range addrs {
+			if !matchesNetwork(network, ip) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if len(errs) == 0 {
+			return nil, &net.OpError{Op: "dial", Net: network,
+				Err: &net.AddrError{Err: "no suitable address found", Addr: host}}
+		}
+		if len(errs) == 1 {
+			return nil, errs[0]
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// matchesNetwork reports whether ip can be dialed over network
+func matchesNetwork(network, ip string) bool {
+	isIPv4 := net.ParseIP(ip).To4() != nil
+	switch network {
+	case "tcp4":
+		return isIPv4
+	case "tcp6":
+		return !isIPv4
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// performDualStackCheck checks an endpoint over IPv4 and then over IPv6. The
+// result is the worse of the two, so one broken family is not masked by the
+// other, and its detail gives the outcome over each.
+func (s *Service) performDualStackCheck(ctx context.Context, endpoint config.Endpoint) HealthCheck {
+	var worst HealthCheck
+	var outcomes []string
+	for _, family := range []string{config.IPVersion4, config.IPVersion6} {
+		single := endpoint
+		single.IPVersion = family
+		// A client's dialer is bound to one network, so each family gets
+		// its own
+		client := newClient(single, s.dns, s.trust.pool(single.CAFile))
+		check := s.performHealthCheck(ctx, client, single)
+		client.CloseIdleConnections()
+		// A failed dial has no remote address to take the family from
+		check.IPVersion = family
+		outcomes = append(outcomes, "IPv"+family+": "+familyOutcome(check))
+		if worst.Status == "" || statusRank(check.Status) > statusRank(worst.Status) {
+			worst = check
+		}
+	}
+
+	if worst.Status != StatusUp {
+		worst.Reason = "over IPv" + worst.IPVersion + ": " + failureReason(worst)
+	}
+	worst.Detail = joinDetail(worst.Detail, strings.Join(outcomes, "; "))
+	return worst
+}
+
+// familyOutcome summarizes a check over one address family
+func familyOutcome(check HealthCheck) string {
+	if check.Status == StatusUp {
+		return fmt.Sprintf("UP in %dms", check.ResponseTime)
+	}
+	return check.Status + " (" + failureReason(check) + ")"
+}
+
+// failureReason returns why a check was not UP
+func failureReason(check HealthCheck) string {
+	if check.Reason != "" {
+		return check.Reason
+	}
+	return check.Error
+}
@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+func TestDualStackCheckReportsBrokenFamily(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	// The test server only listens on 127.0.0.1, so localhost works over
+	// IPv4 and fails over IPv6
+	server := statusServer(t, &status)
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	service := NewService(newRecorder(), nil, nil, log.New(io.Discard, "", 0), testConfig(), nil)
+	endpoint := config.Endpoint{
+		Name:      "api",
+		URL:       url,
+		Interval:  config.Duration(time.Minute),
+		Timeout:   config.Duration(5 * time.Second),
+		IPVersion: config.IPVersionBoth,
+	}
+
+	check := service.performHealthCheck(context.Background(), newClient(endpoint, nil, nil), endpoint)
+	if check.Status != StatusError {
+		t.Fatalf("status = %s, want %s", check.Status, StatusError)
+	}
+	if check.IPVersion != config.IPVersion6 || !strings.HasPrefix(check.Reason, "over IPv6: ") {
+		t.Errorf("ip version %q, reason %q; want the IPv6 failure", check.IPVersion, check.Reason)
+	}
+	if !strings.Contains(check.Detail, "IPv4: UP in ") || !strings.Contains(check.Detail, "IPv6: ERROR (") {
+		t.Errorf("detail = %q, want the outcome over each family", check.Detail)
+	}
+}
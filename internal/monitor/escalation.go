@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// escalation tracks an endpoint's progress up its escalation ladder while
+// it stays down
+type escalation struct {
+	steps      []config.EscalationStep
+	started    time.Time
+	next       int        // index of the next step to fire
+	timer      Timer      // fires the next step
+	transition Transition // the change that started the escalation
+	fired      []string   // notifiers reached so far
+	// paused is set while the next step waits for the incident's
+	// acknowledgement to lapse or be cleared
+	paused bool
+}
+
+// isDown reports whether a confirmed status is an incident under the policy
+func isDown(status string, policy AlertPolicy) bool {
+	return status == StatusError || (status == StatusDegraded && policy.AlertOnDegraded)
+}
+
+// escalationSteps returns the ladder for an endpoint: that of its first tag
+// with one, or the fallback ladder
+func (s *Service) escalationSteps(tags []string) []config.EscalationStep {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	escalations := s.config.Notifications.Escalations
+	for _, tag := range tags {
+		if steps, ok := escalations[tag]; ok {
+			return steps
+		}
+	}
+	return escalations[config.RouteFallback]
+}
+
+// updateEscalation starts an endpoint's escalation when it goes down and
+// cancels it on recovery. The returned names are the notifiers a cancelled
+// escalation had reached, which should hear about the recovery.
+func (s *Service) updateEscalation(monitor *EndpointMonitor, t Transition, policy AlertPolicy) []string {
+	down := isDown(t.Current, policy)
+
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	if e := monitor.escalation; e != nil {
+		if down {
+			// Still down, e.g. ERROR to DEGRADED; the ladder carries on
+			return nil
+		}
+		e.timer.Stop()
+		monitor.escalation = nil
+		return e.fired
+	}
+	if !down {
+		return nil
+	}
+
+	steps := s.escalationSteps(monitor.endpoint.Tags)
+	if len(steps) == 0 {
+		return nil
+	}
+	e := &escalation{steps: steps, started: s.clock.Now(), transition: t}
+	monitor.escalation = e
+	s.scheduleEscalation(monitor, e)
+	return nil
+}
+
+// scheduleEscalation arms the timer for an escalation's next step. The
+// caller holds monitor.mu.
+func (s *Service) scheduleEscalation(monitor *EndpointMonitor, e *escalation) {
+	delay := max(e.steps[e.next].After.ToDuration()-s.since(e.started), 0)
+	e.timer = s.clock.AfterFunc(delay, func() {
+		s.fireEscalation(monitor, e)
+	})
+}
+
+// fireEscalation notifies the next step's notifiers if the escalation is
+// still in progress, and schedules the step after it. While the incident is
+// acknowledged, the step is held until the acknowledgement expires.
+func (s *Service) fireEscalation(monitor *EndpointMonitor, e *escalation) {
+	s.mu.RLock()
+	current := s.endpoints[monitor.endpoint.URL] == monitor
+	s.mu.RUnlock()
+	ack, acknowledged := s.acknowledgement(monitor.endpoint.URL)
+
+	monitor.mu.Lock()
+	if !current || monitor.escalation != e {
+		monitor.mu.Unlock()
+		return
+	}
+	if acknowledged {
+		e.paused = true
+		if ack.Expires != nil {
+			e.timer = s.clock.AfterFunc(ack.Expires.Sub(s.clock.Now()), func() {
+				s.resumeEscalation(monitor)
+			})
+		}
+		monitor.mu.Unlock()
+		s.logger.Printf("Escalation of %s paused, acknowledged by %s", monitor.endpoint.URL, ack.By)
+		return
+	}
+	step := e.steps[e.next]
+	e.next++
+	e.fired = append(e.fired, step.Notifiers...)
+	if e.next < len(e.steps) {
+		s.scheduleEscalation(monitor, e)
+	}
+	t := e.transition
+	t.Current = monitor.state.Status
+	t.Duration = 0
+	t.Detail = fmt.Sprintf("still %s after %s, escalated", t.Current, step.After.ToDuration())
+	t.Notifiers = step.Notifiers
+	monitor.mu.Unlock()
+
+	if s.suppressedOffline(monitor.endpoint.URL, t.Current) || s.suppressed(monitor.endpoint.URL) ||
+		s.inMaintenance(monitor.endpoint) {
+		return
+	}
+	s.logger.Printf("Escalating %s to %v after %s", monitor.endpoint.URL, step.Notifiers, step.After.ToDuration())
+	if s.notifier != nil {
+		s.notifier.Notify(t)
+	}
+}
+
+// resumeEscalation fires the step of a paused escalation right away, with
+// the steps after it keeping their spacing from now
+func (s *Service) resumeEscalation(monitor *EndpointMonitor) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	e := monitor.escalation
+	if e == nil || !e.paused {
+		return
+	}
+	e.paused = false
+	e.started = s.clock.Now().Add(-e.steps[e.next].After.ToDuration())
+	s.scheduleEscalation(monitor, e)
+}
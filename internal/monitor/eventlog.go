@@ -0,0 +1,47 @@
+package monitor
+
+import (
+	"time"
+)
+
+// Event types recorded in the event log
+const (
+	EventStartup         = "startup"
+	EventShutdown        = "shutdown"
+	EventReload          = "reload"
+	EventNotification    = "notification"
+	EventConnectivity    = "connectivity"
+	EventWatchdog        = "watchdog"
+	EventOverride        = "override"
+	EventAcknowledgement = "acknowledgement"
+	EventPanic           = "panic"
+)
+
+// Event is an entry in monitord's log of its own significant events, kept
+// alongside the health checks so it outlives the process logs
+type Event struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	URL       string    `json:"url,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// recordEvent stores an event, logging rather than returning a failure so
+// the event log never interrupts monitoring
+func (s *Service) recordEvent(eventType, message string) {
+	s.recordEndpointEvent(eventType, "", message)
+}
+
+// recordEndpointEvent stores an event about one endpoint
+func (s *Service) recordEndpointEvent(eventType, url, message string) {
+	event := Event{
+		Timestamp: s.clock.Now(),
+		Type:      eventType,
+		URL:       url,
+		Message:   message,
+	}
+	if err := s.storage.SaveEvent(event); err != nil {
+		s.logger.Printf("Error recording %s event: %v", eventType, err)
+	}
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"sync"
+)
+
+// subscriberBuffer is the number of checks held for each subscriber. Checks
+// published while a subscriber's buffer is full are dropped for it, so a slow
+// reader never blocks the monitoring goroutines.
+const subscriberBuffer = 64
+
+// hub fans completed checks out to subscribers
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[chan HealthCheck]struct{}
+}
+
+// publish offers a check to every subscriber without blocking
+func (h *hub) publish(check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- check:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every check as it completes, and a
+// function that ends the subscription and closes the channel. Checks are
+// dropped for a subscriber that falls more than subscriberBuffer behind.
+func (s *Service) Subscribe() (<-chan HealthCheck, func()) {
+	ch := make(chan HealthCheck, subscriberBuffer)
+
+	s.events.mu.Lock()
+	if s.events.subscribers == nil {
+		s.events.subscribers = make(map[chan HealthCheck]struct{})
+	}
+	s.events.subscribers[ch] = struct{}{}
+	s.events.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.events.mu.Lock()
+			delete(s.events.subscribers, ch)
+			s.events.mu.Unlock()
+			close(ch)
+		})
+	}
+}
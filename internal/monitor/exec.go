@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Exec check settings
+const (
+	// maxExecOutput is the most command output kept as a check's detail
+	maxExecOutput = 4096
+	// execWaitDelay bounds how long a finished or killed command may hold
+	// its output open, e.g. through a child process it started
+	execWaitDelay = 5 * time.Second
+)
+
+// performExecCheck runs an exec endpoint's command without a shell. Exit
+// code 0 is UP and anything else, including a timeout, is an ERROR. A
+// non-zero exit status is the check's reason, while a command that could not
+// run or timed out is its error. The combined stdout and stderr become the
+// check's detail.
+func (s *Service) performExecCheck(ctx context.Context, check HealthCheck, endpoint config.Endpoint) HealthCheck {
+	if timeout := endpoint.Timeout.ToDuration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var output limitedBuffer
+	cmd := exec.CommandContext(ctx, endpoint.Command[0], endpoint.Command[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = execWaitDelay
+
+	start := s.clock.Now()
+	err := cmd.Run()
+	check.setResponseTime(s.since(start))
+	check.Detail = output.String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		check.Status = StatusUp
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		check.Status = StatusError
+		check.Error = fmt.Sprintf("command timed out after %s", endpoint.Timeout.ToDuration())
+	case errors.As(err, &exitErr):
+		check.Status = StatusError
+		check.Reason = fmt.Sprintf("command failed: %v", exitErr)
+	default:
+		check.Status = StatusError
+		check.Error = fmt.Sprintf("command failed: %v", err)
+	}
+
+	s.logCheck(check)
+	return check
+}
+
+// limitedBuffer keeps the first maxExecOutput bytes written to it and
+// discards the rest, so a chatty command cannot exhaust memory
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxExecOutput - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// String returns the kept output, trimmed and marked when truncated
+func (b *limitedBuffer) String() string {
+	out := strings.TrimSpace(b.buf.String())
+	if b.truncated {
+		out += " [truncated]"
+	}
+	return out
+}
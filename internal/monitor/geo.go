@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"log"
+	"net"
+	"slices"
+	"sync"
+
+	"github.com/will-wright-eng/monitord/internal/geoip"
+)
+
+// geoCacheSize bounds the cached lookups; the cache is emptied when full
+const geoCacheSize = 4096
+
+// Geo is the network and location of the address a check connected to,
+// looked up in the configured geoip_databases
+type Geo struct {
+	ASN     uint64 `json:"asn,omitempty"`
+	ASOrg   string `json:"as_org,omitempty"`
+	Country string `json:"country,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// geoLookup holds the open geoip_databases and caches lookups by address
+type geoLookup struct {
+	mu      sync.Mutex
+	paths   []string
+	readers []*geoip.Reader
+	cache   map[string]*Geo
+}
+
+// configure opens the configured databases, keeping the open ones and the
+// cache unless the paths changed. Databases that fail to open are logged and
+// skipped.
+func (g *geoLookup) configure(paths []string, logger *log.Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cache != nil && slices.Equal(g.paths, paths) {
+		return
+	}
+	g.paths = slices.Clone(paths)
+	g.readers = nil
+	for _, path := range paths {
+		reader, err := geoip.Open(path)
+		if err != nil {
+			logger.Printf("WARN geoip: %v", err)
+			continue
+		}
+		g.readers = append(g.readers, reader)
+	}
+	g.cache = make(map[string]*Geo)
+}
+
+// lookup returns what the databases record for ip, or nil when there are no
+// databases or none has an entry
+func (g *geoLookup) lookup(ip net.IP) *Geo {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.readers) == 0 || ip == nil {
+		return nil
+	}
+	key := ip.String()
+	if geo, ok := g.cache[key]; ok {
+		return geo
+	}
+
+	var geo Geo
+	for _, reader := range g.readers {
+		// A failed lookup in one database leaves its fields empty
+		info, _ := reader.Info(ip)
+		if geo.ASN == 0 {
+			geo.ASN, geo.ASOrg = info.ASN, info.Organization
+		}
+		if geo.Country == "" {
+			geo.Country = info.Country
+		}
+		if geo.City == "" {
+			geo.City = info.City
+		}
+	}
+	var result *Geo
+	if geo != (Geo{}) {
+		result = &geo
+	}
+	if len(g.cache) >= geoCacheSize {
+		clear(g.cache)
+	}
+	g.cache[key] = result
+	return result
+}
+
+// remoteIP returns the IP address of a connection's remote end, or nil when
+// it is unknown
+func remoteIP(addr net.Addr) net.IP {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP
+	}
+	return nil
+}
+
+// recordRemote stores the address a check connected to and, with
+// geoip_databases configured, its network and location
+func (s *Service) recordRemote(check *HealthCheck, addr net.Addr) {
+	ip := remoteIP(addr)
+	if ip == nil {
+		return
+	}
+	check.RemoteIP = ip.String()
+	check.Geo = s.geo.lookup(ip)
+}
@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+func TestStartupGraceHoldsNotifications(t *testing.T) {
+	const ok, bad = http.StatusOK, http.StatusInternalServerError
+	tests := []struct {
+		name     string
+		statuses []int32 // served for the checks at minutes 1 to 5
+		notified string  // status notified when the grace period ends
+	}{
+		{"still down", []int32{0, 0, 0, 0, 0}, StatusError},
+		{"down then degraded", []int32{0, 0, bad, bad, bad}, StatusDegraded},
+		{"recovered", []int32{0, 0, ok, ok, ok}, ""},
+		{"went down and recovered", []int32{ok, 0, 0, ok, ok}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status atomic.Int32
+			server := statusServer(t, &status)
+			cfg := testConfig(config.Endpoint{
+				Name:              "api",
+				URL:               server.URL,
+				Enabled:           true,
+				Interval:          config.Duration(time.Minute),
+				FailureThreshold:  1,
+				DegradedThreshold: 1,
+			})
+			cfg.Monitor.StartupGrace = config.Duration(5 * time.Minute)
+			_, clock, rec := startService(t, cfg, nil)
+			clock.waitForTimer(t, time.Minute)
+
+			// The grace period ends with the fifth check
+			for _, code := range tt.statuses {
+				status.Store(code)
+				clock.Advance(time.Minute)
+				rec.nextCheck(t)
+			}
+			if tt.notified == "" {
+				rec.noTransition(t)
+				return
+			}
+			select {
+			case transition := <-rec.transitions:
+				if transition.Current != tt.notified || transition.Previous != "" {
+					t.Fatalf("notified %q -> %s, want -> %s", transition.Previous, transition.Current, tt.notified)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s was not notified when the grace period ended", tt.notified)
+			}
+			rec.noTransition(t)
+		})
+	}
+}
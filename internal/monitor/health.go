@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// healthContentType is the media type of the health check response format
+// proposed in the IETF draft "Health Check Response Format for HTTP APIs"
+const healthContentType = "application/health+json"
+
+// healthBodyLimit bounds how much of a health document is read
+const healthBodyLimit = 1 << 20
+
+// healthStatuses maps the draft's status values, and the aliases it allows,
+// to check statuses
+var healthStatuses = map[string]string{
+	"pass":  StatusUp,
+	"ok":    StatusUp,
+	"up":    StatusUp,
+	"warn":  StatusDegraded,
+	"fail":  StatusError,
+	"error": StatusError,
+	"down":  StatusError,
+}
+
+// HealthComponent is one entry from the "checks" of a health document, such
+// as a database's connection count
+type HealthComponent struct {
+	// Name is the key the entry is listed under, e.g. "postgres:responseTime"
+	Name          string          `json:"name"`
+	ComponentID   string          `json:"component_id,omitempty"`
+	ComponentType string          `json:"component_type,omitempty"`
+	Status        string          `json:"status,omitempty"`
+	ObservedValue json.RawMessage `json:"observed_value,omitempty"`
+	ObservedUnit  string          `json:"observed_unit,omitempty"`
+	Output        string          `json:"output,omitempty"`
+}
+
+// healthDocument is the part of an application/health+json body monitord
+// reads
+type healthDocument struct {
+	Status string                          `json:"status"`
+	Output string                          `json:"output"`
+	Checks map[string][]healthCheckDetails `json:"checks"`
+}
+
+type healthCheckDetails struct {
+	ComponentID   string          `json:"componentId"`
+	ComponentType string          `json:"componentType"`
+	Status        string          `json:"status"`
+	ObservedValue json.RawMessage `json:"observedValue"`
+	ObservedUnit  string          `json:"observedUnit"`
+	Output        string          `json:"output"`
+}
+
+// isHealthDocument reports whether a response is an application/health+json
+// document
+func isHealthDocument(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == healthContentType
+}
+
+// readHealthDocument reads a health document body
+func readHealthDocument(body io.Reader) []byte {
+	data, _ := io.ReadAll(io.LimitReader(body, healthBodyLimit))
+	return data
+}
+
+// applyHealthDocument records the components of a health document on a check
+// and lowers its status to the document's when that is worse
+func applyHealthDocument(check *HealthCheck, data []byte) {
+	var doc healthDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		if check.Status == StatusUp {
+			check.Status, check.Reason = StatusDegraded, fmt.Sprintf("invalid health document: %v", err)
+		}
+		return
+	}
+	check.Components = doc.components()
+
+	status, ok := healthStatuses[strings.ToLower(doc.Status)]
+	if !ok {
+		if check.Status == StatusUp {
+			check.Status, check.Reason = StatusDegraded, fmt.Sprintf("health document status %q is not pass, warn or fail", doc.Status)
+		}
+		return
+	}
+	if statusRank(status) > statusRank(check.Status) {
+		check.Status, check.Reason = status, doc.reason(check.Components)
+	}
+}
+
+// components flattens the document's checks, ordered by name
+func (doc healthDocument) components() []HealthComponent {
+	names := make([]string, 0, len(doc.Checks))
+	for name := range doc.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var components []HealthComponent
+	for _, name := range names {
+		for _, details := range doc.Checks[name] {
+			components = append(components, HealthComponent{
+				Name:          name,
+				ComponentID:   details.ComponentID,
+				ComponentType: details.ComponentType,
+				Status:        details.Status,
+				ObservedValue: details.ObservedValue,
+				ObservedUnit:  details.ObservedUnit,
+				Output:        details.Output,
+			})
+		}
+	}
+	return components
+}
+
+// reason describes a warn or fail document by its output, or else by the
+// components that did not pass
+func (doc healthDocument) reason(components []HealthComponent) string {
+	reason := "health status " + strings.ToLower(doc.Status)
+	if doc.Output != "" {
+		return reason + ": " + doc.Output
+	}
+	var failing []string
+	for _, component := range components {
+		if status := strings.ToLower(component.Status); status != "" && healthStatuses[status] != StatusUp {
+			failing = append(failing, fmt.Sprintf("%s %s", component.Name, component.Status))
+		}
+	}
+	if len(failing) > 0 {
+		reason += " (" + strings.Join(failing, ", ") + ")"
+	}
+	return reason
+}
+
+// statusRank orders statuses from healthy to failed
+func statusRank(status string) int {
+	switch status {
+	case StatusUp:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
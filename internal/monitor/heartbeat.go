@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Heartbeat defaults for settings left empty
+const (
+	defaultHeartbeatInterval = time.Minute
+	defaultHeartbeatTimeout  = 10 * time.Second
+)
+
+// heartbeatSummary is the body of a heartbeat ping
+type heartbeatSummary struct {
+	Probe            string `json:"probe,omitempty"`
+	UptimeSeconds    int64  `json:"uptime_seconds"`
+	Endpoints        int    `json:"endpoints"`
+	Up               int    `json:"up"`
+	Degraded         int    `json:"degraded"`
+	Error            int    `json:"error"`
+	Unknown          int    `json:"unknown"`
+	Muted            bool   `json:"muted"`
+	ConnectivityLost bool   `json:"connectivity_lost"`
+}
+
+// heartbeat returns the heartbeat settings, or nil when none are configured
+func (s *Service) heartbeat() *config.HeartbeatConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Monitor.Heartbeat
+}
+
+// heartbeatInterval returns the configured heartbeat interval or the default
+func (s *Service) heartbeatInterval() time.Duration {
+	if cfg := s.heartbeat(); cfg != nil && cfg.Interval > 0 {
+		return cfg.Interval.ToDuration()
+	}
+	return defaultHeartbeatInterval
+}
+
+// sendHeartbeats pings the heartbeat URL right away and then every interval,
+// following reloads that add, change or remove the heartbeat
+func (s *Service) sendHeartbeats(ctx context.Context) {
+	defer s.shutdownWg.Done()
+
+	interval := s.heartbeatInterval()
+	ticker := s.clock.NewTicker(interval)
+	defer func() { ticker.Stop() }()
+
+	// held is whether pings are withheld because endpoints are stale, so
+	// only the change is logged
+	held := false
+	for {
+		if cfg := s.heartbeat(); cfg != nil {
+			held = s.ping(ctx, *cfg, held)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+		if next := s.heartbeatInterval(); next != interval {
+			ticker.Stop()
+			interval = next
+			ticker = s.clock.NewTicker(interval)
+		}
+	}
+}
+
+// ping sends one heartbeat unless an endpoint is stale, meaning the watchdog
+// found monitoring stuck, and reports whether it was withheld
+func (s *Service) ping(ctx context.Context, cfg config.HeartbeatConfig, held bool) bool {
+	if stale := s.staleCount(); stale > 0 {
+		if !held {
+			s.logger.Printf("WARN withholding heartbeats while %d endpoints are stale", stale)
+		}
+		return true
+	}
+	if held {
+		s.logger.Printf("No endpoints are stale, resuming heartbeats")
+	}
+
+	if err := s.postHeartbeat(ctx, cfg); err != nil && ctx.Err() == nil {
+		s.logger.Printf("Error sending heartbeat: %v", err)
+	}
+	return false
+}
+
+// staleCount returns how many endpoints the watchdog last found stale
+func (s *Service) staleCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stale := 0
+	for _, monitor := range s.endpoints {
+		monitor.mu.Lock()
+		if monitor.stale {
+			stale++
+		}
+		monitor.mu.Unlock()
+	}
+	return stale
+}
+
+// postHeartbeat posts the status summary to the heartbeat URL. The URL is
+// left out of errors since it usually carries the check's secret.
+func (s *Service) postHeartbeat(ctx context.Context, cfg config.HeartbeatConfig) error {
+	timeout := cfg.Timeout.ToDuration()
+	if timeout <= 0 {
+		timeout = defaultHeartbeatTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(s.heartbeatSummary())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid heartbeat url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("no response within %s", timeout)
+		}
+		return fmt.Errorf("request failed: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// heartbeatSummary counts the monitored endpoints by confirmed status
+func (s *Service) heartbeatSummary() heartbeatSummary {
+	status := s.Status()
+	summary := heartbeatSummary{
+		Probe:            s.probeName(),
+		UptimeSeconds:    int64(s.since(s.startedAt).Seconds()),
+		Muted:            status.Muted,
+		ConnectivityLost: status.ConnectivityLost,
+	}
+	for _, endpoint := range status.Endpoints {
+		if !endpoint.Enabled {
+			continue
+		}
+		summary.Endpoints++
+		switch endpoint.Status {
+		case StatusUp:
+			summary.Up++
+		case StatusDegraded:
+			summary.Degraded++
+		case StatusError:
+			summary.Error++
+		default:
+			summary.Unknown++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,265 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// calendarEvent is an event of an iCal feed
+type calendarEvent struct {
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+}
+
+// icalDefaultDuration is how long an event without an end lasts: a day for
+// an all-day event, nothing otherwise, as RFC 5545 specifies
+func icalDefaultDuration(allDay bool) time.Duration {
+	if allDay {
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// parseICal reads the events of an iCal feed. Cancelled events are left out.
+// Recurring events are not expanded, so only their first occurrence is
+// returned; recurring counts them.
+func parseICal(r io.Reader) (events []calendarEvent, recurring int, err error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		event     *calendarEvent
+		allDay    bool
+		duration  time.Duration
+		cancelled bool
+		repeats   bool
+		// nested counts the open components within the event, such as
+		// alarms, whose properties are not the event's
+		nested int
+	)
+	for i, line := range lines {
+		name, params, value, ok := splitICalLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &calendarEvent{}
+			allDay, duration, cancelled, repeats, nested = false, -1, false, false, 0
+		case event == nil:
+		case name == "BEGIN":
+			nested++
+		case nested > 0:
+			if name == "END" {
+				nested--
+			}
+		case name == "END" && value == "VEVENT":
+			if event.Start.IsZero() {
+				return nil, 0, fmt.Errorf("line %d: event without DTSTART", i+1)
+			}
+			if event.End.IsZero() {
+				if duration < 0 {
+					duration = icalDefaultDuration(allDay)
+				}
+				event.End = event.Start.Add(duration)
+			}
+			if !cancelled {
+				events = append(events, *event)
+				if repeats {
+					recurring++
+				}
+			}
+			event = nil
+		case name == "SUMMARY":
+			event.Summary = unescapeICalText(value)
+		case name == "DESCRIPTION":
+			event.Description = unescapeICalText(value)
+		case name == "CATEGORIES":
+			for _, category := range splitICalList(value) {
+				event.Categories = append(event.Categories, unescapeICalText(category))
+			}
+		case name == "STATUS":
+			cancelled = value == "CANCELLED"
+		case name == "RRULE" || name == "RDATE":
+			repeats = true
+		case name == "DTSTART":
+			event.Start, allDay, err = parseICalTime(value, params)
+		case name == "DTEND":
+			event.End, _, err = parseICalTime(value, params)
+		case name == "DURATION":
+			duration, err = parseICalDuration(value)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %s: %w", i+1, name, err)
+		}
+	}
+	return events, recurring, nil
+}
+
+// unfoldICal splits a feed into content lines, joining the continuation
+// lines of folded ones
+func unfoldICal(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICalLine splits a content line such as
+// "DTSTART;TZID=Europe/London:20261015T100000" into its upper-cased name,
+// parameters and value
+func splitICalLine(line string) (name string, params map[string]string, value string, ok bool) {
+	// Parameter values may be quoted and contain colons
+	colon := -1
+	quoted := false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return name, params, value, true
+}
+
+// parseICalTime parses a DATE or DATE-TIME value and reports whether it is a
+// date. Times without a zone or TZID, and dates, are in local time.
+func parseICalTime(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseICalDuration parses a DURATION value such as "PT1H30M" or "P1D"
+func parseICalDuration(value string) (time.Duration, error) {
+	rest, negative := strings.CutPrefix(value, "-")
+	rest = strings.TrimPrefix(rest, "+")
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var d time.Duration
+	inTime := false
+	number := 0
+	digits := false
+	for _, c := range rest {
+		switch {
+		case c >= '0' && c <= '9':
+			number = number*10 + int(c-'0')
+			digits = true
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		var unit time.Duration
+		switch {
+		case c == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case c == 'H' && inTime:
+			unit = time.Hour
+		case c == 'M' && inTime:
+			unit = time.Minute
+		case c == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d += time.Duration(number) * unit
+		number, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// splitICalList splits a comma-separated value, leaving escaped commas
+func splitICalList(value string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+// unescapeICalText undoes the escaping of a TEXT value
+func unescapeICalText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
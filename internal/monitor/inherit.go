@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// checkChanged reports whether two configurations of an endpoint check it
+// differently or judge its responses differently, so results under the old
+// one say nothing about the new one. Names, tags, intervals, timeouts,
+// thresholds and what is captured do not count.
+func checkChanged(a, b config.Endpoint) bool {
+	return a.Type != b.Type ||
+		a.Method != b.Method ||
+		a.Body != b.Body ||
+		!maps.Equal(a.Headers, b.Headers) ||
+		!preRequestEqual(a.PreRequest, b.PreRequest) ||
+		!stepsEqual(a.Steps, b.Steps) ||
+		!slices.Equal(a.Command, b.Command) ||
+		a.WebSocketPing != b.WebSocketPing ||
+		a.ExpectContinueTimeout != b.ExpectContinueTimeout ||
+		a.ChunkedBody != b.ChunkedBody ||
+		a.ExpectInaccessible != b.ExpectInaccessible ||
+		!maps.Equal(a.ExpectHeaders, b.ExpectHeaders) ||
+		a.ExpectRedirectTo != b.ExpectRedirectTo ||
+		a.MaxRedirects != b.MaxRedirects ||
+		a.ExpectProtocol != b.ExpectProtocol ||
+		a.HTTPVersion != b.HTTPVersion ||
+		a.MinTLSVersion != b.MinTLSVersion ||
+		a.IPVersion != b.IPVersion ||
+		a.DecodeBody != b.DecodeBody ||
+		a.DecodedBodyLimit != b.DecodedBodyLimit ||
+		!responseBaselineEqual(a.ResponseBaseline, b.ResponseBaseline)
+}
+
+// inheritState carries an endpoint's runtime state over from the monitor it
+// replaces on reload: its last result, consecutive failures, confirmed status
+// and since when, success-rate window, circuit breaker, alert cooldown,
+// escalation and any notification held for the startup grace period. The
+// old monitor's timers are stopped and re-armed for the new one, which is
+// not yet running.
+func (s *Service) inheritState(monitor, old *EndpointMonitor) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	monitor.lastCheck = old.lastCheck
+	monitor.lastStatus = old.lastStatus
+	monitor.lastStatusCode = old.lastStatusCode
+	monitor.lastResponseTime = old.lastResponseTime
+	monitor.lastError = old.lastError
+	monitor.lastReason = old.lastReason
+	monitor.state = old.state
+	monitor.successRate = old.successRate
+	monitor.skipped = old.skipped
+	monitor.stale = old.stale
+	if circuitBreakerEqual(monitor.endpoint.CircuitBreaker, old.endpoint.CircuitBreaker) {
+		monitor.breaker = old.breaker
+	}
+	if monitor.endpoint.CaptureBody {
+		monitor.lastBody = old.lastBody
+	}
+
+	monitor.lastNotified = old.lastNotified
+	monitor.notifiedStatus = old.notifiedStatus
+	monitor.deferred = old.deferred
+	monitor.offlineHeld = old.offlineHeld
+	monitor.offlineFrom = old.offlineFrom
+	monitor.graceHeld = old.graceHeld
+	old.deferred = nil
+	if old.cooldownTimer != nil {
+		old.cooldownTimer.Stop()
+		old.cooldownTimer = nil
+	}
+	if monitor.deferred != nil {
+		// The held notification is sent when the new cooldown ends
+		remaining := max(monitor.endpoint.AlertCooldown.ToDuration()-s.since(monitor.lastNotified), 0)
+		monitor.cooldownTimer = s.clock.AfterFunc(remaining, func() {
+			s.endCooldown(monitor)
+		})
+	}
+
+	if e := old.escalation; e != nil {
+		e.timer.Stop()
+		old.escalation = nil
+		monitor.escalation = e
+		if e.next < len(e.steps) {
+			s.scheduleEscalation(monitor, e)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// evaluated waits until the service has run check through alerting and
+// returns the endpoint's state after it
+func evaluated(t *testing.T, service *Service, check HealthCheck) AlertState {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		service.mu.RLock()
+		monitor := service.endpoints[check.URL]
+		service.mu.RUnlock()
+		monitor.mu.Lock()
+		state, done := monitor.state, monitor.lastCheck.Equal(check.Timestamp)
+		monitor.mu.Unlock()
+		if done {
+			return state
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("check at %s was not evaluated", check.Timestamp)
+	return AlertState{}
+}
+
+func TestReloadKeepsCounters(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := statusServer(t, &status)
+	endpoint := config.Endpoint{
+		Name:              "api",
+		URL:               server.URL,
+		Enabled:           true,
+		Interval:          config.Duration(time.Minute),
+		Timeout:           config.Duration(10 * time.Second),
+		FailureThreshold:  3,
+		DegradedThreshold: 3,
+	}
+	reloaded := testConfig(endpoint)
+	service, clock, rec := startService(t, reloaded, func() (*config.Config, error) {
+		cfg := reloaded
+		return &cfg, nil
+	})
+
+	// reload applies a change to the endpoint and waits for its restarted
+	// monitor to be scheduled
+	reload := func(change func(*config.Endpoint)) {
+		t.Helper()
+		change(&endpoint)
+		reloaded.Monitor.Endpoints = []config.Endpoint{endpoint}
+		if err := service.reloadConfig(); err != nil {
+			t.Fatalf("reloadConfig() = %v", err)
+		}
+		clock.waitForTimer(t, time.Minute)
+	}
+	// check runs the next check with the server answering code
+	check := func(code int) AlertState {
+		t.Helper()
+		status.Store(int32(code))
+		clock.Advance(time.Minute)
+		return evaluated(t, service, rec.nextCheck(t))
+	}
+	want := func(state AlertState, failures, degraded int, current string) {
+		t.Helper()
+		if state.Failures != failures || state.Degraded != degraded || state.Status != current {
+			t.Fatalf("failures %d, degraded %d, status %q; want %d, %d, %q",
+				state.Failures, state.Degraded, state.Status, failures, degraded, current)
+		}
+	}
+
+	clock.waitForTimer(t, time.Minute)
+	want(check(http.StatusOK), 0, 0, StatusUp)
+	check(http.StatusInternalServerError)
+	want(check(http.StatusInternalServerError), 0, 2, StatusUp)
+
+	// Timeouts are not part of the check, so the degraded count carries
+	// over and the next DEGRADED check confirms it
+	reload(func(e *config.Endpoint) { e.Timeout = config.Duration(5 * time.Second) })
+	want(check(http.StatusInternalServerError), 0, 3, StatusDegraded)
+
+	check(0)
+	want(check(0), 2, 3, StatusDegraded)
+	reload(func(e *config.Endpoint) { e.Tags = []string{"edge"} })
+	want(check(0), 3, 3, StatusError)
+
+	// Changing what is checked starts the counts over
+	check(http.StatusOK)
+	check(0)
+	reload(func(e *config.Endpoint) { e.Method = http.MethodHead })
+	want(check(0), 1, 0, "")
+}
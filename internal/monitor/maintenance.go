@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Maintenance calendar defaults for settings left empty
+const (
+	defaultCalendarInterval = 15 * time.Minute
+	defaultCalendarTimeout  = 10 * time.Second
+)
+
+// maintenance holds the events of the last maintenance calendar fetched.
+// It is empty while fetches fail, so no notifications are suppressed.
+type maintenance struct {
+	mu     sync.RWMutex
+	events []calendarEvent
+	// recurring is how many events of the last calendar repeat, so the
+	// warning about them is only logged when it changes
+	recurring int
+}
+
+// maintenanceCalendar returns the calendar settings, or nil when none are
+// configured
+func (s *Service) maintenanceCalendar() *config.MaintenanceCalendarConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Monitor.MaintenanceCalendar
+}
+
+// calendarInterval returns the configured calendar interval or the default
+func (s *Service) calendarInterval() time.Duration {
+	if cfg := s.maintenanceCalendar(); cfg != nil && cfg.Interval > 0 {
+		return cfg.Interval.ToDuration()
+	}
+	return defaultCalendarInterval
+}
+
+// watchMaintenance fetches the maintenance calendar right away and then
+// every interval, following reloads that add, change or remove it
+func (s *Service) watchMaintenance(ctx context.Context) {
+	defer s.shutdownWg.Done()
+
+	interval := s.calendarInterval()
+	ticker := s.clock.NewTicker(interval)
+	defer func() { ticker.Stop() }()
+
+	for {
+		s.refreshMaintenance(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+		if next := s.calendarInterval(); next != interval {
+			ticker.Stop()
+			interval = next
+			ticker = s.clock.NewTicker(interval)
+		}
+	}
+}
+
+// refreshMaintenance replaces the maintenance events with those of the
+// calendar. A failed fetch clears them, failing open.
+func (s *Service) refreshMaintenance(ctx context.Context) {
+	var events []calendarEvent
+	recurring := 0
+	if cfg := s.maintenanceCalendar(); cfg != nil {
+		var err error
+		events, recurring, err = s.fetchCalendar(ctx, *cfg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Printf("Error fetching maintenance calendar, no maintenance windows apply until it succeeds: %v", err)
+		}
+	}
+
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	if recurring > 0 && recurring != s.maintenance.recurring {
+		s.logger.Printf("WARN maintenance calendar has %d recurring events; only their first occurrence is used", recurring)
+	}
+	s.maintenance.events = events
+	s.maintenance.recurring = recurring
+}
+
+// fetchCalendar retrieves the calendar and returns its events whose summary
+// carries the configured tag. The URL is left out of errors since it may
+// carry a secret.
+func (s *Service) fetchCalendar(ctx context.Context, cfg config.MaintenanceCalendarConfig) ([]calendarEvent, int, error) {
+	timeout := cfg.Timeout.ToDuration()
+	if timeout <= 0 {
+		timeout = defaultCalendarTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return nil, 0, errors.New("invalid calendar url")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, fmt.Errorf("no response within %s", timeout)
+		}
+		return nil, 0, fmt.Errorf("request failed: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	events, recurring, err := parseICal(io.LimitReader(resp.Body, maxRemoteResponse))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid calendar: %w", err)
+	}
+	if cfg.SummaryTag == "" {
+		return events, recurring, nil
+	}
+	tag := strings.ToLower(cfg.SummaryTag)
+	events = slices.DeleteFunc(events, func(event calendarEvent) bool {
+		return !strings.Contains(strings.ToLower(event.Summary), tag)
+	})
+	return events, recurring, nil
+}
+
+// maintenanceWindow returns the calendar event in progress that covers the
+// endpoint, if any
+func (s *Service) maintenanceWindow(endpoint config.Endpoint) (calendarEvent, bool) {
+	cfg := s.maintenanceCalendar()
+	if cfg == nil {
+		return calendarEvent{}, false
+	}
+	now := s.clock.Now()
+	s.maintenance.mu.RLock()
+	defer s.maintenance.mu.RUnlock()
+	for _, event := range s.maintenance.events {
+		if !now.Before(event.Start) && now.Before(event.End) && coversEndpoint(event, endpoint, cfg.Match) {
+			return event, true
+		}
+	}
+	return calendarEvent{}, false
+}
+
+// coversEndpoint reports whether an event is maintenance of the endpoint
+// under a match rule
+func coversEndpoint(event calendarEvent, endpoint config.Endpoint, match string) bool {
+	switch match {
+	case config.MaintenanceMatchName:
+		text := strings.ToLower(event.Summary + "\n" + event.Description)
+		return endpoint.Name != "" && strings.Contains(text, strings.ToLower(endpoint.Name)) ||
+			strings.Contains(text, strings.ToLower(endpoint.URL))
+	case config.MaintenanceMatchTags:
+		words := strings.FieldsFunc(event.Summary, func(r rune) bool {
+			return r == ' ' || r == ',' || r == ';' || r == '(' || r == ')'
+		})
+		for _, tag := range endpoint.Tags {
+			if slices.ContainsFunc(event.Categories, func(category string) bool {
+				return strings.EqualFold(strings.TrimSpace(category), tag)
+			}) || slices.ContainsFunc(words, func(word string) bool {
+				return strings.EqualFold(word, "#"+tag)
+			}) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// inMaintenance reports, and logs, whether notifications for the endpoint
+// are suppressed by a maintenance window
+func (s *Service) inMaintenance(endpoint config.Endpoint) bool {
+	event, ok := s.maintenanceWindow(endpoint)
+	if !ok {
+		return false
+	}
+	s.logger.Printf("Notification for %s suppressed, maintenance %q until %s",
+		endpoint.URL, event.Summary, event.End.Format(time.RFC3339))
+	return true
+}
@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/metrics"
+)
+
+// Metrics holds the per-endpoint series updated by the service. A nil
+// *Metrics is valid and records nothing.
+type Metrics struct {
+	up           *metrics.GaugeVec
+	checks       *metrics.CounterVec
+	responseTime *metrics.HistogramVec
+	skipped      *metrics.CounterVec
+	stale        *metrics.CounterVec
+	droppedSaves *metrics.CounterVec
+	panics       *metrics.CounterVec
+	// The series about monitord itself, nil unless self metrics are on
+	saveDuration *metrics.HistogramVec
+	saveErrors   *metrics.CounterVec
+	reloads      *metrics.CounterVec
+	// confirmed makes the up gauge follow the confirmed status instead of
+	// the last check
+	confirmed bool
+}
+
+// saveBuckets are the save latency histogram bounds in seconds. Saves
+// usually take well under the 5ms the response-time buckets start at.
+var saveBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5}
+
+// NewMetrics registers the endpoint metrics, and the save and reload ones
+// when self metrics are on. Buckets are response-time histogram bounds in
+// seconds; metrics.DefaultBuckets is used when empty.
+func NewMetrics(registry *metrics.Registry, cfg config.MetricsConfig) *Metrics {
+	confirmed := cfg.UpStatus == config.UpStatusConfirmed
+	upHelp := "Whether the last check of the endpoint was UP (1) or not (0)."
+	if confirmed {
+		upHelp = "Whether the endpoint's confirmed status is UP (1) or not (0)."
+	}
+	m := &Metrics{
+		confirmed: confirmed,
+		up:        registry.NewGaugeVec("monitord_endpoint_up", upHelp, "name", "url"),
+		checks: registry.NewCounterVec("monitord_checks_total",
+			"Health checks performed, by resulting status.", "name", "url", "status"),
+		responseTime: registry.NewHistogramVec("monitord_response_time_seconds",
+			"Response time of health checks that received a response.", cfg.Buckets, "name", "url"),
+		skipped: registry.NewCounterVec("monitord_skipped_checks_total",
+			"Scheduled checks skipped because the previous check was still running.", "name", "url"),
+		stale: registry.NewCounterVec("monitord_stale_status_total",
+			"Times the endpoint's status went stale because its checks stopped completing.", "name", "url"),
+		droppedSaves: registry.NewCounterVec("monitord_dropped_saves_total",
+			"Check results dropped without being saved because the save queue was full.", "name", "url"),
+		panics: registry.NewCounterVec("monitord_panics_total",
+			"Times the endpoint's monitoring panicked and was restarted.", "name", "url"),
+	}
+	if cfg.ReportsSelf() {
+		m.saveDuration = registry.NewHistogramVec("monitord_save_duration_seconds",
+			"Time taken to save a check to the database.", saveBuckets)
+		m.saveErrors = registry.NewCounterVec("monitord_save_errors_total",
+			"Checks that failed to save.")
+		m.saveErrors.Add(0)
+		m.reloads = registry.NewCounterVec("monitord_config_reloads_total",
+			"Configuration reloads, by result.", "result")
+		m.reloads.Add(0, "success")
+		m.reloads.Add(0, "error")
+	}
+	return m
+}
+
+// timeSave runs save, recording how long it took and whether it failed
+func (m *Metrics) timeSave(save func() error) error {
+	if m == nil || m.saveDuration == nil {
+		return save()
+	}
+	start := time.Now()
+	err := save()
+	m.saveDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.saveErrors.Inc()
+	}
+	return err
+}
+
+// observeReload records a configuration reload and whether it failed
+func (m *Metrics) observeReload(err error) {
+	if m == nil || m.reloads == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.reloads.Inc(result)
+}
+
+// observeCheck records the result of a health check
+func (m *Metrics) observeCheck(check HealthCheck) {
+	if m == nil {
+		return
+	}
+
+	if !m.confirmed {
+		m.setUp(check.Name, check.URL, check.Status)
+	}
+	m.checks.Inc(check.Name, check.URL, check.Status)
+	if check.StatusCode != 0 {
+		seconds := (time.Duration(check.ResponseTimeMicros) * time.Microsecond).Seconds()
+		m.responseTime.Observe(seconds, check.Name, check.URL)
+	}
+}
+
+// observeConfirmed records an endpoint's confirmed status, which is empty
+// until the first one is established
+func (m *Metrics) observeConfirmed(name, url, status string) {
+	if m == nil || !m.confirmed || status == "" {
+		return
+	}
+	m.setUp(name, url, status)
+}
+
+func (m *Metrics) setUp(name, url, status string) {
+	up := 0.0
+	if status == StatusUp {
+		up = 1
+	}
+	m.up.Set(up, name, url)
+}
+
+// observeSkipped records scheduled checks that were skipped
+func (m *Metrics) observeSkipped(name, url string, skipped int) {
+	if m == nil {
+		return
+	}
+	m.skipped.Add(float64(skipped), name, url)
+}
+
+// observeStale records an endpoint whose status went stale
+func (m *Metrics) observeStale(name, url string) {
+	if m == nil {
+		return
+	}
+	m.stale.Inc(name, url)
+}
+
+// observeDroppedSave records a check result the save queue dropped
+func (m *Metrics) observeDroppedSave(name, url string) {
+	if m == nil {
+		return
+	}
+	m.droppedSaves.Inc(name, url)
+}
+
+// observePanic records a panic in an endpoint's monitoring
+func (m *Metrics) observePanic(name, url string) {
+	if m == nil {
+		return
+	}
+	m.panics.Inc(name, url)
+}
+
+// removeEndpoint drops the gauge for an endpoint that is no longer monitored.
+// Counters and histograms are kept so totals do not reset.
+func (m *Metrics) removeEndpoint(name, url string) {
+	if m == nil {
+		return
+	}
+	m.up.Delete(name, url)
+}
+
+// ActiveMonitors returns how many endpoints are being monitored
+func (s *Service) ActiveMonitors() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.endpoints)
+}
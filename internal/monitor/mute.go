@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"time"
+)
+
+// Mute suppresses all status-change notifications for the given duration.
+// Checks continue to run and be recorded, and alerting resumes automatically
+// when the mute expires. Muting again replaces the previous expiry.
+func (s *Service) Mute(d time.Duration) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until := s.clock.Now().Add(d)
+	s.mutedUntil = until
+	if s.muteTimer != nil {
+		s.muteTimer.Stop()
+	}
+	s.muteTimer = s.clock.AfterFunc(d, func() {
+		s.logger.Printf("Notification mute expired, alerting resumed")
+	})
+
+	s.logger.Printf("All notifications muted until %s", until.Format(time.RFC3339))
+	return until
+}
+
+// Unmute ends a mute early
+func (s *Service) Unmute() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.muteTimer != nil {
+		s.muteTimer.Stop()
+		s.muteTimer = nil
+	}
+	if s.clock.Now().Before(s.mutedUntil) {
+		s.logger.Printf("Notification mute cleared, alerting resumed")
+	}
+	s.mutedUntil = time.Time{}
+}
+
+// MutedUntil returns when the current mute expires, or the zero time when
+// notifications are not muted
+func (s *Service) MutedUntil() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.clock.Now().Before(s.mutedUntil) {
+		return s.mutedUntil
+	}
+	return time.Time{}
+}
@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Sources of an endpoint's enabled state, as reported in its status
+const (
+	EnabledByConfig   = "config"
+	EnabledByOperator = "operator"
+)
+
+// EndpointOverride is an enabled state set by an operator through the API in
+// place of the endpoint's enabled setting
+type EndpointOverride struct {
+	URL     string    `json:"url"`
+	Enabled bool      `json:"enabled"`
+	Updated time.Time `json:"updated"`
+	// ConfigEnabled is the endpoint's enabled setting when the override was
+	// made. A config that changes it takes precedence over the override.
+	ConfigEnabled bool `json:"config_enabled"`
+}
+
+// OverrideStore keeps operator overrides across restarts
+type OverrideStore interface {
+	EndpointOverrides() ([]EndpointOverride, error)
+	SaveEndpointOverride(override EndpointOverride) error
+	DeleteEndpointOverride(url string) error
+}
+
+// overrides holds the operator overrides by URL
+type overrides struct {
+	store   OverrideStore
+	entries map[string]EndpointOverride
+}
+
+// SetOverrideStore loads the overrides saved by a previous run and saves new
+// ones to store. Without one, overrides last until monitord stops. It is
+// called before Start, or once running when the database opens late; then
+// overrides made since win over saved ones and saved ones apply at once.
+func (s *Service) SetOverrideStore(store OverrideStore) error {
+	saved, err := store.EndpointOverrides()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides.store = store
+	for _, override := range saved {
+		if _, ok := s.overrides.entries[override.URL]; ok {
+			continue
+		}
+		s.overrides.entries[override.URL] = override
+		if endpoint, ok := s.configuredEndpoint(override.URL); ok && !s.startedAt.IsZero() {
+			if err := s.applyEnabled(endpoint); err != nil {
+				s.logger.Printf("Error applying saved override for %s: %v", override.URL, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SetEnabled starts or stops monitoring a configured endpoint regardless of
+// its enabled setting, until the config changes that setting or the override
+// is cleared
+func (s *Service) SetEnabled(url string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.configuredEndpoint(url)
+	if !ok {
+		return ErrUnknownEndpoint
+	}
+	override := EndpointOverride{
+		URL:           url,
+		Enabled:       enabled,
+		Updated:       s.clock.Now(),
+		ConfigEnabled: endpoint.Enabled,
+	}
+	if store := s.overrides.store; store != nil {
+		if err := store.SaveEndpointOverride(override); err != nil {
+			return fmt.Errorf("failed to save override: %w", err)
+		}
+	}
+	s.overrides.entries[url] = override
+
+	s.recordEndpointEvent(EventOverride, url, enabledLabel(enabled)+" by operator")
+	return s.applyEnabled(endpoint)
+}
+
+// ClearOverride drops the operator override of an endpoint, so its enabled
+// setting applies again
+func (s *Service) ClearOverride(url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.configuredEndpoint(url)
+	if !ok {
+		return ErrUnknownEndpoint
+	}
+	if _, ok := s.overrides.entries[url]; !ok {
+		return nil
+	}
+	if store := s.overrides.store; store != nil {
+		if err := store.DeleteEndpointOverride(url); err != nil {
+			return fmt.Errorf("failed to delete override: %w", err)
+		}
+	}
+	delete(s.overrides.entries, url)
+	s.recordEndpointEvent(EventOverride, url, "override cleared, following the config")
+	return s.applyEnabled(endpoint)
+}
+
+// configuredEndpoint returns the endpoint with the given URL from the running
+// config. The caller holds s.mu.
+func (s *Service) configuredEndpoint(url string) (config.Endpoint, bool) {
+	for _, endpoint := range s.config.Monitor.Endpoints {
+		if endpoint.URL == url {
+			return endpoint, true
+		}
+	}
+	return config.Endpoint{}, false
+}
+
+// applyEnabled starts or stops monitoring an endpoint to match whether it is
+// enabled. The caller holds s.mu.
+func (s *Service) applyEnabled(endpoint config.Endpoint) error {
+	running := s.endpoints[endpoint.URL]
+	enabled, _ := s.enabled(endpoint)
+	switch {
+	case enabled && running == nil:
+		s.logger.Printf("Starting monitoring of %s", endpoint.URL)
+		return s.startEndpoint(context.Background(), endpoint, 0, nil)
+	case !enabled && running != nil:
+		s.logger.Printf("Stopping monitoring of %s", endpoint.URL)
+		running.cancel()
+		delete(s.endpoints, endpoint.URL)
+		s.metrics.removeEndpoint(endpoint.Name, endpoint.URL)
+	}
+	return nil
+}
+
+// enabled reports whether an endpoint is monitored and whether that comes
+// from the config or an operator override. The caller holds s.mu.
+func (s *Service) enabled(endpoint config.Endpoint) (bool, string) {
+	override, ok := s.overrides.entries[endpoint.URL]
+	if ok && override.ConfigEnabled == endpoint.Enabled {
+		return override.Enabled, EnabledByOperator
+	}
+	return endpoint.Enabled, EnabledByConfig
+}
+
+// enabledLabel describes an enabled state
+func enabledLabel(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// pruneOverrides drops the overrides of endpoints whose enabled setting was
+// changed in the config since, as the config then takes precedence. The
+// caller holds s.mu.
+func (s *Service) pruneOverrides(endpoints []config.Endpoint) {
+	for _, endpoint := range endpoints {
+		override, ok := s.overrides.entries[endpoint.URL]
+		if !ok || override.ConfigEnabled == endpoint.Enabled {
+			continue
+		}
+		s.logger.Printf("Config changed enabled for %s, dropping its operator override", endpoint.URL)
+		if store := s.overrides.store; store != nil {
+			if err := store.DeleteEndpointOverride(endpoint.URL); err != nil {
+				s.logger.Printf("Error deleting override for %s: %v", endpoint.URL, err)
+			}
+		}
+		delete(s.overrides.entries, endpoint.URL)
+	}
+}
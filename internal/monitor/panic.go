@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// recoverMonitor is deferred by the goroutines that run an endpoint's checks.
+// A panic is logged with its stack, counted and recorded in the event log,
+// and the endpoint's monitoring is restarted, so one misbehaving endpoint
+// cannot take the others down with monitord.
+func (s *Service) recoverMonitor(ctx context.Context, monitor *EndpointMonitor) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	url := monitor.endpoint.URL
+	s.logger.Printf("ERROR monitoring of %s panicked: %v\n%s", url, r, debug.Stack())
+	s.metrics.observePanic(monitor.endpoint.Name, url)
+	message := fmt.Sprintf("monitoring panicked: %v", r)
+	s.recordEndpointEvent(EventPanic, url, message+s.restartPanicked(ctx, monitor))
+}
+
+// restartPanicked replaces a monitor whose goroutine panicked with a new one
+// that checks after an interval, so a check that always panics does not
+// spin. It returns how the restart went for the event log.
+func (s *Service) restartPanicked(ctx context.Context, monitor *EndpointMonitor) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	url := monitor.endpoint.URL
+	if ctx.Err() != nil || s.endpoints[url] != monitor {
+		// Stopped, or already replaced by a reload
+		return ""
+	}
+	monitor.cancel()
+
+	// A panic while the monitor's lock was held leaves it locked and its
+	// state possibly half updated, so the new monitor starts afresh
+	previous, note := monitor, ""
+	if monitor.mu.TryLock() {
+		monitor.mu.Unlock()
+	} else {
+		previous, note = nil, " without its alert state"
+	}
+	if err := s.startEndpoint(context.Background(), monitor.endpoint, monitor.endpoint.Interval.ToDuration(), previous); err != nil {
+		s.logger.Printf("ERROR failed to restart monitoring of %s after a panic: %v", url, err)
+		return fmt.Sprintf("; restart failed: %v", err)
+	}
+	s.logger.Printf("Restarted monitoring of %s after a panic%s", url, note)
+	return "; restarted its monitoring" + note
+}
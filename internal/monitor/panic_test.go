@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// panicLog is a log writer that panics on the first line containing trigger,
+// standing in for a bug in a check, and keeps what it is given
+type panicLog struct {
+	trigger string
+	fired   atomic.Bool
+
+	mu    sync.Mutex
+	lines strings.Builder
+}
+
+func (l *panicLog) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), l.trigger) && l.fired.CompareAndSwap(false, true) {
+		panic("injected check panic")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines.Write(p)
+}
+
+func (l *panicLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines.String()
+}
+
+func TestCheckPanicRestartsEndpoint(t *testing.T) {
+	for _, scheduler := range []string{config.SchedulerGoroutines, config.SchedulerHeap} {
+		t.Run(scheduler, func(t *testing.T) {
+			var status atomic.Int32
+			status.Store(http.StatusOK)
+			server := statusServer(t, &status)
+			bad, good := server.URL+"/bad", server.URL+"/good"
+			cfg := testConfig(
+				config.Endpoint{Name: "bad", URL: bad, Enabled: true, Interval: config.Duration(time.Minute)},
+				config.Endpoint{Name: "good", URL: good, Enabled: true, Interval: config.Duration(time.Minute)},
+			)
+			cfg.Monitor.Scheduler = scheduler
+			// The check logs as it starts, so panicking in the log write
+			// panics in the middle of the check
+			logs := &panicLog{trigger: "Starting health check for endpoint: " + bad}
+			_, clock, rec := startServiceLogging(t, cfg, nil, log.New(logs, "", 0))
+
+			if scheduler == config.SchedulerHeap {
+				clock.waitForTimer(t, time.Minute)
+			} else {
+				clock.waitForTimers(t, 2)
+			}
+			clock.Advance(time.Minute)
+			if check := rec.nextCheck(t); check.URL != good {
+				t.Fatalf("checked %s, want only %s to complete", check.URL, good)
+			}
+			event := rec.waitForEvent(t, EventPanic)
+			if event.URL != bad || !strings.Contains(event.Message, "injected check panic") || !strings.Contains(event.Message, "restarted its monitoring") {
+				t.Fatalf("panic event = %+v", event)
+			}
+			if !strings.Contains(logs.String(), "ERROR monitoring of "+bad+" panicked: injected check panic") {
+				t.Errorf("panic was not logged as an error:\n%s", logs)
+			}
+
+			// Both endpoints are checked again, the restarted one included.
+			// The restart reschedules it while other timers may be set, so
+			// the clock moves on until both have been checked.
+			checked := map[string]bool{}
+			deadline := time.Now().Add(5 * time.Second)
+			for len(checked) < 2 && time.Now().Before(deadline) {
+				clock.Advance(time.Second)
+				select {
+				case check := <-rec.checks:
+					checked[check.URL] = true
+				case <-time.After(time.Millisecond):
+				}
+			}
+			if !checked[bad] || !checked[good] {
+				t.Fatalf("checked %v after the panic, want both endpoints", checked)
+			}
+		})
+	}
+}
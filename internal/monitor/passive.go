@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// ErrNotPassive is returned when a check is reported for an endpoint that
+// monitord checks itself
+var ErrNotPassive = errors.New("endpoint is not passive")
+
+// CheckReport is the result of a check of a passive endpoint, run elsewhere
+// and reported through the API
+type CheckReport struct {
+	// Status defaults to UP
+	Status       string `json:"status,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+	ResponseTime int64  `json:"responseTime,omitempty"`
+}
+
+// ReportCheck records a check of a passive endpoint, which is saved and
+// alerted on like a scheduled check and restarts the wait for the next report
+func (s *Service) ReportCheck(url string, report CheckReport) (HealthCheck, error) {
+	s.mu.RLock()
+	monitor, ok := s.endpoints[url]
+	s.mu.RUnlock()
+	if !ok {
+		return HealthCheck{}, ErrUnknownEndpoint
+	}
+	if monitor.reported == nil {
+		return HealthCheck{}, ErrNotPassive
+	}
+
+	status := report.Status
+	switch status {
+	case "":
+		status = StatusUp
+	case StatusUp, StatusDegraded, StatusError:
+	default:
+		return HealthCheck{}, fmt.Errorf("status must be %q, %q or %q, got %q",
+			StatusUp, StatusDegraded, StatusError, status)
+	}
+	detail := report.Detail
+	if len(detail) > maxExecOutput {
+		detail = detail[:maxExecOutput]
+	}
+	check := HealthCheck{
+		Name:      monitor.endpoint.Name,
+		URL:       url,
+		Status:    status,
+		Timestamp: s.clock.Now(),
+		Error:     report.Error,
+		Reason:    report.Reason,
+		Tags:      monitor.endpoint.Tags,
+		Probe:     s.probeName(),
+		Detail:    detail,
+	}
+	check.setResponseTime(time.Duration(report.ResponseTime) * time.Millisecond)
+	s.recordCheck(monitor, check)
+	select {
+	case monitor.reported <- struct{}{}:
+	default:
+	}
+	return check, nil
+}
+
+// watchPassive waits for reports of a passive endpoint and records an ERROR
+// check each time one is not received within its interval and grace
+func (s *Service) watchPassive(ctx context.Context, monitor *EndpointMonitor) {
+	defer s.shutdownWg.Done()
+	defer s.recoverMonitor(ctx, monitor)
+
+	interval := monitor.endpoint.Interval.ToDuration()
+	window := interval + monitor.endpoint.Grace.ToDuration()
+
+	// A restarted monitor keeps waiting from the last report it inherited
+	monitor.mu.Lock()
+	last := monitor.lastCheck
+	monitor.mu.Unlock()
+	if last.IsZero() {
+		last = s.clock.Now()
+	}
+	deadline := last.Add(window)
+
+	for {
+		monitor.setNextCheck(deadline)
+		fired := make(chan struct{})
+		timer := s.clock.AfterFunc(deadline.Sub(s.clock.Now()), func() { close(fired) })
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Printf("Stopping monitoring for endpoint: %s", monitor.endpoint.URL)
+			return
+		case <-monitor.reported:
+			timer.Stop()
+			deadline = s.clock.Now().Add(window)
+		case <-fired:
+			s.recordCheck(monitor, s.missedReport(monitor.endpoint, deadline))
+			// Further misses are counted every interval, as reports are due
+			deadline = deadline.Add(interval)
+		}
+	}
+}
+
+// missedReport is the check recorded when a passive endpoint's report is
+// overdue
+func (s *Service) missedReport(endpoint config.Endpoint, deadline time.Time) HealthCheck {
+	return HealthCheck{
+		Name:      endpoint.Name,
+		URL:       endpoint.URL,
+		Status:    StatusError,
+		Timestamp: deadline,
+		Reason: fmt.Sprintf("no report received, expected every %s with %s grace",
+			endpoint.Interval.ToDuration(), endpoint.Grace.ToDuration()),
+		Tags:  endpoint.Tags,
+		Probe: s.probeName(),
+	}
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// performPreRequest sends an endpoint's pre-request with the endpoint's
+// client, whose cookie jar keeps the cookies it sets. It returns an empty
+// status on success; otherwise the status for the check with either the
+// reason the response was rejected or the transport error, both naming the
+// pre-request so its failures are told apart from the check's own.
+func performPreRequest(ctx context.Context, client *http.Client, pre config.PreRequest) (string, string, error) {
+	req, err := newRequest(ctx, config.Endpoint{
+		URL:     pre.URL,
+		Method:  pre.Method,
+		Body:    pre.Body,
+		Headers: pre.Headers,
+	})
+	if err != nil {
+		return StatusError, "", fmt.Errorf("pre-request failed: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return StatusError, "", fmt.Errorf("pre-request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused for the check
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return StatusDegraded, fmt.Sprintf("pre-request to %s returned %d", pre.URL, resp.StatusCode), nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// hostLimits holds the rate limiters for the configured host_rate_limits,
+// created on first use for each host
+type hostLimits struct {
+	mu       sync.Mutex
+	config   []config.HostRateLimit
+	patterns []*regexp.Regexp
+	limiters map[string]*rate.Limiter
+}
+
+// configure applies the configured limits. Existing limiters are kept
+// unless the limits changed.
+func (h *hostLimits) configure(limits []config.HostRateLimit) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.limiters != nil && slices.Equal(h.config, limits) {
+		return
+	}
+	h.config = slices.Clone(limits)
+	h.patterns = make([]*regexp.Regexp, len(limits))
+	for i, limit := range limits {
+		h.patterns[i] = globRegexp(strings.ToLower(limit.Host))
+	}
+	h.limiters = make(map[string]*rate.Limiter)
+}
+
+// limiter returns the limiter for a URL's host, or nil when the host is not
+// limited
+func (h *hostLimits) limiter(rawURL string) *rate.Limiter {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if limiter, ok := h.limiters[host]; ok || h.limiters == nil {
+		return limiter
+	}
+	for i, pattern := range h.patterns {
+		if pattern.MatchString(host) {
+			limit := h.config[i]
+			burst := limit.Burst
+			if burst == 0 {
+				burst = 1
+			}
+			limiter := rate.NewLimiter(rate.Limit(limit.Rate), burst)
+			h.limiters[host] = limiter
+			return limiter
+		}
+	}
+	h.limiters[host] = nil
+	return nil
+}
+
+// waitForHost blocks until a check may be sent to the endpoint's host. It
+// returns an error only when ctx is done first.
+func (s *Service) waitForHost(ctx context.Context, endpoint config.Endpoint) error {
+	limiter := s.limits.limiter(endpoint.URL)
+	if limiter == nil {
+		return nil
+	}
+	if err := limiter.Wait(ctx); err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return nil
+}
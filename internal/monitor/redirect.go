@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// RedirectHop is a redirect followed on the way to a check's final response
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	// Time is how long the request to URL took to return the redirect, in
+	// milliseconds
+	Time int64 `json:"time_ms"`
+}
+
+// redirectTraceKey is the request context key of a check's redirectTrace
+type redirectTraceKey struct{}
+
+// redirectTrace records the redirects followed by one check. At most
+// config.RedirectLimit hops are followed, which bounds the chain.
+type redirectTrace struct {
+	mu    sync.Mutex
+	clock Clock
+	last  time.Time
+	hops  []RedirectHop
+}
+
+// withRedirectTrace returns a context that records the redirects followed
+// by a request sent at start
+func withRedirectTrace(ctx context.Context, clock Clock, start time.Time) (context.Context, *redirectTrace) {
+	trace := &redirectTrace{clock: clock, last: start}
+	return context.WithValue(ctx, redirectTraceKey{}, trace), trace
+}
+
+// add records a hop, timing it from the previous one
+func (t *redirectTrace) add(url string, statusCode int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	t.hops = append(t.hops, RedirectHop{URL: url, StatusCode: statusCode, Time: now.Sub(t.last).Milliseconds()})
+	t.last = now
+}
+
+// chain returns the hops recorded so far, or nil when there were none
+func (t *redirectTrace) chain() []RedirectHop {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.hops
+}
+
+// followRedirect is the redirect policy of clients that follow redirects. It
+// records each hop on the check's redirectTrace, if any, and stops after
+// config.RedirectLimit redirects like the default policy.
+func followRedirect(req *http.Request, via []*http.Request) error {
+	if trace, ok := req.Context().Value(redirectTraceKey{}).(*redirectTrace); ok && req.Response != nil {
+		trace.add(via[len(via)-1].URL.String(), req.Response.StatusCode)
+	}
+	if len(via) >= config.RedirectLimit {
+		return fmt.Errorf("stopped after %d redirects", config.RedirectLimit)
+	}
+	return nil
+}
+
+// applyMaxRedirects marks a successful check DEGRADED when it followed more
+// redirects than the endpoint's max_redirects
+func applyMaxRedirects(check *HealthCheck, endpoint config.Endpoint) {
+	n := len(check.Redirects)
+	if endpoint.MaxRedirects == 0 || n <= endpoint.MaxRedirects || check.Status != StatusUp {
+		return
+	}
+	check.Status = StatusDegraded
+	check.Reason = fmt.Sprintf("followed %d redirects, more than max_redirects %d", n, endpoint.MaxRedirects)
+}
+
+// matchRedirect checks that a response redirects to the expected location
+// and describes the mismatch. A relative Location is resolved against the
+// request URL first. The expected value matches exactly, by prefix with a
+// "prefix:" prefix, or as a pattern with a "glob:" prefix, where * matches any
+// run of characters.
+func matchRedirect(expected string, resp *http.Response) string {
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return fmt.Sprintf("expected a redirect to %s, got status %d", expected, resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return fmt.Sprintf("redirect with status %d has no Location header", resp.StatusCode)
+	}
+	if resp.Request != nil {
+		if resolved, err := resp.Request.URL.Parse(location); err == nil {
+			location = resolved.String()
+		}
+	}
+
+	if !redirectMatches(expected, location) {
+		return fmt.Sprintf("redirect to %s, expected %s", location, expected)
+	}
+	return ""
+}
+
+// redirectMatches reports whether a location satisfies an expect_redirect_to
+// value
+func redirectMatches(expected, location string) bool {
+	if prefix, ok := strings.CutPrefix(expected, "prefix:"); ok {
+		return strings.HasPrefix(location, prefix)
+	}
+	if glob, ok := strings.CutPrefix(expected, "glob:"); ok {
+		return globRegexp(glob).MatchString(location)
+	}
+	return location == expected
+}
+
+// globRegexp converts a glob in which * matches any run of characters to an
+// anchored regular expression
+func globRegexp(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
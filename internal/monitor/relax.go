@@ -0,0 +1,165 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Relaxation is an endpoint checked with a longer timeout and higher alert
+// thresholds until Until, such as during a deploy
+type Relaxation struct {
+	Until             time.Time       `json:"until"`
+	Timeout           config.Duration `json:"timeout,omitempty"`
+	FailureThreshold  int             `json:"failure_threshold"`
+	DegradedThreshold int             `json:"degraded_threshold"`
+}
+
+// relaxations holds when each relaxed endpoint's relaxation ends, by URL,
+// with the timers that record its end
+type relaxations struct {
+	mu     sync.Mutex
+	until  map[string]time.Time
+	timers map[string]Timer
+}
+
+// relaxEndpoint returns the endpoint with its relax settings in place of
+// its timeout and thresholds, or twice those where none are set
+func relaxEndpoint(endpoint config.Endpoint) config.Endpoint {
+	var relax config.RelaxSettings
+	if endpoint.Relax != nil {
+		relax = *endpoint.Relax
+	}
+	if relax.Timeout == 0 {
+		relax.Timeout = 2 * endpoint.Timeout
+	}
+	if relax.FailureThreshold == 0 {
+		relax.FailureThreshold = 2 * max(endpoint.FailureThreshold, 1)
+	}
+	if relax.DegradedThreshold == 0 {
+		relax.DegradedThreshold = 2 * max(endpoint.DegradedThreshold, 1)
+	}
+	endpoint.Timeout = relax.Timeout
+	endpoint.FailureThreshold = relax.FailureThreshold
+	endpoint.DegradedThreshold = relax.DegradedThreshold
+	return endpoint
+}
+
+// Relax checks a monitored endpoint with its relax settings for d, after
+// which its own apply again. Relaxing it again replaces the end time.
+func (s *Service) Relax(url string, d time.Duration) (Relaxation, error) {
+	s.mu.RLock()
+	monitor, ok := s.endpoints[url]
+	s.mu.RUnlock()
+	if !ok {
+		return Relaxation{}, ErrUnknownEndpoint
+	}
+
+	until := s.clock.Now().Add(d)
+	s.relaxed.mu.Lock()
+	if timer, ok := s.relaxed.timers[url]; ok {
+		timer.Stop()
+	}
+	s.relaxed.until[url] = until
+	s.relaxed.timers[url] = s.clock.AfterFunc(d, func() {
+		s.relaxed.mu.Lock()
+		current := s.relaxed.until[url].Equal(until)
+		if current {
+			delete(s.relaxed.until, url)
+			delete(s.relaxed.timers, url)
+		}
+		s.relaxed.mu.Unlock()
+		if !current {
+			return
+		}
+		s.logger.Printf("Relaxation of %s expired, its own timeout and thresholds apply again", url)
+		s.recordEndpointEvent(EventOverride, url, "relaxation expired")
+	})
+	s.relaxed.mu.Unlock()
+
+	relaxation := newRelaxation(monitor.endpoint, until)
+	message := "relaxed until " + until.Format(time.RFC3339) + ": " + relaxation.String()
+	s.logger.Printf("Endpoint %s %s", url, message)
+	s.recordEndpointEvent(EventOverride, url, message)
+	return relaxation, nil
+}
+
+// Unrelax ends an endpoint's relaxation early
+func (s *Service) Unrelax(url string) error {
+	s.mu.RLock()
+	_, ok := s.endpoints[url]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrUnknownEndpoint
+	}
+
+	s.relaxed.mu.Lock()
+	until, ok := s.relaxed.until[url]
+	if ok {
+		s.relaxed.timers[url].Stop()
+		delete(s.relaxed.until, url)
+		delete(s.relaxed.timers, url)
+	}
+	s.relaxed.mu.Unlock()
+	if ok && s.clock.Now().Before(until) {
+		s.logger.Printf("Relaxation of %s cleared, its own timeout and thresholds apply again", url)
+		s.recordEndpointEvent(EventOverride, url, "relaxation cleared")
+	}
+	return nil
+}
+
+// relaxation returns the endpoint's relaxation while it is in effect
+func (s *Service) relaxation(endpoint config.Endpoint) (Relaxation, bool) {
+	s.relaxed.mu.Lock()
+	until, ok := s.relaxed.until[endpoint.URL]
+	s.relaxed.mu.Unlock()
+	if !ok || !s.clock.Now().Before(until) {
+		return Relaxation{}, false
+	}
+	return newRelaxation(endpoint, until), true
+}
+
+// String describes the relaxed settings, leaving out a timeout when the
+// endpoint has none
+func (r Relaxation) String() string {
+	thresholds := fmt.Sprintf("failure_threshold %d, degraded_threshold %d", r.FailureThreshold, r.DegradedThreshold)
+	if r.Timeout == 0 {
+		return thresholds
+	}
+	return fmt.Sprintf("timeout %s, %s", r.Timeout.ToDuration(), thresholds)
+}
+
+// newRelaxation describes the relaxed settings of an endpoint
+func newRelaxation(endpoint config.Endpoint, until time.Time) Relaxation {
+	relaxed := relaxEndpoint(endpoint)
+	return Relaxation{
+		Until:             until,
+		Timeout:           relaxed.Timeout,
+		FailureThreshold:  relaxed.FailureThreshold,
+		DegradedThreshold: relaxed.DegradedThreshold,
+	}
+}
+
+// checkedEndpoint returns the endpoint settings a check or its evaluation
+// uses: relaxed while a relaxation is in effect, or the endpoint's own
+func (s *Service) checkedEndpoint(endpoint config.Endpoint) config.Endpoint {
+	if _, ok := s.relaxation(endpoint); ok {
+		return relaxEndpoint(endpoint)
+	}
+	return endpoint
+}
+
+// checkClient returns the client to check the endpoint with: a copy of
+// client, sharing its connections and cookies, with the relaxed timeout when
+// the endpoint's is relaxed
+func checkClient(client *http.Client, endpoint config.Endpoint) *http.Client {
+	if timeout := endpoint.Timeout.ToDuration(); timeout != client.Timeout {
+		relaxed := *client
+		relaxed.Timeout = timeout
+		return &relaxed
+	}
+	return client
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+const (
+	defaultRemoteTimeout = 10 * time.Second
+	// maxRemoteResponse bounds the size of a remote endpoint list
+	maxRemoteResponse = 10 << 20
+)
+
+// remoteEndpoints remembers the last endpoint list fetched successfully from
+// remote_endpoints, so a failed fetch never drops the remote endpoints
+type remoteEndpoints struct {
+	lastGood []config.Endpoint
+	// fetched is set once a fetch has succeeded, so the endpoints monitored
+	// are known to include the remote ones
+	fetched atomic.Bool
+}
+
+// fetch retrieves and validates the remote endpoint list
+func (r *remoteEndpoints) fetch(cfg config.RemoteEndpointsConfig) ([]config.Endpoint, error) {
+	timeout := cfg.Timeout.ToDuration()
+	if timeout <= 0 {
+		timeout = defaultRemoteTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var endpoints []config.Endpoint
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRemoteResponse)).Decode(&endpoints); err != nil {
+		return nil, fmt.Errorf("invalid endpoint list: %w", err)
+	}
+	if err := config.ValidateRemoteEndpoints(endpoints); err != nil {
+		return nil, fmt.Errorf("invalid endpoint list: %w", err)
+	}
+	return endpoints, nil
+}
+
+// resolveEndpoints returns the configured endpoints merged with the remote
+// list. Endpoints in the config file take precedence over remote ones with
+// the same URL. If the remote list cannot be fetched the last good one is
+// used instead.
+func (s *Service) resolveEndpoints(cfg config.MonitorConfig) []config.Endpoint {
+	if cfg.RemoteEndpoints == nil {
+		s.remote.lastGood = nil
+		s.remote.fetched.Store(false)
+		return cfg.Endpoints
+	}
+
+	remote, err := s.remote.fetch(*cfg.RemoteEndpoints)
+	if err != nil {
+		s.logger.Printf("Error fetching remote endpoints from %s, keeping %d last known: %v",
+			cfg.RemoteEndpoints.URL, len(s.remote.lastGood), err)
+		remote = s.remote.lastGood
+	} else {
+		s.remote.lastGood = remote
+		s.remote.fetched.Store(true)
+	}
+
+	endpoints := append([]config.Endpoint(nil), cfg.Endpoints...)
+	static := make(map[string]bool, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		static[endpoint.URL] = true
+	}
+	for _, endpoint := range remote {
+		if !static[endpoint.URL] {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// ConfiguredURLs returns the URL of every configured endpoint, enabled or
+// not, including the remote ones. It reports false while remote endpoints
+// are configured but have not been fetched yet, as the list is then
+// incomplete.
+func (s *Service) ConfiguredURLs() ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config.Monitor.RemoteEndpoints != nil && !s.remote.fetched.Load() {
+		return nil, false
+	}
+	urls := make([]string, len(s.config.Monitor.Endpoints))
+	for i, endpoint := range s.config.Monitor.Endpoints {
+		urls[i] = endpoint.URL
+	}
+	return urls, true
+}
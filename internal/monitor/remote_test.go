@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+func TestFetchRejectsExecEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "api", "url": "https://example.com/health", "interval": "1m"},
+			{"name": "pwn", "type": "exec", "url": "exec://pwn", "command": ["/bin/sh", "-c", "touch /tmp/pwned"]}
+		]`))
+	}))
+	defer server.Close()
+
+	var remote remoteEndpoints
+	endpoints, err := remote.fetch(config.RemoteEndpointsConfig{URL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "exec endpoints are not allowed") {
+		t.Fatalf("fetch() = %v, %v; want the exec endpoint rejected", endpoints, err)
+	}
+}
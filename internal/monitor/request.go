@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// newRequest builds an endpoint's check request. A body without an explicit
+// Content-Type header is sent as application/json when it is valid JSON and
+// with a sniffed type otherwise.
+func newRequest(ctx context.Context, endpoint config.Endpoint) (*http.Request, error) {
+	method := endpoint.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.URL, strings.NewReader(endpoint.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range endpoint.Headers {
+		req.Header.Set(name, value)
+	}
+	if endpoint.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", defaultContentType(endpoint.Body))
+	}
+	if endpoint.Body != "" && endpoint.ExpectContinueTimeout > 0 {
+		req.Header.Set("Expect", "100-continue")
+	}
+	if endpoint.Body != "" && endpoint.ChunkedBody {
+		// An unknown length makes HTTP/1.1 send the body chunked
+		req.ContentLength = -1
+	}
+	if endpoint.DecodeBody && req.Header.Get("Accept-Encoding") == "" {
+		// Asking explicitly stops the transport from decompressing gzip
+		// itself, so the encoded size can be measured
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	return req, nil
+}
+
+// mergeHeaders combines global headers with an endpoint's own, which win
+// when both set the same header, regardless of case. Names are returned in
+// canonical form.
+func mergeHeaders(global, own map[string]string) map[string]string {
+	merged := make(map[string]string, len(global)+len(own))
+	for name, value := range global {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	for name, value := range own {
+		merged[http.CanonicalHeaderKey(name)] = value
+	}
+	return merged
+}
+
+// defaultContentType picks the Content-Type for a request body
+func defaultContentType(body string) string {
+	if json.Valid([]byte(body)) {
+		return "application/json"
+	}
+	return http.DetectContentType([]byte(body))
+}
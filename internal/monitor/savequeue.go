@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// saveQueue hands checks to a single writer goroutine, so checks do not wait
+// for storage unless the queue is full
+type saveQueue struct {
+	storage Storage
+	metrics *Metrics
+	logger  *log.Logger
+	depth   int
+	policy  string
+
+	mu      sync.Mutex
+	changed *sync.Cond // signalled when checks are queued or taken
+	items   []HealthCheck
+	stopped bool
+	done    chan struct{}
+}
+
+// newSaveQueue returns the save queue configured for the database, or nil
+// when checks are saved synchronously
+func newSaveQueue(cfg config.DatabaseConfig, storage Storage, metrics *Metrics, logger *log.Logger) *saveQueue {
+	if cfg.SaveQueue == 0 {
+		return nil
+	}
+	q := &saveQueue{
+		storage: storage,
+		metrics: metrics,
+		logger:  logger,
+		depth:   cfg.SaveQueue,
+		policy:  cfg.SaveQueuePolicy,
+		done:    make(chan struct{}),
+	}
+	q.changed = sync.NewCond(&q.mu)
+	return q
+}
+
+// start launches the writer
+func (q *saveQueue) start() {
+	if q == nil {
+		return
+	}
+	go q.write()
+}
+
+// save queues a check for the writer, applying the policy when the queue is
+// full. It saves the check itself once the writer has stopped.
+func (q *saveQueue) save(check HealthCheck) error {
+	q.mu.Lock()
+	for len(q.items) >= q.depth && !q.stopped {
+		if q.policy == config.SaveQueueDropSuccesses && q.dropSuccess(check) {
+			break
+		}
+		q.changed.Wait()
+	}
+	if q.stopped {
+		q.mu.Unlock()
+		return q.storage.SaveCheck(check)
+	}
+	if len(q.items) < q.depth {
+		q.items = append(q.items, check)
+		q.changed.Broadcast()
+	}
+	q.mu.Unlock()
+	return nil
+}
+
+// dropSuccess makes room for check by dropping the oldest queued UP check,
+// or check itself when it is UP and none is queued. It reports false when
+// only failures are queued and check is not UP, which then waits. The caller
+// holds q.mu.
+func (q *saveQueue) dropSuccess(check HealthCheck) bool {
+	dropped := check
+	i := len(q.items)
+	for j, queued := range q.items {
+		if queued.Status == StatusUp {
+			dropped, i = queued, j
+			break
+		}
+	}
+	if i == len(q.items) && check.Status != StatusUp {
+		return false
+	}
+	if i < len(q.items) {
+		q.items = append(q.items[:i], q.items[i+1:]...)
+	}
+	q.logger.Printf("Save queue is full, dropping the %s check of %s from %s",
+		dropped.Status, dropped.URL, dropped.Timestamp.Format("15:04:05.000"))
+	q.metrics.observeDroppedSave(dropped.Name, dropped.URL)
+	return true
+}
+
+// write saves queued checks in order until the queue is stopped and drained
+func (q *saveQueue) write() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for len(q.items) == 0 && !q.stopped {
+			q.changed.Wait()
+		}
+		if len(q.items) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		check := q.items[0]
+		q.items = q.items[1:]
+		q.changed.Broadcast()
+		q.mu.Unlock()
+
+		if err := q.metrics.timeSave(func() error { return q.storage.SaveCheck(check) }); err != nil {
+			q.logger.Printf("Error saving check for %s: %v", check.URL, err)
+		}
+	}
+}
+
+// shutdown saves the checks still queued, then stops the writer
+func (q *saveQueue) shutdown() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		if n := len(q.items); n > 0 {
+			q.logger.Printf("Saving %d queued checks", n)
+		}
+	}
+	q.changed.Broadcast()
+	q.mu.Unlock()
+	<-q.done
+}
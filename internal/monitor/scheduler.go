@@ -0,0 +1,207 @@
+package monitor
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultSchedulerWorkers is the number of checks the heap scheduler runs at
+// once when scheduler_workers is not set
+const defaultSchedulerWorkers = 16
+
+// scheduledCheck is an endpoint waiting in the heap scheduler for its next
+// check
+type scheduledCheck struct {
+	ctx     context.Context
+	monitor *EndpointMonitor
+	client  *http.Client
+	due     time.Time
+	index   int
+}
+
+// checkQueue is a min-heap of scheduled checks ordered by due time
+type checkQueue []*scheduledCheck
+
+func (q checkQueue) Len() int           { return len(q) }
+func (q checkQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q checkQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *checkQueue) Push(x any) {
+	item := x.(*scheduledCheck)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *checkQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return item
+}
+
+// heapScheduler runs every endpoint's checks from one timer goroutine and a
+// fixed pool of workers instead of a goroutine per endpoint. Checks fall due
+// on the same schedule as with per-endpoint tickers: interval after the
+// startup delay, then every interval, skipping ticks missed while a check was
+// still running.
+type heapScheduler struct {
+	service *Service
+	cancel  context.CancelFunc
+
+	mu    sync.Mutex
+	queue checkQueue
+	wake  chan struct{}
+	work  chan *scheduledCheck
+}
+
+// startScheduler launches the heap scheduler's goroutines
+func (s *Service) startScheduler(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = defaultSchedulerWorkers
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.scheduler = &heapScheduler{
+		service: s,
+		cancel:  cancel,
+		wake:    make(chan struct{}, 1),
+		work:    make(chan *scheduledCheck),
+	}
+
+	s.shutdownWg.Add(1 + workers)
+	go s.scheduler.dispatch(ctx)
+	for i := 0; i < workers; i++ {
+		go s.scheduler.worker(ctx)
+	}
+}
+
+// add schedules an endpoint's next check
+func (h *heapScheduler) add(item *scheduledCheck) {
+	h.mu.Lock()
+	heap.Push(&h.queue, item)
+	h.mu.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due removes and returns the checks due by now, dropping endpoints that have
+// been stopped, and returns how long until the next one falls due
+func (h *heapScheduler) due(now time.Time) ([]*scheduledCheck, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var ready []*scheduledCheck
+	for h.queue.Len() > 0 {
+		next := h.queue[0]
+		if next.ctx.Err() != nil {
+			heap.Pop(&h.queue)
+			h.service.logger.Printf("Stopping monitoring for endpoint: %s", next.monitor.endpoint.URL)
+			continue
+		}
+		if next.due.After(now) {
+			return ready, next.due.Sub(now)
+		}
+		ready = append(ready, heap.Pop(&h.queue).(*scheduledCheck))
+	}
+	return ready, -1
+}
+
+// dispatch hands checks to the workers as they fall due
+func (h *heapScheduler) dispatch(ctx context.Context) {
+	defer h.service.shutdownWg.Done()
+
+	for {
+		ready, wait := h.due(h.service.clock.Now())
+		for _, item := range ready {
+			select {
+			case h.work <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(ready) > 0 {
+			continue
+		}
+
+		var timer Timer
+		if wait >= 0 {
+			timer = h.service.clock.AfterFunc(wait, func() {
+				select {
+				case h.wake <- struct{}{}:
+				default:
+				}
+			})
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-h.wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// worker runs due checks and schedules each endpoint's next one
+func (h *heapScheduler) worker(ctx context.Context) {
+	defer h.service.shutdownWg.Done()
+
+	for {
+		var item *scheduledCheck
+		select {
+		case <-ctx.Done():
+			return
+		case item = <-h.work:
+		}
+		h.run(item)
+	}
+}
+
+// run performs a due check and schedules the endpoint's next one. A panic
+// restarts the endpoint's monitoring, which schedules it anew.
+func (h *heapScheduler) run(item *scheduledCheck) {
+	s := h.service
+	defer s.recoverMonitor(item.ctx, item.monitor)
+
+	start := s.clock.Now()
+	item.monitor.setNextCheck(item.due.Add(item.monitor.endpoint.Interval.ToDuration()))
+	endpoint := s.checkedEndpoint(item.monitor.endpoint)
+	check := s.performHealthCheck(item.ctx, checkClient(item.client, endpoint), endpoint)
+	if item.ctx.Err() != nil {
+		s.logger.Printf("Stopping monitoring for endpoint: %s", item.monitor.endpoint.URL)
+		return
+	}
+	s.recordCheck(item.monitor, check)
+
+	interval := item.monitor.endpoint.Interval.ToDuration()
+	finished := s.clock.Now()
+	if backoff := s.updateBreaker(item.monitor, check); backoff > 0 {
+		item.due = finished.Add(backoff)
+	} else {
+		s.countSkipped(item.monitor, finished.Sub(start), interval)
+		// The next check is the first tick after this one finished
+		item.due = nextTick(item.due, interval, finished)
+	}
+	item.monitor.setNextCheck(item.due)
+	h.add(item)
+}
+
+// stop ends the scheduler's goroutines
+func (h *heapScheduler) stop() {
+	h.cancel()
+}
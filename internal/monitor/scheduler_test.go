@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+func TestSchedulerDueOrder(t *testing.T) {
+	h := &heapScheduler{service: &Service{logger: log.New(io.Discard, "", 0)}}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	stopped, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, item := range []struct {
+		url string
+		due time.Duration
+		ctx context.Context
+	}{
+		{"c", 3 * time.Minute, context.Background()},
+		{"a", time.Minute, context.Background()},
+		{"stopped", 30 * time.Second, stopped},
+		{"d", 4 * time.Minute, context.Background()},
+		{"b", 2 * time.Minute, context.Background()},
+	} {
+		h.add(&scheduledCheck{
+			ctx:     item.ctx,
+			monitor: &EndpointMonitor{endpoint: config.Endpoint{URL: item.url}},
+			due:     start.Add(item.due),
+		})
+	}
+
+	ready, wait := h.due(start.Add(150 * time.Second))
+	var urls []string
+	for _, item := range ready {
+		urls = append(urls, item.monitor.endpoint.URL)
+	}
+	if fmt.Sprint(urls) != "[a b]" {
+		t.Errorf("due checks = %v, want [a b]", urls)
+	}
+	if wait != 30*time.Second {
+		t.Errorf("wait = %s, want 30s", wait)
+	}
+
+	ready, wait = h.due(start.Add(time.Hour))
+	if len(ready) != 2 || ready[0].monitor.endpoint.URL != "c" || ready[1].monitor.endpoint.URL != "d" {
+		t.Errorf("due checks = %d, want c then d", len(ready))
+	}
+	if wait != -1 {
+		t.Errorf("wait = %s with nothing queued, want -1", wait)
+	}
+}
+
+func TestRescheduleAfterIntervalChange(t *testing.T) {
+	for _, scheduler := range []string{config.SchedulerGoroutines, config.SchedulerHeap} {
+		t.Run(scheduler, func(t *testing.T) {
+			var status atomic.Int32
+			status.Store(http.StatusOK)
+			server := statusServer(t, &status)
+			endpoint := config.Endpoint{
+				Name:     "api",
+				URL:      server.URL,
+				Enabled:  true,
+				Interval: config.Duration(time.Minute),
+			}
+			cfg := testConfig(endpoint)
+			cfg.Monitor.Scheduler = scheduler
+			reloaded := cfg
+			endpoint.Interval = config.Duration(3 * time.Minute)
+			reloaded.Monitor.Endpoints = []config.Endpoint{endpoint}
+			service, clock, rec := startService(t, cfg, func() (*config.Config, error) {
+				return &reloaded, nil
+			})
+			start := clock.Now()
+			heap := scheduler == config.SchedulerHeap
+
+			clock.waitForTimer(t, time.Minute)
+			clock.Advance(time.Minute)
+			rec.nextCheck(t)
+			if heap {
+				clock.waitForTimer(t, time.Minute)
+			}
+
+			// Halfway to the next check the interval grows to three
+			// minutes, which applies from the check already due
+			clock.Advance(30 * time.Second)
+			if err := service.reloadConfig(); err != nil {
+				t.Fatalf("reloadConfig() = %v", err)
+			}
+			clock.waitForTimer(t, 30*time.Second)
+			clock.Advance(30 * time.Second)
+			if check := rec.nextCheck(t); !check.Timestamp.Equal(start.Add(2 * time.Minute)) {
+				t.Fatalf("check at %s after the reload, want %s", check.Timestamp, start.Add(2*time.Minute))
+			}
+			if heap {
+				clock.waitForTimer(t, 3*time.Minute)
+			}
+			for range 3 {
+				clock.Advance(time.Minute)
+			}
+			if check := rec.nextCheck(t); !check.Timestamp.Equal(start.Add(5 * time.Minute)) {
+				t.Fatalf("check at %s, want %s on the new interval", check.Timestamp, start.Add(5*time.Minute))
+			}
+			if n := len(rec.checks); n != 0 {
+				t.Fatalf("%d more checks saved, want none", n)
+			}
+		})
+	}
+}
+
+// BenchmarkScheduler runs rounds of checks of many endpoints with each
+// scheduler, reporting the goroutines each needs
+func BenchmarkScheduler(b *testing.B) {
+	const endpoints = 200
+	for _, scheduler := range []string{config.SchedulerGoroutines, config.SchedulerHeap} {
+		b.Run(scheduler, func(b *testing.B) {
+			var status atomic.Int32
+			status.Store(http.StatusOK)
+			server := statusServer(b, &status)
+			var list []config.Endpoint
+			for i := range endpoints {
+				list = append(list, config.Endpoint{
+					Name:     fmt.Sprintf("endpoint %d", i),
+					URL:      fmt.Sprintf("%s/%d", server.URL, i),
+					Enabled:  true,
+					Interval: config.Duration(time.Minute),
+				})
+			}
+			cfg := testConfig(list...)
+			cfg.Monitor.Scheduler = scheduler
+			goroutines := runtime.NumGoroutine()
+			_, clock, rec := startService(b, cfg, nil)
+			heap := scheduler == config.SchedulerHeap
+
+			// Every endpoint waits for its first check on its own timer
+			// unless the heap schedules them all
+			if !heap {
+				clock.waitForTimers(b, endpoints)
+			}
+			started := runtime.NumGoroutine() - goroutines
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if heap {
+					clock.waitForTimer(b, time.Minute)
+				}
+				clock.Advance(time.Minute)
+				for range endpoints {
+					rec.nextCheck(b)
+				}
+			}
+			b.ReportMetric(float64(started), "goroutines")
+		})
+	}
+}
@@ -1,39 +1,99 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/will-wright-eng/monitord/internal/config"
 )
 
+// Default startup ramp, used when startup_ramp is not configured
+const (
+	rampPerEndpoint = 20 * time.Millisecond
+	maxDefaultRamp  = 30 * time.Second
+)
+
 // NewService creates a new monitor service
-func NewService(storage Storage, logger *log.Logger, cfg config.MonitorConfig, reloadFn func() (*config.Config, error)) *Service {
-	return &Service{
+func NewService(storage Storage, notifier Notifier, metrics *Metrics, logger *log.Logger, cfg config.Config, reloadFn func() (*config.Config, error)) *Service {
+	s := &Service{
 		storage:   storage,
+		notifier:  notifier,
+		metrics:   metrics,
 		logger:    logger,
 		config:    cfg,
 		endpoints: make(map[string]*EndpointMonitor),
 		onReload:  reloadFn,
+		clock:     realClock{},
+		callbacks: newCheckCallbacks(),
+		overrides: overrides{entries: make(map[string]EndpointOverride)},
+		acks:      acknowledgements{entries: make(map[string]Acknowledgement)},
+		relaxed:   relaxations{until: make(map[string]time.Time), timers: make(map[string]Timer)},
 	}
+	s.saves = newSaveQueue(cfg.Database, storage, metrics, logger)
+	s.dns = newDNSCache(func() time.Time { return s.clock.Now() })
+	return s
 }
 
-// Start begins monitoring all configured endpoints
+// Start begins monitoring all configured endpoints. Every enabled endpoint is
+// attempted; endpoints that fail to start are reported together in a
+// *StartError while the others keep running, so one bad endpoint does not
+// stop the rest from being monitored.
 func (s *Service) Start(ctx context.Context) error {
+	endpoints := applyDefaults(s.config.Monitor, s.resolveEndpoints(s.config.Monitor))
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.config.Monitor.Endpoints = endpoints
+	s.limits.configure(s.config.Monitor.HostRateLimits)
+	s.geo.configure(s.config.Monitor.GeoIPDatabases, s.logger)
+	s.trust.configure(s.config.Monitor.CABundle, endpoints, s.logger)
+	s.startedAt = s.clock.Now()
+	if grace := s.config.Monitor.StartupGrace.ToDuration(); grace > 0 {
+		s.graceTimer = s.clock.AfterFunc(grace, s.endStartupGrace)
+	}
+	s.callbacks.start(s.logger)
+	s.saves.start()
+	if s.config.Monitor.Scheduler == config.SchedulerHeap {
+		s.startScheduler(ctx, s.config.Monitor.SchedulerWorkers)
+	}
 
-	// Start monitoring each enabled endpoint
-	for _, endpoint := range s.config.Endpoints {
-		if !endpoint.Enabled {
-			continue
+	s.pruneOverrides(s.config.Monitor.Endpoints)
+	var enabled []config.Endpoint
+	for _, endpoint := range s.config.Monitor.Endpoints {
+		on, source := s.enabled(endpoint)
+		if source == EnabledByOperator {
+			s.logger.Printf("Operator override keeps %s %s", endpoint.URL, enabledLabel(on))
+		}
+		if on {
+			enabled = append(enabled, endpoint)
 		}
+	}
 
-		if err := s.startEndpoint(ctx, endpoint); err != nil {
-			return fmt.Errorf("failed to start endpoint %s: %w", endpoint.URL, err)
+	// Start monitoring each enabled endpoint, staggered over the ramp
+	ramp := startupRamp(s.config.Monitor, len(enabled))
+	if ramp > 0 {
+		s.logger.Printf("Starting %d endpoints over %s", len(enabled), ramp)
+	}
+	var failed startFailures
+	for i, endpoint := range enabled {
+		if _, exists := s.endpoints[endpoint.URL]; exists {
+			failed.add(endpoint.URL, errors.New("duplicate url"))
+			continue
+		}
+		delay := ramp * time.Duration(i) / time.Duration(len(enabled))
+		if err := s.startEndpoint(ctx, endpoint, delay+endpoint.Interval.ToDuration(), nil); err != nil {
+			failed.add(endpoint.URL, err)
 		}
 	}
 
@@ -41,103 +101,528 @@ func (s *Service) Start(ctx context.Context) error {
 	s.shutdownWg.Add(1)
 	go s.watchConfig(ctx)
 
-	return nil
+	// Start the watchdog for endpoints that stop producing checks
+	s.shutdownWg.Add(1)
+	go s.watchStale(ctx, s.config.Monitor.WatchdogInterval.ToDuration())
+
+	// Ping the dead man's switch while monitoring is healthy
+	s.shutdownWg.Add(1)
+	go s.sendHeartbeats(ctx)
+
+	// Follow the maintenance calendar, if any
+	s.shutdownWg.Add(1)
+	go s.watchMaintenance(ctx)
+
+	started := len(enabled) - len(failed.urls)
+	message := fmt.Sprintf("started monitoring %d endpoints", started)
+	if len(failed.urls) > 0 {
+		message += fmt.Sprintf(", %d failed to start", len(failed.urls))
+	}
+	s.recordEvent(EventStartup, message)
+	return failed.err()
+}
+
+// applyDefaults fills endpoint settings left empty with the monitor-wide
+// values, returning a new slice
+func applyDefaults(cfg config.MonitorConfig, endpoints []config.Endpoint) []config.Endpoint {
+	resolved := make([]config.Endpoint, len(endpoints))
+	for i, endpoint := range endpoints {
+		if endpoint.MinTLSVersion == "" {
+			endpoint.MinTLSVersion = cfg.MinTLSVersion
+		}
+		if endpoint.DNSCacheTTL == 0 {
+			endpoint.DNSCacheTTL = cfg.DNSCacheTTL
+		}
+		if endpoint.DisableDNSCache {
+			endpoint.DNSCacheTTL = 0
+		}
+		if endpoint.CircuitBreaker == nil {
+			endpoint.CircuitBreaker = cfg.CircuitBreaker
+		}
+		if endpoint.ResponseBaseline == nil && endpoint.SendsRequests() {
+			endpoint.ResponseBaseline = cfg.ResponseBaseline
+		}
+		if len(cfg.GlobalHeaders) > 0 && endpoint.SendsRequests() {
+			endpoint.Headers = mergeHeaders(cfg.GlobalHeaders, endpoint.Headers)
+			if pre := endpoint.PreRequest; pre != nil {
+				preCopy := *pre
+				preCopy.Headers = mergeHeaders(cfg.GlobalHeaders, pre.Headers)
+				endpoint.PreRequest = &preCopy
+			}
+		}
+		resolved[i] = endpoint
+	}
+	return resolved
+}
+
+// startupRamp returns the window over which endpoints are started
+func startupRamp(cfg config.MonitorConfig, endpoints int) time.Duration {
+	if ramp := cfg.StartupRamp.ToDuration(); ramp > 0 {
+		return ramp
+	}
+	ramp := time.Duration(endpoints) * rampPerEndpoint
+	if ramp > maxDefaultRamp {
+		ramp = maxDefaultRamp
+	}
+	return ramp
 }
 
-// startEndpoint begins monitoring a single endpoint
-func (s *Service) startEndpoint(ctx context.Context, endpoint config.Endpoint) error {
+// startEndpoint begins monitoring a single endpoint, with its first check
+// after the given delay and then every interval. When previous is set, the
+// new monitor takes over its runtime state.
+func (s *Service) startEndpoint(ctx context.Context, endpoint config.Endpoint, first time.Duration, previous *EndpointMonitor) error {
+	// Configs loaded from disk are already validated, but a service embedded
+	// as a library may be given any endpoints
+	if err := config.ValidateEndpoints([]config.Endpoint{endpoint}); err != nil {
+		return err
+	}
+	endpoint.Prepare()
+
 	endpointCtx, cancel := context.WithCancel(ctx)
 	monitor := &EndpointMonitor{
-		endpoint: endpoint,
-		cancel:   cancel,
+		endpoint:  endpoint,
+		cancel:    cancel,
+		nextCheck: s.clock.Now().Add(first),
+	}
+	if previous != nil {
+		s.inheritState(monitor, previous)
 	}
 	s.endpoints[endpoint.URL] = monitor
 
+	if endpoint.Type == config.EndpointTypePassive {
+		monitor.reported = make(chan struct{}, 1)
+		s.shutdownWg.Add(1)
+		go s.watchPassive(endpointCtx, monitor)
+		return nil
+	}
+
+	if s.scheduler != nil {
+		s.scheduler.add(&scheduledCheck{
+			ctx:     endpointCtx,
+			monitor: monitor,
+			client:  newClient(endpoint, s.dns, s.trust.pool(endpoint.CAFile)),
+			due:     s.clock.Now().Add(first),
+		})
+		return nil
+	}
+
 	s.shutdownWg.Add(1)
-	go s.monitorEndpoint(endpointCtx, monitor)
+	go s.monitorEndpoint(endpointCtx, monitor, first)
 
 	return nil
 }
 
-// monitorEndpoint performs the actual health checks for an endpoint
-func (s *Service) monitorEndpoint(ctx context.Context, monitor *EndpointMonitor) {
+// monitorEndpoint performs the actual health checks for an endpoint, the
+// first after the given delay and then every interval
+func (s *Service) monitorEndpoint(ctx context.Context, monitor *EndpointMonitor, first time.Duration) {
 	defer s.shutdownWg.Done()
+	defer s.recoverMonitor(ctx, monitor)
+
+	if first > 0 {
+		ready := make(chan struct{})
+		timer := s.clock.AfterFunc(first, func() { close(ready) })
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-ready:
+		}
+	}
 
-	ticker := time.NewTicker(monitor.endpoint.Interval.ToDuration())
+	// The ticker starts with the first check, so later checks keep its
+	// schedule
+	interval := monitor.endpoint.Interval.ToDuration()
+	ticker := s.clock.NewTicker(interval)
 	defer ticker.Stop()
 
-	client := &http.Client{
-		Timeout: monitor.endpoint.Timeout.ToDuration(),
-	}
+	client := newClient(monitor.endpoint, s.dns, s.trust.pool(monitor.endpoint.CAFile))
+
+	var backoff time.Duration
+	for due := true; ; due = false {
+		if backoff > 0 {
+			// An open circuit breaker delays the next check past the
+			// ticks, which resume once it closes
+			ready := make(chan struct{})
+			timer := s.clock.AfterFunc(backoff, func() { close(ready) })
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				s.logger.Printf("Stopping monitoring for endpoint: %s", monitor.endpoint.URL)
+				return
+			case <-ready:
+			}
+			select {
+			case <-ticker.Chan():
+			default:
+			}
+		} else if !due {
+			select {
+			case <-ctx.Done():
+				s.logger.Printf("Stopping monitoring for endpoint: %s", monitor.endpoint.URL)
+				return
+			case <-ticker.Chan():
+			}
+		}
 
-	for {
-		select {
-		case <-ctx.Done():
+		start := s.clock.Now()
+		monitor.setNextCheck(start.Add(interval))
+		endpoint := s.checkedEndpoint(monitor.endpoint)
+		check := s.performHealthCheck(ctx, checkClient(client, endpoint), endpoint)
+		if ctx.Err() != nil {
+			// Checks interrupted by shutdown or reload say nothing
+			// about the endpoint
 			s.logger.Printf("Stopping monitoring for endpoint: %s", monitor.endpoint.URL)
 			return
-		case <-ticker.C:
-			check := s.performHealthCheck(client, monitor.endpoint)
-			if err := s.storage.SaveCheck(check); err != nil {
-				s.logger.Printf("Error saving check for %s: %v", monitor.endpoint.URL, err)
-			}
-			monitor.lastCheck = check.Timestamp
 		}
+		s.recordCheck(monitor, check)
+		if backoff = s.updateBreaker(monitor, check); backoff > 0 {
+			monitor.setNextCheck(s.clock.Now().Add(backoff))
+		} else if elapsed := s.since(start); elapsed >= interval {
+			s.skipOverrun(monitor, ticker, elapsed, interval)
+			monitor.setNextCheck(nextTick(start, interval, s.clock.Now()))
+		}
+	}
+}
+
+// recordCheck saves a check result, or queues it when save_queue is set, and
+// feeds it to metrics and alerting
+func (s *Service) recordCheck(monitor *EndpointMonitor, check HealthCheck) {
+	save := func(check HealthCheck) error {
+		return s.metrics.timeSave(func() error { return s.storage.SaveCheck(check) })
+	}
+	if s.saves != nil {
+		save = s.saves.save
+	}
+	if err := save(check); err != nil {
+		s.logger.Printf("Error saving check for %s: %v", monitor.endpoint.URL, err)
+	}
+	s.metrics.observeCheck(check)
+	s.events.publish(check)
+	s.callbacks.dispatch(check, s.logger)
+	s.evaluateCheck(monitor, check)
+}
+
+// skipOverrun drops the ticks that fell due while a check was still running,
+// so a slow endpoint is checked on its next scheduled tick instead of
+// immediately again
+func (s *Service) skipOverrun(monitor *EndpointMonitor, ticker Ticker, elapsed, interval time.Duration) {
+	if elapsed < interval {
+		return
+	}
+	select {
+	case <-ticker.Chan():
+	default:
+	}
+	s.countSkipped(monitor, elapsed, interval)
+}
+
+// nextTick returns the first tick of a schedule that ticked at prev and every
+// interval since that falls after now
+func nextTick(prev time.Time, interval time.Duration, now time.Time) time.Time {
+	next := prev.Add(interval)
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
+
+// setNextCheck records when the monitor's next check is due
+func (m *EndpointMonitor) setNextCheck(t time.Time) {
+	m.mu.Lock()
+	m.nextCheck = t
+	m.mu.Unlock()
+}
+
+// nextCheckTime returns when the monitor's next check is due
+func (m *EndpointMonitor) nextCheckTime() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.nextCheck
+}
+
+// countSkipped records the scheduled checks that fell due while a check that
+// took elapsed was running
+func (s *Service) countSkipped(monitor *EndpointMonitor, elapsed, interval time.Duration) {
+	skipped := int(elapsed / interval)
+	if skipped == 0 {
+		return
 	}
+	monitor.mu.Lock()
+	monitor.skipped += skipped
+	monitor.mu.Unlock()
+	s.metrics.observeSkipped(monitor.endpoint.Name, monitor.endpoint.URL, skipped)
+	s.logger.Printf("Check for %s took %s, longer than its %s interval; skipped %d scheduled checks",
+		monitor.endpoint.URL, elapsed.Round(time.Millisecond), interval, skipped)
 }
 
 // performHealthCheck executes a single health check
-func (s *Service) performHealthCheck(client *http.Client, endpoint config.Endpoint) HealthCheck {
-	s.logger.Printf("Starting health check for endpoint: %s", endpoint.URL)
-	start := time.Now()
+func (s *Service) performHealthCheck(ctx context.Context, client *http.Client, endpoint config.Endpoint) HealthCheck {
+	if endpoint.IPVersion == config.IPVersionBoth && endpoint.Type != config.EndpointTypeExec {
+		return s.performDualStackCheck(ctx, endpoint)
+	}
+	if endpoint.Type != config.EndpointTypeExec {
+		// The wait for a rate-limited host is not part of the response time
+		if err := s.waitForHost(ctx, endpoint); err != nil {
+			return HealthCheck{Name: endpoint.Name, URL: endpoint.URL, Status: StatusError, Error: err.Error()}
+		}
+	}
+	if s.slowCheckThreshold() == 0 {
+		s.logger.Printf("Starting health check for endpoint: %s", endpoint.URL)
+	}
+	start := s.clock.Now()
 	check := HealthCheck{
 		Name:      endpoint.Name,
 		URL:       endpoint.URL,
-
+		Probe:     s.probeName(),
 		Tags:      endpoint.Tags,
 		Timestamp: start,
 	}
+	if endpoint.Type == config.EndpointTypeExec {
+		return s.performExecCheck(ctx, check, endpoint)
+	}
+	if endpoint.PreRequest != nil {
+		if status, reason, err := performPreRequest(ctx, client, *endpoint.PreRequest); status != "" {
+			check.Status, check.Reason = status, reason
+			if err != nil {
+				check.Error = err.Error()
+			}
+			s.logCheck(check)
+			return check
+		}
+		// Response time covers only the check request itself
+		start = s.clock.Now()
+	}
+	if endpoint.Type == config.EndpointTypeWebSocket {
+		return s.performWebSocketCheck(ctx, client, check, endpoint, start)
+	}
+	if len(endpoint.Steps) > 0 {
+		return s.performTransactionCheck(ctx, client, check, endpoint, start)
+	}
 
-	resp, err := client.Get(endpoint.URL)
+	// The connection's remote address shows which address family was used
+	var remoteAddr net.Addr
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			remoteAddr = info.Conn.RemoteAddr()
+		},
+	}
+	var timing *timingTrace
+	if verboseOnFailure(endpoint) {
+		timing = newTimingTrace(s.clock)
+		timing.hook(trace)
+	}
+	var debug *debugTrace
+	if endpoint.DebugTrace {
+		debug = newDebugTrace(s.clock)
+		ctx = httptrace.WithClientTrace(ctx, debug.hooks())
+	}
+	var upload *uploadTrace
+	if tracesUpload(endpoint) {
+		upload = &uploadTrace{}
+		upload.hook(trace)
+	}
+	ctx, dnsLookup := withDNSLookup(ctx)
+	ctx, redirects := withRedirectTrace(ctx, s.clock, start)
+	var resp *http.Response
+	req, err := newRequest(httptrace.WithClientTrace(ctx, trace), endpoint)
+	if err == nil {
+		if upload != nil {
+			upload.wrap(req)
+		}
+		resp, err = client.Do(req)
+	}
+	check.IPVersion = addressFamily(remoteAddr)
+	check.Redirects = redirects.chain()
+	s.recordRemote(&check, remoteAddr)
+	if stale := dnsLookup.stale(); stale != "" {
+		check.Detail = stale
+		s.logger.Printf("WARN %s: %s", endpoint.URL, stale)
+	}
+	if upload != nil && req != nil {
+		check.Detail = joinDetail(check.Detail, upload.describe(endpoint, req, resp))
+	}
 	if err != nil {
-		s.logger.Printf("Error checking endpoint %s: %v", endpoint.URL, err)
-		check.Status = "ERROR"
-		check.Error = err.Error()
-		return check
+		check.Error = describeError(endpoint, err)
+	} else {
+		defer resp.Body.Close()
+		check.StatusCode = resp.StatusCode
+		check.Protocol = resp.Proto
+		if resp.TLS != nil {
+			check.TLSVersion = tls.VersionName(resp.TLS.Version)
+		}
+		check.setResponseTime(s.since(start))
+	}
+
+	check.Status, check.Reason = classify(endpoint, resp, err)
+	applyMaxRedirects(&check, endpoint)
+	if endpoint.CaptureHeadersOnFailure && resp != nil && check.Status != StatusUp {
+		check.Headers = redactHeaders(resp.Header)
+	}
+	// Health documents are read whole, so the body is kept for capture
+	healthBody := resp != nil && !endpoint.ExpectInaccessible && isHealthDocument(resp)
+	var body []byte
+	if endpoint.DecodeBody && resp != nil {
+		decoded, err := decodeBody(resp, endpoint.DecodedBodyLimit)
+		check.BodySize, check.DecodedBodySize = decoded.raw, decoded.decoded
+		if err != nil && check.Status == StatusUp {
+			check.Status, check.Reason = StatusDegraded, err.Error()
+		}
+		body = decoded.data
+	} else if healthBody {
+		body = readHealthDocument(resp.Body)
+	}
+	if healthBody {
+		applyHealthDocument(&check, body)
+	}
+	if body != nil {
+		if endpoint.CaptureBody {
+			check.body = captureBody(check, bytes.NewReader(body), endpoint.CaptureBodyLimit)
+		}
+		if timing != nil && check.Status != StatusUp {
+			check.BodySnippet = bodySnippet(bytes.NewReader(body))
+		}
+	} else if endpoint.CaptureBody && resp != nil {
+		check.body = captureBody(check, resp.Body, endpoint.CaptureBodyLimit)
+		if timing != nil && check.Status != StatusUp {
+			check.BodySnippet = bodySnippet(strings.NewReader(check.body.Body))
+		}
+	} else if timing != nil && resp != nil && check.Status != StatusUp {
+		check.BodySnippet = bodySnippet(resp.Body)
+	}
+	if timing != nil && check.Status != StatusUp {
+		check.Timing = timing.result()
+		if resp != nil {
+			check.Headers = redactHeaders(resp.Header)
+		}
 	}
-	defer resp.Body.Close()
+	s.applyBaseline(&check, endpoint)
 
-	duration := time.Since(start).Milliseconds()
-	check.StatusCode = resp.StatusCode
-	check.ResponseTime = duration
+	s.logCheck(check)
+	if debug != nil && check.Status != StatusUp {
+		s.logger.Printf("DEBUG trace of %s check of %s:\n%s", check.Status, endpoint.URL, debug.dump(req, resp, err))
+	}
+	return check
+}
+
+// probeName returns the configured name of this monitord instance
+func (s *Service) probeName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Monitor.ProbeName
+}
 
-	if resp.StatusCode == http.StatusOK {
-		check.Status = "UP"
+// slowCheckThreshold returns the response time above which successful checks
+// are logged, or 0 when every check is logged
+func (s *Service) slowCheckThreshold() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Logging.SlowCheckThreshold.ToDuration()
+}
+
+// logCheck records the outcome of a health check. With a slow check
+// threshold, successful checks are only logged when slower than it.
+func (s *Service) logCheck(check HealthCheck) {
+	threshold := s.slowCheckThreshold()
+	switch {
+	case check.Status == StatusUp && threshold > 0:
+		if responseTime := time.Duration(check.ResponseTime) * time.Millisecond; responseTime > threshold {
+			s.logger.Printf("WARN slow health check for %s - Response time: %dms, threshold %s",
+				check.URL, check.ResponseTime, threshold)
+		}
+	case check.Status == StatusUp:
 		s.logger.Printf("Health check successful for %s - Status: %s, Response time: %dms",
-			endpoint.URL, check.Status, duration)
-	} else {
-		check.Status = "DEGRADED"
-		s.logger.Printf("Health check degraded for %s - Status: %s, Status code: %d, Response time: %dms",
-			endpoint.URL, check.Status, resp.StatusCode, duration)
+			check.URL, check.Status, check.ResponseTime)
+	case check.StatusCode == 0 && check.Error != "":
+		s.logger.Printf("Error checking endpoint %s: %s", check.URL, check.Error)
+	default:
+		line := fmt.Sprintf("Health check degraded for %s - Status: %s, Status code: %d, Response time: %dms",
+			check.URL, check.Status, check.StatusCode, check.ResponseTime)
+		if check.Reason != "" {
+			line += ", Reason: " + check.Reason
+		}
+		s.logger.Print(line)
 	}
+}
 
-	return check
+// evaluateCheck feeds a check result through the alert state machine
+func (s *Service) evaluateCheck(monitor *EndpointMonitor, check HealthCheck) {
+	var transition *Transition
+	monitor.mu.Lock()
+	monitor.lastCheck = check.Timestamp
+	monitor.lastStatus = check.Status
+	monitor.lastStatusCode = check.StatusCode
+	monitor.lastResponseTime = check.ResponseTime
+	monitor.lastError = check.Error
+	monitor.lastReason = check.Reason
+	if check.body != nil {
+		monitor.lastBody = check.body
+	}
+	policy := PolicyFor(s.checkedEndpoint(monitor.endpoint))
+	monitor.state, transition = Evaluate(monitor.state, check, policy)
+	confirmed := monitor.state.Status
+	rateTransition := monitor.successRate.record(monitor.endpoint, check)
+	monitor.mu.Unlock()
+	s.metrics.observeConfirmed(check.Name, check.URL, confirmed)
+	// Canaries are reported through the connectivity notification only
+	canary := s.isCanary(monitor.endpoint.URL)
+
+	if rateTransition != nil {
+		s.logger.Printf("Success rate change for %s: %s -> %s, %s", monitor.endpoint.URL,
+			rateTransition.Previous, rateTransition.Current, rateTransition.Detail)
+		if !canary && !s.suppressedOffline(monitor.endpoint.URL, rateTransition.Current) &&
+			!s.suppressed(monitor.endpoint.URL) && !s.inMaintenance(monitor.endpoint) && s.notifier != nil {
+			s.notifier.Notify(*rateTransition)
+		}
+	}
+	if transition == nil {
+		return
+	}
+
+	s.logger.Printf("Status change for %s: %s -> %s", monitor.endpoint.URL,
+		statusLabel(transition.Previous), transition.Current)
+	if canary {
+		s.updateConnectivity()
+		return
+	}
+	transition.Escalated = s.updateEscalation(monitor, *transition, policy)
+	if !isDown(transition.Current, policy) {
+		s.endIncident(monitor.endpoint.URL)
+	}
+	if !policy.Notifies(*transition) {
+		s.logger.Printf("Not notifying for %s, alert_on_degraded is disabled", monitor.endpoint.URL)
+		return
+	}
+	s.alert(monitor, *transition)
+}
+
+// statusLabel renders a confirmed status for log output
+func statusLabel(status string) string {
+	if status == "" {
+		return "UNKNOWN"
+	}
+	return status
 }
 
 // watchConfig periodically checks for configuration updates
 func (s *Service) watchConfig(ctx context.Context) {
 	defer s.shutdownWg.Done()
 
-	ticker := time.NewTicker(s.config.ConfigCheck.ToDuration())
+	ticker := s.clock.NewTicker(s.config.Monitor.ConfigCheck.ToDuration())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			if err := s.reloadConfig(); err != nil {
+		case <-ticker.Chan():
+			err := s.reloadConfig()
+			s.metrics.observeReload(err)
+			if err != nil {
 				s.logger.Printf("Error reloading configuration: %v", err)
+				continue
 			}
+			// The canaries may have changed
+			s.updateConnectivity()
 		}
 	}
 }
@@ -150,26 +635,46 @@ func (s *Service) reloadConfig() error {
 	if err != nil {
 		return fmt.Errorf("failed to reload config: %w", err)
 	}
+	cfg.Monitor.Endpoints = applyDefaults(cfg.Monitor, s.resolveEndpoints(cfg.Monitor))
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Track new endpoints
+	// Endpoints whose CA certificates changed are restarted for new clients
+	trustChanged := s.trust.configure(cfg.Monitor.CABundle, cfg.Monitor.Endpoints, s.logger)
+
+	// Track new endpoints, and the changes for the event log
 	newEndpoints := make(map[string]*EndpointMonitor)
+	var added, updated, removed []string
 
-	// Process new or existing endpoints
+	// Process new or existing endpoints. Operator overrides apply until the
+	// config changes the endpoint's enabled setting.
+	s.pruneOverrides(cfg.Monitor.Endpoints)
 	for _, endpoint := range cfg.Monitor.Endpoints {
-		if !endpoint.Enabled {
+		if enabled, _ := s.enabled(endpoint); !enabled {
 			continue
 		}
 
 		// Check if endpoint already exists
 		if monitor, exists := s.endpoints[endpoint.URL]; exists {
 			// Update existing endpoint if configuration changed
-			if !endpointConfigEqual(monitor.endpoint, endpoint) {
+			rootsChanged := endpoint.SendsRequests() && trustChanged[endpoint.CAFile]
+			if !endpointConfigEqual(monitor.endpoint, endpoint) || rootsChanged {
 				s.logger.Printf("Updating configuration for endpoint: %s", endpoint.URL)
+				updated = append(updated, endpoint.URL)
 				monitor.cancel()
-				if err := s.startEndpoint(context.Background(), endpoint); err != nil {
+				// The restarted endpoint keeps its schedule, so a changed
+				// interval applies from the next check rather than
+				// checking early or twice
+				first := max(monitor.nextCheckTime().Sub(s.clock.Now()), 0)
+				// Alerting state carries over unless what is checked, or
+				// how its responses are judged, changed
+				previous := monitor
+				if checkChanged(monitor.endpoint, endpoint) {
+					s.logger.Printf("Check settings changed for %s, resetting its alert state", endpoint.URL)
+					previous = nil
+				}
+				if err := s.startEndpoint(context.Background(), endpoint, first, previous); err != nil {
 					return fmt.Errorf("failed to restart endpoint %s: %w", endpoint.URL, err)
 				}
 			}
@@ -177,7 +682,8 @@ func (s *Service) reloadConfig() error {
 		} else {
 			// Start monitoring new endpoint
 			s.logger.Printf("Adding new endpoint: %s", endpoint.URL)
-			if err := s.startEndpoint(context.Background(), endpoint); err != nil {
+			added = append(added, endpoint.URL)
+			if err := s.startEndpoint(context.Background(), endpoint, endpoint.Interval.ToDuration(), nil); err != nil {
 				return fmt.Errorf("failed to start new endpoint %s: %w", endpoint.URL, err)
 			}
 			newEndpoints[endpoint.URL] = s.endpoints[endpoint.URL]
@@ -188,17 +694,49 @@ func (s *Service) reloadConfig() error {
 	for url, monitor := range s.endpoints {
 		if _, exists := newEndpoints[url]; !exists {
 			s.logger.Printf("Removing endpoint: %s", url)
+			removed = append(removed, url)
 			monitor.cancel()
+			s.metrics.removeEndpoint(monitor.endpoint.Name, url)
 		}
 	}
 
-	// Update endpoints map
+	// Update endpoints map. Only the monitor settings are applied on reload,
+	// so the rest of the running config is kept as it was started.
 	s.endpoints = newEndpoints
-	s.config = cfg.Monitor
+	s.config.Monitor = cfg.Monitor
+	s.limits.configure(cfg.Monitor.HostRateLimits)
+	s.geo.configure(cfg.Monitor.GeoIPDatabases, s.logger)
 
+	if summary := reloadSummary(added, updated, removed); summary != "" {
+		s.recordEvent(EventReload, summary)
+	}
 	return nil
 }
 
+// reloadSummary describes the endpoint changes made by a reload, or returns
+// "" when there were none
+func reloadSummary(added, updated, removed []string) string {
+	var parts []string
+	for _, change := range []struct {
+		verb string
+		urls []string
+	}{{"added", added}, {"updated", updated}, {"removed", removed}} {
+		if len(change.urls) > 0 {
+			slices.Sort(change.urls)
+			parts = append(parts, change.verb+" "+strings.Join(change.urls, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Config returns the configuration the service is running, including
+// endpoints merged from remote_endpoints
+func (s *Service) Config() config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
 // Shutdown gracefully stops all monitoring
 func (s *Service) Shutdown(ctx context.Context) error {
 	s.mu.Lock()
@@ -206,19 +744,34 @@ func (s *Service) Shutdown(ctx context.Context) error {
 	for _, monitor := range s.endpoints {
 		monitor.cancel()
 	}
+	if s.muteTimer != nil {
+		s.muteTimer.Stop()
+	}
+	if s.graceTimer != nil {
+		s.graceTimer.Stop()
+	}
+	if s.scheduler != nil {
+		s.scheduler.stop()
+	}
 	s.mu.Unlock()
 
-	// Wait for all goroutines to finish or context to cancel
+	// Wait for all goroutines to finish or context to cancel. Queued saves
+	// and check callbacks finish the results already queued once no more
+	// can arrive.
 	done := make(chan struct{})
 	go func() {
 		s.shutdownWg.Wait()
+		s.saves.shutdown()
+		s.callbacks.shutdown()
 		close(done)
 	}()
 
 	select {
 	case <-done:
+		s.recordEvent(EventShutdown, "stopped monitoring")
 		return nil
 	case <-ctx.Done():
+		s.recordEvent(EventShutdown, fmt.Sprintf("stopped before all checks finished: %v", ctx.Err()))
 		return ctx.Err()
 	}
 }
@@ -228,8 +781,96 @@ func endpointConfigEqual(a, b config.Endpoint) bool {
 	return a.URL == b.URL &&
 		a.Interval == b.Interval &&
 		a.Timeout == b.Timeout &&
+		a.ConnectTimeout == b.ConnectTimeout &&
+		a.DNSCacheTTL == b.DNSCacheTTL &&
+		a.DisableDNSCache == b.DisableDNSCache &&
 		a.Name == b.Name &&
-		sliceEqual(a.Tags, b.Tags)
+		a.Private == b.Private &&
+		a.FailureThreshold == b.FailureThreshold &&
+		a.DegradedThreshold == b.DegradedThreshold &&
+		a.AlertsOnDegraded() == b.AlertsOnDegraded() &&
+		a.AlertCooldown == b.AlertCooldown &&
+		a.MinSuccessRate == b.MinSuccessRate &&
+		a.SuccessRateWindow == b.SuccessRateWindow &&
+		a.ExpectInaccessible == b.ExpectInaccessible &&
+		a.CaptureHeadersOnFailure == b.CaptureHeadersOnFailure &&
+		a.HTTPVersion == b.HTTPVersion &&
+		a.ExpectProtocol == b.ExpectProtocol &&
+		a.ExpectRedirectTo == b.ExpectRedirectTo &&
+		a.MaxRedirects == b.MaxRedirects &&
+		a.MinTLSVersion == b.MinTLSVersion &&
+		a.CAFile == b.CAFile &&
+		a.CaptureBody == b.CaptureBody &&
+		a.CaptureBodyLimit == b.CaptureBodyLimit &&
+		a.DecodeBody == b.DecodeBody &&
+		a.DecodedBodyLimit == b.DecodedBodyLimit &&
+		a.DetailLevel == b.DetailLevel &&
+		a.DebugTrace == b.DebugTrace &&
+		a.IPVersion == b.IPVersion &&
+		a.Type == b.Type &&
+		a.Method == b.Method &&
+		a.Body == b.Body &&
+		maps.Equal(a.Headers, b.Headers) &&
+		a.ExpectContinueTimeout == b.ExpectContinueTimeout &&
+		a.ChunkedBody == b.ChunkedBody &&
+		preRequestEqual(a.PreRequest, b.PreRequest) &&
+		stepsEqual(a.Steps, b.Steps) &&
+		circuitBreakerEqual(a.CircuitBreaker, b.CircuitBreaker) &&
+		responseBaselineEqual(a.ResponseBaseline, b.ResponseBaseline) &&
+		relaxEqual(a.Relax, b.Relax) &&
+		slices.Equal(a.Command, b.Command) &&
+		a.WebSocketPing == b.WebSocketPing &&
+		a.Grace == b.Grace &&
+		sliceEqual(a.Tags, b.Tags) &&
+		maps.Equal(a.ExpectHeaders, b.ExpectHeaders)
+}
+
+// preRequestEqual compares two optional pre-requests
+func preRequestEqual(a, b *config.PreRequest) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.URL == b.URL &&
+		a.Method == b.Method &&
+		a.Body == b.Body &&
+		maps.Equal(a.Headers, b.Headers)
+}
+
+// stepsEqual compares two lists of transaction steps
+func stepsEqual(a, b []config.TransactionStep) bool {
+	return slices.EqualFunc(a, b, func(x, y config.TransactionStep) bool {
+		return x.Name == y.Name &&
+			x.Method == y.Method &&
+			x.Path == y.Path &&
+			x.Body == y.Body &&
+			x.ExpectStatus == y.ExpectStatus &&
+			maps.Equal(x.Headers, y.Headers) &&
+			maps.Equal(x.Capture, y.Capture)
+	})
+}
+
+// circuitBreakerEqual compares two optional circuit breaker settings
+func circuitBreakerEqual(a, b *config.CircuitBreaker) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// responseBaselineEqual compares two optional response baseline settings
+func responseBaselineEqual(a, b *config.ResponseBaseline) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// relaxEqual compares two optional relax settings
+func relaxEqual(a, b *config.RelaxSettings) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 // sliceEqual compares two string slices
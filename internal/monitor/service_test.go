@@ -0,0 +1,339 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// recorder is a Storage and Notifier that hands what it receives to the test
+type recorder struct {
+	checks      chan HealthCheck
+	transitions chan Transition
+
+	mu     sync.Mutex
+	events []Event
+}
+
+func newRecorder() *recorder {
+	return &recorder{checks: make(chan HealthCheck, 100), transitions: make(chan Transition, 100)}
+}
+
+func (r *recorder) SaveCheck(check HealthCheck) error {
+	r.checks <- check
+	return nil
+}
+
+func (r *recorder) SaveEvent(event Event) error {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+func (r *recorder) Notify(transition Transition) {
+	r.transitions <- transition
+}
+
+// nextCheck waits for the next saved check
+func (r *recorder) nextCheck(t testing.TB) HealthCheck {
+	t.Helper()
+	select {
+	case check := <-r.checks:
+		return check
+	case <-time.After(5 * time.Second):
+		t.Fatal("no check was saved")
+		return HealthCheck{}
+	}
+}
+
+// waitForEvent waits for an event of the given type and returns it
+func (r *recorder) waitForEvent(t testing.TB, eventType string) Event {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		for _, event := range r.events {
+			if event.Type == eventType {
+				r.mu.Unlock()
+				return event
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %s event was recorded", eventType)
+	return Event{}
+}
+
+// noTransition fails if a transition is waiting
+func (r *recorder) noTransition(t *testing.T) {
+	t.Helper()
+	select {
+	case transition := <-r.transitions:
+		t.Fatalf("unexpected transition %s -> %s", transition.Previous, transition.Current)
+	default:
+	}
+}
+
+// statusServer serves the status code held in status, or drops the
+// connection while it is 0
+func statusServer(t testing.TB, status *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := int(status.Load())
+		if code == 0 {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testConfig monitors the given endpoints with quiet defaults
+func testConfig(endpoints ...config.Endpoint) config.Config {
+	var cfg config.Config
+	cfg.Monitor.ConfigCheck = config.Duration(time.Hour)
+	cfg.Monitor.StartupRamp = config.Duration(time.Nanosecond)
+	cfg.Monitor.Endpoints = endpoints
+	return cfg
+}
+
+// startService runs a service on a fake clock until the test ends
+func startService(t testing.TB, cfg config.Config, reload func() (*config.Config, error)) (*Service, *fakeClock, *recorder) {
+	t.Helper()
+	return startServiceLogging(t, cfg, reload, log.New(io.Discard, "", 0))
+}
+
+// startServiceLogging is startService with the service logging to logger
+func startServiceLogging(t testing.TB, cfg config.Config, reload func() (*config.Config, error), logger *log.Logger) (*Service, *fakeClock, *recorder) {
+	t.Helper()
+	rec := newRecorder()
+	clock := newFakeClock()
+	service := NewService(rec, rec, nil, logger, cfg, reload)
+	service.SetClock(clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := service.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start() = %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		defer done()
+		service.Shutdown(shutdownCtx)
+	})
+	return service, clock, rec
+}
+
+func TestChecksFollowInterval(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := statusServer(t, &status)
+	_, clock, rec := startService(t, testConfig(config.Endpoint{
+		Name:     "api",
+		URL:      server.URL,
+		Enabled:  true,
+		Interval: config.Duration(time.Minute),
+	}), nil)
+	start := clock.Now()
+	clock.waitForTimer(t, time.Minute)
+
+	// Nothing is due before the first interval, so the first check saved
+	// must come from the advance that reaches it
+	clock.Advance(30 * time.Second)
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Minute - 30*time.Second)
+		check := rec.nextCheck(t)
+		if want := start.Add(time.Duration(i) * time.Minute); !check.Timestamp.Equal(want) {
+			t.Fatalf("check %d at %s, want %s", i, check.Timestamp, want)
+		}
+		if check.Status != StatusUp {
+			t.Fatalf("check %d status = %s, want %s", i, check.Status, StatusUp)
+		}
+		clock.Advance(30 * time.Second)
+	}
+}
+
+func TestHeapSchedulerFollowsInterval(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := statusServer(t, &status)
+	cfg := testConfig(config.Endpoint{
+		Name:     "api",
+		URL:      server.URL,
+		Enabled:  true,
+		Interval: config.Duration(time.Minute),
+	})
+	cfg.Monitor.Scheduler = config.SchedulerHeap
+	_, clock, rec := startService(t, cfg, nil)
+	start := clock.Now()
+
+	// The dispatcher sets its timer again after every check
+	for i := 1; i <= 3; i++ {
+		clock.waitForTimer(t, time.Minute)
+		clock.Advance(30 * time.Second)
+		clock.Advance(30 * time.Second)
+		check := rec.nextCheck(t)
+		if want := start.Add(time.Duration(i) * time.Minute); !check.Timestamp.Equal(want) {
+			t.Fatalf("check %d at %s, want %s", i, check.Timestamp, want)
+		}
+	}
+}
+
+func TestMuteExpires(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := statusServer(t, &status)
+	service, clock, rec := startService(t, testConfig(config.Endpoint{
+		Name:     "api",
+		URL:      server.URL,
+		Enabled:  true,
+		Interval: config.Duration(time.Minute),
+	}), nil)
+
+	clock.waitForTimer(t, time.Minute)
+	clock.Advance(time.Minute)
+	rec.nextCheck(t)
+
+	until := service.Mute(90 * time.Second)
+	if got := service.MutedUntil(); !got.Equal(until) {
+		t.Fatalf("MutedUntil() = %s, want %s", got, until)
+	}
+
+	// Going down while muted is not notified
+	status.Store(http.StatusInternalServerError)
+	clock.Advance(time.Minute)
+	if check := rec.nextCheck(t); check.Status != StatusDegraded {
+		t.Fatalf("check status = %s, want %s", check.Status, StatusDegraded)
+	}
+	rec.noTransition(t)
+
+	// The recovery after the mute expires is
+	status.Store(http.StatusOK)
+	clock.Advance(time.Minute)
+	rec.nextCheck(t)
+	if got := service.MutedUntil(); !got.IsZero() {
+		t.Fatalf("MutedUntil() = %s after expiry, want zero", got)
+	}
+	select {
+	case transition := <-rec.transitions:
+		if transition.Current != StatusUp {
+			t.Fatalf("transition to %s, want %s", transition.Current, StatusUp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("recovery was not notified")
+	}
+}
+
+func TestStartKeepsGoodEndpoints(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := statusServer(t, &status)
+	cfg := testConfig(
+		config.Endpoint{Name: "good 1", URL: server.URL + "/1", Enabled: true, Interval: config.Duration(time.Minute)},
+		config.Endpoint{Name: "no interval", URL: server.URL + "/bad", Enabled: true},
+		config.Endpoint{Name: "good 2", URL: server.URL + "/2", Enabled: true, Interval: config.Duration(time.Minute)},
+		config.Endpoint{Name: "duplicate", URL: server.URL + "/1", Enabled: true, Interval: config.Duration(time.Minute)},
+		config.Endpoint{Name: "good 3", URL: server.URL + "/3", Enabled: true, Interval: config.Duration(time.Minute)},
+	)
+	rec := newRecorder()
+	clock := newFakeClock()
+	service := NewService(rec, rec, nil, log.New(io.Discard, "", 0), cfg, nil)
+	service.SetClock(clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		service.Shutdown(context.Background())
+	}()
+
+	err := service.Start(ctx)
+	var startErr *StartError
+	if !errors.As(err, &startErr) {
+		t.Fatalf("Start() = %v, want a *StartError", err)
+	}
+	if want := []string{server.URL + "/bad", server.URL + "/1"}; !slices.Equal(startErr.URLs, want) {
+		t.Errorf("failed URLs = %v, want %v", startErr.URLs, want)
+	}
+
+	// The good endpoints are monitored all the same
+	clock.waitForTimers(t, 3)
+	clock.Advance(time.Minute)
+	var checked []string
+	for range 3 {
+		checked = append(checked, rec.nextCheck(t).Name)
+	}
+	slices.Sort(checked)
+	if want := []string{"good 1", "good 2", "good 3"}; !slices.Equal(checked, want) {
+		t.Errorf("checked %v, want %v", checked, want)
+	}
+}
+
+func TestReloadKeepsSchedule(t *testing.T) {
+	for _, scheduler := range []string{config.SchedulerGoroutines, config.SchedulerHeap} {
+		t.Run(scheduler, func(t *testing.T) {
+			var status atomic.Int32
+			status.Store(http.StatusOK)
+			server := statusServer(t, &status)
+			endpoint := config.Endpoint{
+				Name:     "api",
+				URL:      server.URL,
+				Enabled:  true,
+				Interval: config.Duration(time.Minute),
+				Timeout:  config.Duration(10 * time.Second),
+			}
+			cfg := testConfig(endpoint)
+			cfg.Monitor.Scheduler = scheduler
+			reloaded := cfg
+			endpoint.Timeout = config.Duration(5 * time.Second)
+			reloaded.Monitor.Endpoints = []config.Endpoint{endpoint}
+			service, clock, rec := startService(t, cfg, func() (*config.Config, error) {
+				return &reloaded, nil
+			})
+			start := clock.Now()
+
+			clock.waitForTimer(t, time.Minute)
+			clock.Advance(time.Minute)
+			rec.nextCheck(t)
+			if scheduler == config.SchedulerHeap {
+				clock.waitForTimer(t, time.Minute)
+			}
+
+			// The restarted endpoint waits out what was left of its
+			// interval rather than checking straight away
+			clock.Advance(30 * time.Second)
+			if err := service.reloadConfig(); err != nil {
+				t.Fatalf("reloadConfig() = %v", err)
+			}
+			clock.waitForTimer(t, 30*time.Second)
+			clock.Advance(29 * time.Second)
+			if n := len(rec.checks); n != 0 {
+				t.Fatalf("%d checks saved right after the reload, want none", n)
+			}
+			clock.Advance(time.Second)
+			if check := rec.nextCheck(t); !check.Timestamp.Equal(start.Add(2 * time.Minute)) {
+				t.Fatalf("check at %s, want %s", check.Timestamp, start.Add(2*time.Minute))
+			}
+			clock.Advance(30 * time.Second)
+			if n := len(rec.checks); n != 0 {
+				t.Fatalf("%d more checks saved within the interval, want none", n)
+			}
+		})
+	}
+}
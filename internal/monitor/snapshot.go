@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// EndpointState is a copy of one endpoint's configuration and latest result.
+// It shares no memory with the service, so callers may keep and modify it.
+type EndpointState struct {
+	Endpoint config.Endpoint
+	// Status is the confirmed status after failure thresholds, empty until
+	// one is established
+	Status string
+	// The remaining fields describe the most recent check and are zero
+	// until the first one completes
+	LastStatus       string
+	LastStatusCode   int
+	LastResponseTime int64 // milliseconds
+	LastError        string
+	LastReason       string
+	LastCheck        time.Time
+	SkippedChecks    int
+	// Breaker is the state of the endpoint's circuit breaker, nil when it
+	// has none
+	Breaker *BreakerStatus
+	// Stale is set when the endpoint's checks are overdue by more than
+	// stale_after_intervals, so its status may no longer be current
+	Stale bool
+}
+
+// Snapshot returns the state of every monitored endpoint, ordered by URL.
+// It is the read path for anything outside the monitoring goroutines.
+func (s *Service) Snapshot() []EndpointState {
+	intervals := s.staleIntervals()
+	now := s.clock.Now()
+	s.mu.RLock()
+	states := make([]EndpointState, 0, len(s.endpoints))
+	for _, monitor := range s.endpoints {
+		endpoint := monitor.endpoint
+		endpoint.Tags = slices.Clone(endpoint.Tags)
+		endpoint.ExpectHeaders = maps.Clone(endpoint.ExpectHeaders)
+		endpoint.Headers = maps.Clone(endpoint.Headers)
+		endpoint.Command = slices.Clone(endpoint.Command)
+		if alert := endpoint.AlertOnDegraded; alert != nil {
+			alertCopy := *alert
+			endpoint.AlertOnDegraded = &alertCopy
+		}
+		if pre := endpoint.PreRequest; pre != nil {
+			preCopy := *pre
+			preCopy.Headers = maps.Clone(pre.Headers)
+			endpoint.PreRequest = &preCopy
+		}
+		if steps := endpoint.Steps; steps != nil {
+			endpoint.Steps = make([]config.TransactionStep, len(steps))
+			for i, step := range steps {
+				step.Headers = maps.Clone(step.Headers)
+				step.Capture = maps.Clone(step.Capture)
+				endpoint.Steps[i] = step
+			}
+		}
+		if breaker := endpoint.CircuitBreaker; breaker != nil {
+			breakerCopy := *breaker
+			endpoint.CircuitBreaker = &breakerCopy
+		}
+		if baseline := endpoint.ResponseBaseline; baseline != nil {
+			baselineCopy := *baseline
+			endpoint.ResponseBaseline = &baselineCopy
+		}
+		if relax := endpoint.Relax; relax != nil {
+			relaxCopy := *relax
+			endpoint.Relax = &relaxCopy
+		}
+
+		monitor.mu.Lock()
+		states = append(states, EndpointState{
+			Endpoint:         endpoint,
+			Status:           monitor.state.Status,
+			LastStatus:       monitor.lastStatus,
+			LastStatusCode:   monitor.lastStatusCode,
+			LastResponseTime: monitor.lastResponseTime,
+			LastError:        monitor.lastError,
+			LastReason:       monitor.lastReason,
+			LastCheck:        monitor.lastCheck,
+			SkippedChecks:    monitor.skipped,
+			Breaker:          monitor.breakerStatus(),
+			Stale:            monitor.isStale(now, intervals),
+		})
+		monitor.mu.Unlock()
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Endpoint.URL < states[j].Endpoint.URL
+	})
+	return states
+}
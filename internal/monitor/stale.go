@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultStaleIntervals is how many intervals a check may be overdue before
+// the endpoint's status is stale, when stale_after_intervals is not set
+const defaultStaleIntervals = 3
+
+// defaultWatchdogInterval is how often the watchdog looks for stale
+// endpoints, when watchdog_interval is not set
+const defaultWatchdogInterval = time.Second
+
+// staleIntervals returns the configured stale_after_intervals or the default
+func (s *Service) staleIntervals() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if n := s.config.Monitor.StaleAfterIntervals; n > 0 {
+		return n
+	}
+	return defaultStaleIntervals
+}
+
+// watchdogRestarts reports whether stale endpoints are restarted
+func (s *Service) watchdogRestarts() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Monitor.WatchdogRestart
+}
+
+// isStale reports whether the monitor has stopped producing checks: the last
+// check is more than the given number of intervals, plus the timeout, older
+// than when it was due. Counting from the due time keeps the startup ramp and
+// a circuit breaker's backoff from making an endpoint stale. The caller holds
+// m.mu.
+func (m *EndpointMonitor) isStale(now time.Time, intervals int) bool {
+	interval := m.endpoint.Interval.ToDuration()
+	if interval <= 0 || m.nextCheck.IsZero() {
+		return false
+	}
+	expected := m.nextCheck.Add(-interval)
+	limit := time.Duration(intervals)*interval + m.endpoint.Timeout.ToDuration()
+	return now.Sub(expected) > limit
+}
+
+// watchStale is the watchdog that periodically looks for endpoints whose
+// checks stopped completing, every interval
+func (s *Service) watchStale(ctx context.Context, interval time.Duration) {
+	defer s.shutdownWg.Done()
+
+	if interval <= 0 {
+		interval = defaultWatchdogInterval
+	}
+	ticker := s.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+			s.checkStale()
+		}
+	}
+}
+
+// checkStale logs, counts and records endpoints that went stale since the
+// last look, restarting them when watchdog_restart is set, and logs those
+// whose checks resumed
+func (s *Service) checkStale() {
+	intervals := s.staleIntervals()
+	now := s.clock.Now()
+
+	type change struct {
+		monitor   *EndpointMonitor
+		stale     bool
+		lastCheck time.Time
+	}
+	var changes []change
+	s.mu.RLock()
+	for _, monitor := range s.endpoints {
+		monitor.mu.Lock()
+		stale := monitor.isStale(now, intervals)
+		if stale != monitor.stale {
+			monitor.stale = stale
+			changes = append(changes, change{monitor, stale, monitor.lastCheck})
+		}
+		monitor.mu.Unlock()
+	}
+	s.mu.RUnlock()
+
+	restart := s.watchdogRestarts()
+	for _, c := range changes {
+		endpoint := c.monitor.endpoint
+		if !c.stale {
+			s.logger.Printf("Checks of %s resumed, its status is current again", endpoint.URL)
+			continue
+		}
+		since := "it started"
+		if !c.lastCheck.IsZero() {
+			since = c.lastCheck.Format(time.RFC3339)
+		}
+		message := fmt.Sprintf("no check has completed since %s, monitoring appears to be stuck", since)
+		s.logger.Printf("ERROR status of %s is stale: %s", endpoint.URL, message)
+		s.metrics.observeStale(endpoint.Name, endpoint.URL)
+		if restart {
+			message += s.restartStuck(c.monitor)
+		}
+		s.recordEndpointEvent(EventWatchdog, endpoint.URL, message)
+	}
+}
+
+// restartStuck replaces a stuck monitor with a new one that checks right
+// away and keeps its state, returning how the restart went for the event log
+func (s *Service) restartStuck(monitor *EndpointMonitor) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	url := monitor.endpoint.URL
+	if s.endpoints[url] != monitor {
+		// Already replaced by a reload
+		return ""
+	}
+	monitor.cancel()
+	if err := s.startEndpoint(context.Background(), monitor.endpoint, 0, monitor); err != nil {
+		s.logger.Printf("ERROR failed to restart stuck monitoring of %s: %v", url, err)
+		return fmt.Sprintf("; restart failed: %v", err)
+	}
+	// The restart is what gets reported, not checks resuming
+	restarted := s.endpoints[url]
+	restarted.mu.Lock()
+	restarted.stale = false
+	restarted.mu.Unlock()
+	s.logger.Printf("Restarted stuck monitoring of %s", url)
+	return "; restarted its monitoring"
+}
@@ -0,0 +1,41 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StartError reports the endpoints that failed to start. The service keeps
+// monitoring every other endpoint.
+type StartError struct {
+	URLs []string // endpoints that failed to start, in config order
+	Err  error    // one error per failed endpoint, joined
+}
+
+func (e *StartError) Error() string {
+	return fmt.Sprintf("%d endpoints failed to start: %v", len(e.URLs), e.Err)
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+// startFailures collects endpoint start errors
+type startFailures struct {
+	urls []string
+	errs []error
+}
+
+func (f *startFailures) add(url string, err error) {
+	f.urls = append(f.urls, url)
+	f.errs = append(f.errs, fmt.Errorf("failed to start endpoint %s: %w", url, err))
+}
+
+// err returns a *StartError for the collected failures, or nil when there
+// were none
+func (f *startFailures) err() error {
+	if len(f.urls) == 0 {
+		return nil
+	}
+	return &StartError{URLs: f.urls, Err: errors.Join(f.errs...)}
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// Check statuses
+const (
+	StatusUp       = "UP"
+	StatusDegraded = "DEGRADED"
+	StatusError    = "ERROR"
+)
+
+// Success-rate alert statuses, reported separately from the check status
+const (
+	StatusSuccessRateLow = "SUCCESS_RATE_LOW"
+	StatusSuccessRateOK  = "SUCCESS_RATE_OK"
+)
+
+// AlertPolicy holds the thresholds that decide when a status change is
+// confirmed
+type AlertPolicy struct {
+	// FailureThreshold is the number of consecutive failed checks required
+	// before an endpoint is considered down
+	FailureThreshold int
+	// DegradedThreshold is the number of consecutive DEGRADED checks
+	// required before an endpoint is considered degraded
+	DegradedThreshold int
+	// AlertOnDegraded makes DEGRADED notify like ERROR. Without it, DEGRADED
+	// is treated as UP when deciding whether a transition is notified.
+	AlertOnDegraded bool
+}
+
+// PolicyFor builds the alert policy for an endpoint configuration
+func PolicyFor(endpoint config.Endpoint) AlertPolicy {
+	return AlertPolicy{
+		FailureThreshold:  max(endpoint.FailureThreshold, 1),
+		DegradedThreshold: max(endpoint.DegradedThreshold, 1),
+		AlertOnDegraded:   endpoint.AlertsOnDegraded(),
+	}
+}
+
+// Notifies reports whether a transition should be sent to notifiers. The
+// transition itself, and the DEGRADED status it carries, is unaffected.
+func (p AlertPolicy) Notifies(t Transition) bool {
+	if p.AlertOnDegraded {
+		return true
+	}
+	current := p.alertStatus(t.Current)
+	if t.Previous == "" {
+		// Like UP, a first status that alerts treat as UP is only a baseline
+		return current != StatusUp
+	}
+	return p.alertStatus(t.Previous) != current
+}
+
+// alertStatus maps a confirmed status to the one alerting acts on
+func (p AlertPolicy) alertStatus(status string) string {
+	if status == StatusDegraded && !p.AlertOnDegraded {
+		return StatusUp
+	}
+	return status
+}
+
+// AlertState is the per-endpoint state carried from one check to the next
+type AlertState struct {
+	Status   string    // last confirmed status, empty before the first confirmation
+	Since    time.Time // when the confirmed status began
+	Failures int       // consecutive failed checks
+	// Degraded counts the DEGRADED checks since the last UP one. Failed
+	// checks neither add to it nor reset it.
+	Degraded int
+}
+
+// Transition describes a confirmed status change for an endpoint
+type Transition struct {
+	Check    HealthCheck
+	Previous string
+	Current  string
+	Duration time.Duration // time spent in the previous status
+	Detail   string        // optional explanation, e.g. for success-rate alerts
+	// Notifiers, when set, replaces the notifiers the transition is routed
+	// to, as for an escalation step
+	Notifiers []string
+	// Escalated lists the notifiers an escalation reached, which are told of
+	// a recovery as well as the routed ones
+	Escalated []string
+}
+
+// Evaluate runs a check result through the alerting state machine. It is a
+// pure function so the same logic can drive the live monitor and replays of
+// historical data. A non-nil transition is returned when the confirmed status
+// changes.
+func Evaluate(state AlertState, check HealthCheck, policy AlertPolicy) (AlertState, *Transition) {
+	status := check.Status
+	switch status {
+	case StatusError:
+		state.Failures++
+		if state.Failures < policy.FailureThreshold {
+			return state, nil
+		}
+	case StatusDegraded:
+		state.Failures = 0
+		state.Degraded++
+		if state.Degraded < policy.DegradedThreshold {
+			return state, nil
+		}
+	default:
+		state.Failures = 0
+		state.Degraded = 0
+	}
+
+	if status == state.Status {
+		return state, nil
+	}
+
+	previous := state.Status
+	var duration time.Duration
+	if !state.Since.IsZero() {
+		duration = check.Timestamp.Sub(state.Since)
+	}
+	state.Status = status
+	state.Since = check.Timestamp
+
+	// The first confirmed status only establishes a baseline unless the
+	// endpoint is already unhealthy
+	if previous == "" && status == StatusUp {
+		return state, nil
+	}
+
+	return state, &Transition{
+		Check:    check,
+		Previous: previous,
+		Current:  status,
+		Duration: duration,
+	}
+}
@@ -0,0 +1,236 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// evaluateAll runs checks with the given statuses, a minute apart, through
+// Evaluate and returns the transitions as "PREVIOUS->CURRENT" and the final
+// state
+func evaluateAll(policy AlertPolicy, statuses ...string) ([]string, AlertState) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var state AlertState
+	var transitions []string
+	for i, status := range statuses {
+		check := HealthCheck{Status: status, Timestamp: start.Add(time.Duration(i) * time.Minute)}
+		var transition *Transition
+		state, transition = Evaluate(state, check, policy)
+		if transition != nil {
+			transitions = append(transitions, transition.Previous+"->"+transition.Current)
+		}
+	}
+	return transitions, state
+}
+
+func TestEvaluate(t *testing.T) {
+	const up, degraded, down = StatusUp, StatusDegraded, StatusError
+	tests := []struct {
+		name        string
+		policy      AlertPolicy
+		statuses    []string
+		transitions []string
+		status      string
+	}{
+		{
+			name:     "first UP is a baseline",
+			policy:   AlertPolicy{FailureThreshold: 1, DegradedThreshold: 1},
+			statuses: []string{up, up},
+			status:   up,
+		},
+		{
+			name:        "first ERROR is reported",
+			policy:      AlertPolicy{FailureThreshold: 1, DegradedThreshold: 1},
+			statuses:    []string{down},
+			transitions: []string{"->ERROR"},
+			status:      down,
+		},
+		{
+			name:        "down and recovery",
+			policy:      AlertPolicy{FailureThreshold: 1, DegradedThreshold: 1},
+			statuses:    []string{up, down, down, up},
+			transitions: []string{"UP->ERROR", "ERROR->UP"},
+			status:      up,
+		},
+		{
+			name:     "failures below the threshold",
+			policy:   AlertPolicy{FailureThreshold: 3, DegradedThreshold: 1},
+			statuses: []string{up, down, down, up, down, down, up},
+			status:   up,
+		},
+		{
+			name:        "failures reach the threshold",
+			policy:      AlertPolicy{FailureThreshold: 3, DegradedThreshold: 1},
+			statuses:    []string{up, down, down, down, down},
+			transitions: []string{"UP->ERROR"},
+			status:      down,
+		},
+		{
+			name:        "one UP recovers",
+			policy:      AlertPolicy{FailureThreshold: 3, DegradedThreshold: 1},
+			statuses:    []string{up, down, down, down, up},
+			transitions: []string{"UP->ERROR", "ERROR->UP"},
+			status:      up,
+		},
+		{
+			name:     "degraded below the threshold",
+			policy:   AlertPolicy{FailureThreshold: 1, DegradedThreshold: 2},
+			statuses: []string{up, degraded, up, degraded, up},
+			status:   up,
+		},
+		{
+			name:        "degraded reaches the threshold",
+			policy:      AlertPolicy{FailureThreshold: 1, DegradedThreshold: 2},
+			statuses:    []string{up, degraded, degraded, up},
+			transitions: []string{"UP->DEGRADED", "DEGRADED->UP"},
+			status:      up,
+		},
+		{
+			name:        "degraded to down and back",
+			policy:      AlertPolicy{FailureThreshold: 1, DegradedThreshold: 1},
+			statuses:    []string{up, degraded, down, degraded, up},
+			transitions: []string{"UP->DEGRADED", "DEGRADED->ERROR", "ERROR->DEGRADED", "DEGRADED->UP"},
+			status:      up,
+		},
+		{
+			name:        "failures below the threshold keep DEGRADED",
+			policy:      AlertPolicy{FailureThreshold: 2, DegradedThreshold: 1},
+			statuses:    []string{up, degraded, down, degraded},
+			transitions: []string{"UP->DEGRADED"},
+			status:      degraded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transitions, state := evaluateAll(tt.policy, tt.statuses...)
+			if !reflect.DeepEqual(transitions, tt.transitions) {
+				t.Errorf("transitions = %q, want %q", transitions, tt.transitions)
+			}
+			if state.Status != tt.status {
+				t.Errorf("status = %q, want %q", state.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestEvaluateDuration(t *testing.T) {
+	policy := AlertPolicy{FailureThreshold: 2, DegradedThreshold: 1}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var state AlertState
+	var last *Transition
+	for i, status := range []string{StatusUp, StatusError, StatusError, StatusError, StatusUp} {
+		var transition *Transition
+		state, transition = Evaluate(state, HealthCheck{Status: status, Timestamp: start.Add(time.Duration(i) * time.Minute)}, policy)
+		if transition != nil {
+			last = transition
+		}
+	}
+
+	// Down was confirmed by the second failure, two minutes in, and
+	// recovered at four
+	if last == nil || last.Current != StatusUp {
+		t.Fatalf("last transition = %+v, want a recovery", last)
+	}
+	if last.Duration != 2*time.Minute {
+		t.Errorf("recovery Duration = %s, want 2m", last.Duration)
+	}
+	if !state.Since.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("Since = %s, want %s", state.Since, start.Add(4*time.Minute))
+	}
+}
+
+func TestDegradedAndFailureCounters(t *testing.T) {
+	const up, degraded, down = StatusUp, StatusDegraded, StatusError
+	type step struct {
+		status   string
+		failures int
+		degraded int
+		current  string // confirmed status after the check
+	}
+	tests := []struct {
+		name   string
+		policy AlertPolicy
+		steps  []step
+	}{
+		{
+			// Each DEGRADED resets the failure count, so alternating
+			// results never confirm ERROR
+			name:   "DEGRADED resets failures",
+			policy: AlertPolicy{FailureThreshold: 2, DegradedThreshold: 3},
+			steps: []step{
+				{up, 0, 0, up},
+				{down, 1, 0, up},
+				{degraded, 0, 1, up},
+				{down, 1, 1, up},
+				{degraded, 0, 2, up},
+				{down, 1, 2, up},
+			},
+		},
+		{
+			// Failures neither add to nor reset the degraded count, which
+			// keeps building across them
+			name:   "ERROR keeps the degraded count",
+			policy: AlertPolicy{FailureThreshold: 2, DegradedThreshold: 3},
+			steps: []step{
+				{up, 0, 0, up},
+				{degraded, 0, 1, up},
+				{down, 1, 1, up},
+				{degraded, 0, 2, up},
+				{down, 1, 2, up},
+				{degraded, 0, 3, degraded},
+			},
+		},
+		{
+			name:   "confirmed ERROR then DEGRADED",
+			policy: AlertPolicy{FailureThreshold: 2, DegradedThreshold: 2},
+			steps: []step{
+				{up, 0, 0, up},
+				{down, 1, 0, up},
+				{down, 2, 0, down},
+				{degraded, 0, 1, down},
+				{down, 1, 1, down},
+				{degraded, 0, 2, degraded},
+			},
+		},
+		{
+			name:   "confirmed DEGRADED then ERROR",
+			policy: AlertPolicy{FailureThreshold: 2, DegradedThreshold: 1},
+			steps: []step{
+				{up, 0, 0, up},
+				{degraded, 0, 1, degraded},
+				{down, 1, 1, degraded},
+				{degraded, 0, 2, degraded},
+				{down, 1, 2, degraded},
+				{down, 2, 2, down},
+			},
+		},
+		{
+			name:   "UP resets both",
+			policy: AlertPolicy{FailureThreshold: 3, DegradedThreshold: 3},
+			steps: []step{
+				{degraded, 0, 1, ""},
+				{down, 1, 1, ""},
+				{degraded, 0, 2, ""},
+				{down, 1, 2, ""},
+				{up, 0, 0, up},
+				{degraded, 0, 1, up},
+				{down, 1, 1, up},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state AlertState
+			for i, step := range tt.steps {
+				state, _ = Evaluate(state, HealthCheck{Status: step.status}, tt.policy)
+				if state.Failures != step.failures || state.Degraded != step.degraded || state.Status != step.current {
+					t.Fatalf("after check %d (%s): failures %d, degraded %d, status %q; want %d, %d, %q",
+						i+1, step.status, state.Failures, state.Degraded, state.Status, step.failures, step.degraded, step.current)
+				}
+			}
+		})
+	}
+}
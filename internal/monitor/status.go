@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// statusDisabled is the status of endpoints that are not monitored
+const statusDisabled = "DISABLED"
+
+// Status is a point-in-time view of the service
+type Status struct {
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	// ConnectivityLost is set while every canary is down
+	ConnectivityLost bool             `json:"connectivity_lost,omitempty"`
+	Endpoints        []EndpointStatus `json:"endpoints"`
+}
+
+// EndpointStatus summarizes one monitored endpoint
+type EndpointStatus struct {
+	Name       string     `json:"name"`
+	URL        string     `json:"url"`
+	Status     string     `json:"status"`
+	LastStatus string     `json:"last_status,omitempty"`
+	LastCheck  *time.Time `json:"last_check,omitempty"`
+	// SkippedChecks counts scheduled checks skipped because the previous
+	// check was still running
+	SkippedChecks int `json:"skipped_checks"`
+	// CircuitBreaker is set for endpoints with a circuit breaker
+	CircuitBreaker *BreakerStatus `json:"circuit_breaker,omitempty"`
+	// Stale is set when no check has completed for longer than expected,
+	// meaning Status may be out of date because monitoring is stuck
+	Stale bool `json:"is_stale"`
+	// Enabled is whether the endpoint is monitored, and EnabledSource
+	// whether that follows the config or an operator override
+	Enabled       bool   `json:"enabled"`
+	EnabledSource string `json:"enabled_source"`
+	// Maintenance is the summary of the maintenance calendar event in
+	// progress for the endpoint, during which it is not notified
+	Maintenance string `json:"maintenance,omitempty"`
+	// Acknowledgement is set while an operator has acknowledged the
+	// endpoint's incident
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+	// Private is set for endpoints left out of the public status
+	Private bool `json:"private,omitempty"`
+	// Relaxation is set while the endpoint is checked with its relax
+	// settings
+	Relaxation *Relaxation `json:"relaxation,omitempty"`
+}
+
+// Status reports the mute state and the confirmed status of every endpoint.
+// Configured endpoints that are not monitored are listed as DISABLED.
+func (s *Service) Status() Status {
+	status := Status{Endpoints: []EndpointStatus{}, ConnectivityLost: s.ConnectivityLost()}
+	if until := s.MutedUntil(); !until.IsZero() {
+		status.Muted = true
+		status.MutedUntil = &until
+	}
+
+	// Endpoints that are not monitored have no state in the snapshot
+	sources := make(map[string]string)
+	var disabled []EndpointStatus
+	s.mu.RLock()
+	for _, endpoint := range s.config.Monitor.Endpoints {
+		enabled, source := s.enabled(endpoint)
+		sources[endpoint.URL] = source
+		if !enabled {
+			disabled = append(disabled, EndpointStatus{
+				Name:          endpoint.Name,
+				URL:           endpoint.URL,
+				Status:        statusDisabled,
+				EnabledSource: source,
+				Private:       endpoint.Private,
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, state := range s.Snapshot() {
+		endpoint := EndpointStatus{
+			Name:           state.Endpoint.Name,
+			URL:            state.Endpoint.URL,
+			Status:         statusLabel(state.Status),
+			LastStatus:     state.LastStatus,
+			SkippedChecks:  state.SkippedChecks,
+			CircuitBreaker: state.Breaker,
+			Stale:          state.Stale,
+			Enabled:        true,
+			EnabledSource:  sources[state.Endpoint.URL],
+			Private:        state.Endpoint.Private,
+		}
+		if !state.LastCheck.IsZero() {
+			lastCheck := state.LastCheck
+			endpoint.LastCheck = &lastCheck
+		}
+		if event, ok := s.maintenanceWindow(state.Endpoint); ok {
+			endpoint.Maintenance = event.Summary
+		}
+		if ack, ok := s.acknowledgement(state.Endpoint.URL); ok {
+			endpoint.Acknowledgement = &ack
+		}
+		if relaxation, ok := s.relaxation(state.Endpoint); ok {
+			endpoint.Relaxation = &relaxation
+		}
+		status.Endpoints = append(status.Endpoints, endpoint)
+	}
+
+	status.Endpoints = append(status.Endpoints, disabled...)
+	sort.Slice(status.Endpoints, func(i, j int) bool {
+		return status.Endpoints[i].URL < status.Endpoints[j].URL
+	})
+	return status
+}
+
+// Public returns the status without its private endpoints
+func (s Status) Public() Status {
+	public := s
+	public.Endpoints = slices.DeleteFunc(slices.Clone(s.Endpoints), func(endpoint EndpointStatus) bool {
+		return endpoint.Private
+	})
+	return public
+}
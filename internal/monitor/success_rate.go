@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// defaultSuccessRateWindow is the number of checks used when min_success_rate
+// is set without success_rate_window
+const defaultSuccessRateWindow = 20
+
+// successWindow tracks the outcome of an endpoint's most recent checks to
+// catch partial outages that never change the confirmed status
+type successWindow struct {
+	results []bool // ring buffer of recent outcomes, true for UP
+	next    int
+	count   int
+	ups     int
+	low     bool
+	since   time.Time // when the current low/ok state began
+}
+
+// record adds a check to the window and returns a transition when the
+// success rate crosses the endpoint's minimum. Nothing is reported until the
+// window holds enough checks.
+func (w *successWindow) record(endpoint config.Endpoint, check HealthCheck) *Transition {
+	if endpoint.MinSuccessRate <= 0 {
+		return nil
+	}
+	size := endpoint.SuccessRateWindow
+	if size <= 0 {
+		size = defaultSuccessRateWindow
+	}
+	if len(w.results) != size {
+		*w = successWindow{results: make([]bool, size)}
+	}
+
+	if w.count == size {
+		if w.results[w.next] {
+			w.ups--
+		}
+	} else {
+		w.count++
+	}
+	up := check.Status == StatusUp
+	w.results[w.next] = up
+	if up {
+		w.ups++
+	}
+	w.next = (w.next + 1) % size
+
+	if w.count < size {
+		return nil
+	}
+	if w.since.IsZero() {
+		w.since = check.Timestamp
+	}
+
+	rate := 100 * float64(w.ups) / float64(size)
+	low := rate < endpoint.MinSuccessRate
+	if low == w.low {
+		return nil
+	}
+
+	t := &Transition{
+		Check:    check,
+		Previous: StatusSuccessRateOK,
+		Current:  StatusSuccessRateLow,
+		Duration: check.Timestamp.Sub(w.since),
+		Detail: fmt.Sprintf("success rate %.1f%% over the last %d checks (minimum %g%%)",
+			rate, size, endpoint.MinSuccessRate),
+	}
+	if !low {
+		t.Previous, t.Current = t.Current, t.Previous
+	}
+	w.low = low
+	w.since = check.Timestamp
+	return t
+}
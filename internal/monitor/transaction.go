@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// maxStepResponse bounds how much of a step's response is read for captures
+const maxStepResponse = 1 << 20
+
+// StepResult is the outcome of one step of a transaction check
+type StepResult struct {
+	Name       string `json:"name,omitempty"`
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code,omitempty"`
+	// Time is how long the step took to get its response, in milliseconds
+	Time   int64  `json:"time_ms"`
+	Error  string `json:"error,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// performTransactionCheck sends an endpoint's steps in order, passing the
+// values captured from each response on to the later ones. The first step
+// that fails ends the transaction and sets the check's status: ERROR for a
+// transport failure, DEGRADED for an unexpected status or a missing
+// capture. The response time covers every step sent and the status code is
+// the last step's.
+func (s *Service) performTransactionCheck(ctx context.Context, client *http.Client, check HealthCheck, endpoint config.Endpoint, start time.Time) HealthCheck {
+	vars := make(map[string]string)
+	check.Status = StatusUp
+	for i, step := range endpoint.Steps {
+		result, captured, err := s.performStep(ctx, client, endpoint, step, vars)
+		check.Steps = append(check.Steps, result)
+		check.StatusCode = result.StatusCode
+		label := step.Label(i)
+		if err != nil {
+			check.Status, check.Error = StatusError, label+": "+result.Error
+			break
+		}
+		if result.Reason != "" {
+			check.Status, check.Reason = StatusDegraded, label+": "+result.Reason
+			break
+		}
+		for name, value := range captured {
+			vars[name] = value
+		}
+	}
+	check.setResponseTime(s.since(start))
+	s.applyBaseline(&check, endpoint)
+
+	s.logCheck(check)
+	return check
+}
+
+// performStep sends one step with the variables captured so far and returns
+// its result along with the variables it captures. The error is a transport
+// failure; a response that fails the step gets a Reason instead.
+func (s *Service) performStep(ctx context.Context, client *http.Client, endpoint config.Endpoint, step config.TransactionStep, vars map[string]string) (StepResult, map[string]string, error) {
+	target, err := stepURL(endpoint.URL, config.ExpandStepVariables(step.Path, vars, url.PathEscape))
+	result := StepResult{Name: step.Name, Method: step.Method, URL: target}
+	if result.Method == "" {
+		result.Method = http.MethodGet
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil, err
+	}
+	headers := make(map[string]string, len(step.Headers))
+	for name, value := range step.Headers {
+		headers[name] = config.ExpandStepVariables(value, vars, nil)
+	}
+	req, err := newRequest(ctx, config.Endpoint{
+		URL:     target,
+		Method:  step.Method,
+		Body:    config.ExpandStepVariables(step.Body, vars, nil),
+		Headers: mergeHeaders(endpoint.Headers, headers),
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil, err
+	}
+
+	start := s.clock.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = describeError(endpoint, err)
+		return result, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStepResponse))
+	result.Time = s.since(start).Milliseconds()
+	result.StatusCode = resp.StatusCode
+	if err != nil {
+		result.Error = describeError(endpoint, err)
+		return result, nil, err
+	}
+
+	switch {
+	case step.ExpectStatus != 0 && resp.StatusCode != step.ExpectStatus:
+		result.Reason = fmt.Sprintf("expected status code %d, got %d", step.ExpectStatus, resp.StatusCode)
+	case step.ExpectStatus == 0 && resp.StatusCode >= http.StatusBadRequest:
+		result.Reason = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	if result.Reason != "" || len(step.Capture) == 0 {
+		return result, nil, nil
+	}
+	captured, reason := captureVariables(body, step.Capture)
+	result.Reason = reason
+	return result, captured, nil
+}
+
+// stepURL resolves a step's path against the endpoint's URL
+func stepURL(base, path string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
+
+// captureVariables reads the captured fields from a JSON response body. It
+// returns the reason the step fails when the body is not JSON or lacks a
+// field.
+func captureVariables(body []byte, capture map[string]string) (map[string]string, string) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	names := slices.Sorted(maps.Keys(capture))
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, "response is not JSON, cannot capture " + strings.Join(names, ", ")
+	}
+	vars := make(map[string]string, len(capture))
+	for _, name := range names {
+		value, ok := lookupField(document, capture[name])
+		if !ok {
+			return nil, fmt.Sprintf("response has no field %s to capture as %s", capture[name], name)
+		}
+		vars[name] = value
+	}
+	return vars, ""
+}
+
+// lookupField follows a dotted path such as "data.items.0.id" through a
+// decoded JSON document. Strings and numbers are returned as they are; any
+// other value as JSON.
+func lookupField(document interface{}, path string) (string, bool) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return "", false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+			value = v[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err == nil
+	}
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// defaultConnectTimeout matches the dialer of http.DefaultTransport
+const defaultConnectTimeout = 30 * time.Second
+
+// newClient builds the HTTP client used to check an endpoint, restricting
+// the offered protocol and TLS versions when the endpoint sets them. The
+// endpoint's timeout bounds the whole request and connect_timeout only the
+// dial. Endpoints with a DNS cache TTL resolve their host through dns, and
+// servers are verified against roots when it is not nil.
+func newClient(endpoint config.Endpoint, dns *dnsCache, roots *x509.CertPool) *http.Client {
+	client := &http.Client{
+		Timeout: endpoint.Timeout.ToDuration(),
+	}
+	if endpoint.PreRequest != nil || len(endpoint.Steps) > 0 {
+		// Cookies from the pre-request, or from a transaction's steps, are
+		// kept for the endpoint's checks
+		client.Jar, _ = cookiejar.New(nil)
+	}
+	if endpoint.ExpectRedirectTo != "" {
+		// The redirect itself is checked, so it must not be followed
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	} else {
+		client.CheckRedirect = followRedirect
+	}
+
+	minTLS, hasMinTLS := config.ParseTLSVersion(endpoint.MinTLSVersion)
+	network := dialNetwork(endpoint.IPVersion)
+	connectTimeout := endpoint.ConnectTimeout.ToDuration()
+	dnsTTL := endpoint.DNSCacheTTL.ToDuration()
+	expectContinue := endpoint.ExpectContinueTimeout.ToDuration()
+	if endpoint.HTTPVersion == "" && !hasMinTLS && network == "tcp" && connectTimeout == 0 && dnsTTL == 0 && expectContinue == 0 && roots == nil {
+		return client
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	switch endpoint.HTTPVersion {
+	case config.HTTPVersion1:
+		// A non-nil, empty map disables HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	case config.HTTPVersion2:
+		// The transport always offers HTTP/1.1 as a fallback, so a server
+		// that does not negotiate HTTP/2 is caught by expectedProtocol
+		transport.ForceAttemptHTTP2 = true
+	}
+	if hasMinTLS || roots != nil {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		if hasMinTLS {
+			transport.TLSClientConfig.MinVersion = minTLS
+		}
+		transport.TLSClientConfig.RootCAs = roots
+	}
+	if expectContinue > 0 {
+		transport.ExpectContinueTimeout = expectContinue
+	}
+	if network != "tcp" || connectTimeout > 0 || dnsTTL > 0 {
+		if connectTimeout == 0 {
+			connectTimeout = defaultConnectTimeout
+		}
+		// Forcing the network stops the dialer from falling back to the
+		// other family, so a broken AAAA record is not masked by IPv4
+		dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if dnsTTL > 0 && dns != nil {
+			transport.DialContext = dns.dialContext(dialer, network, dnsTTL)
+		}
+	}
+	client.Transport = transport
+	return client
+}
+
+// dialNetwork returns the network to dial for an ip_version setting
+func dialNetwork(ipVersion string) string {
+	switch ipVersion {
+	case config.IPVersion4:
+		return "tcp4"
+	case config.IPVersion6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// addressFamily returns "4" or "6" for the IP version of a connection's
+// remote address, or "" when it is unknown
+func addressFamily(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+	if tcpAddr.IP.To4() != nil {
+		return config.IPVersion4
+	}
+	return config.IPVersion6
+}
+
+// isTLSVersionError reports whether a request failed because the client and
+// server share no acceptable TLS version. crypto/tls does not export these
+// errors, so they are recognized by message: the first is the server's
+// protocol_version alert, the second the client rejecting the version the
+// server picked.
+func isTLSVersionError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "tls: protocol version not supported") ||
+		strings.Contains(msg, "tls: server selected unsupported protocol version")
+}
+
+// describeError returns the check error for a failed request, describing a
+// timeout by the limit it hit
+func describeError(endpoint config.Endpoint, err error) string {
+	if reason := timeoutReason(endpoint, err); reason != "" {
+		return reason
+	}
+	return err.Error()
+}
+
+// timeoutReason describes a request that failed by timing out, telling a
+// connection that could not be established from a request that was too slow
+// overall. It returns "" for other errors.
+func timeoutReason(endpoint config.Endpoint, err error) string {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return ""
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Sprintf("connect timeout: no connection within %s (%v)", dialTimeout(endpoint), err)
+	}
+	return fmt.Sprintf("request timeout: no complete response within %s (%v)", endpoint.Timeout.ToDuration(), err)
+}
+
+// dialTimeout returns the limit that applied to establishing a connection
+func dialTimeout(endpoint config.Endpoint) time.Duration {
+	connect := endpoint.ConnectTimeout.ToDuration()
+	if connect == 0 {
+		connect = defaultConnectTimeout
+	}
+	if total := endpoint.Timeout.ToDuration(); total > 0 && total < connect {
+		return total
+	}
+	return connect
+}
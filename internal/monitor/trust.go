@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"log"
+	"sync"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// trustStore holds the certificate pools checks verify servers against when
+// a ca_bundle or an endpoint's ca_file is configured. Pools are keyed by
+// ca_file, with "" for endpoints that only use the bundle.
+type trustStore struct {
+	mu      sync.RWMutex
+	pools   map[string]*x509.CertPool
+	digests map[string][sha256.Size]byte
+}
+
+// configure reads the CA bundle and the endpoints' CA files and rebuilds the
+// pools, returning the ca_file keys whose certificates changed. A file that
+// cannot be read is logged and its previous pool, if any, kept.
+func (t *trustStore) configure(bundle string, endpoints []config.Endpoint, logger *log.Logger) map[string]bool {
+	var base []*x509.Certificate
+	failed := make(map[string]bool)
+	if bundle != "" {
+		certs, err := config.ReadCertificates(bundle)
+		if err != nil {
+			logger.Printf("WARN ca_bundle: %v", err)
+			failed[""] = true
+		}
+		base = certs
+	}
+	files := map[string][]*x509.Certificate{"": base}
+	for _, endpoint := range endpoints {
+		if _, ok := files[endpoint.CAFile]; ok || failed[endpoint.CAFile] {
+			continue
+		}
+		certs, err := config.ReadCertificates(endpoint.CAFile)
+		if err != nil {
+			logger.Printf("WARN ca_file for %s: %v", endpoint.URL, err)
+			failed[endpoint.CAFile] = true
+			continue
+		}
+		files[endpoint.CAFile] = append(certs, base...)
+	}
+	for key := range failed {
+		files[key] = nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pools := make(map[string]*x509.CertPool, len(files))
+	digests := make(map[string][sha256.Size]byte, len(files))
+	changed := make(map[string]bool)
+	for key, certs := range files {
+		if len(certs) == 0 {
+			if pool, ok := t.pools[key]; ok && failed[key] {
+				// Keep trusting the certificates last read
+				pools[key], digests[key] = pool, t.digests[key]
+			}
+			continue
+		}
+		digest := certDigest(certs)
+		if previous, ok := t.digests[key]; ok && previous == digest {
+			pools[key], digests[key] = t.pools[key], digest
+			continue
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+		pools[key], digests[key] = pool, digest
+		changed[key] = true
+	}
+	for key := range t.digests {
+		if _, ok := digests[key]; !ok {
+			changed[key] = true
+		}
+	}
+	t.pools, t.digests = pools, digests
+	return changed
+}
+
+// pool returns the roots for an endpoint with the given ca_file, or nil to
+// use the system roots
+func (t *trustStore) pool(caFile string) *x509.CertPool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if pool, ok := t.pools[caFile]; ok {
+		return pool
+	}
+	return t.pools[""]
+}
+
+// certDigest identifies a set of certificates, to tell whether a reload
+// changed them
+func certDigest(certs []*x509.Certificate) [sha256.Size]byte {
+	h := sha256.New()
+	for _, cert := range certs {
+		h.Write(cert.Raw)
+	}
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	return digest
+}
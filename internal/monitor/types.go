@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"log"
+	"net/http"
 	"sync"
 	"time"
 
@@ -10,27 +11,95 @@ import (
 )
 
 // Storage interface defines the required methods for storing health checks
+// and the event log
 type Storage interface {
 	SaveCheck(check HealthCheck) error
+	SaveEvent(event Event) error
 	Close() error
 }
 
+// Notifier is informed of confirmed status changes
+type Notifier interface {
+	Notify(t Transition)
+}
+
 // Service handles the monitoring of endpoints
 type Service struct {
-	storage    Storage
-	logger     *log.Logger
-	config     config.MonitorConfig
-	endpoints  map[string]*EndpointMonitor
-	mu         sync.RWMutex
-	shutdownWg sync.WaitGroup
-	onReload   func() (*config.Config, error)
+	storage     Storage
+	notifier    Notifier
+	metrics     *Metrics
+	logger      *log.Logger
+	config      config.Config
+	endpoints   map[string]*EndpointMonitor
+	mu          sync.RWMutex
+	shutdownWg  sync.WaitGroup
+	onReload    func() (*config.Config, error)
+	mutedUntil  time.Time
+	muteTimer   Timer
+	graceTimer  Timer
+	remote      remoteEndpoints
+	clock       Clock
+	startedAt   time.Time
+	events      hub
+	callbacks   *checkCallbacks
+	saves       *saveQueue
+	scheduler   *heapScheduler
+	dns         *dnsCache
+	limits      hostLimits
+	geo         geoLookup
+	trust       trustStore
+	baselines   baselines
+	maintenance maintenance
+	overrides   overrides
+	acks        acknowledgements
+	relaxed     relaxations
+
+	connectivity connectivity
 }
 
 // EndpointMonitor represents an individual endpoint monitoring goroutine
 type EndpointMonitor struct {
-	endpoint  config.Endpoint
-	cancel    context.CancelFunc
-	lastCheck time.Time
+	endpoint config.Endpoint
+	cancel   context.CancelFunc
+
+	// mu guards the fields below, which are written by the monitoring
+	// goroutine and read by status queries
+	mu               sync.Mutex
+	lastCheck        time.Time
+	lastStatus       string
+	lastStatusCode   int
+	lastResponseTime int64
+	lastError        string
+	lastReason       string
+	state            AlertState
+	successRate      successWindow
+	skipped          int
+	lastBody         *CapturedBody
+	nextCheck        time.Time
+	breaker          circuitBreaker
+	// stale is set by the watchdog while the monitor's checks are overdue
+	stale bool
+	// reported is signalled by each report of a passive endpoint; it is nil
+	// for endpoints monitord checks itself
+	reported chan struct{}
+
+	// Alert cooldown tracking
+	lastNotified   time.Time
+	notifiedStatus string
+	deferred       *Transition
+	cooldownTimer  Timer
+
+	// offlineHeld is set while notifications are suppressed because
+	// connectivity was lost; offlineFrom is the status before them
+	offlineHeld bool
+	offlineFrom string
+
+	// graceHeld is the latest transition held during the startup grace
+	// period, sent when it ends unless the endpoint is UP by then
+	graceHeld *Transition
+
+	// Escalation ladder in progress while the endpoint is down
+	escalation *escalation
 }
 
 // HealthCheck represents the result of a single health check
@@ -41,6 +110,56 @@ type HealthCheck struct {
 	StatusCode   int       `json:"statusCode"`
 	ResponseTime int64     `json:"responseTime"`
 	Timestamp    time.Time `json:"timestamp"`
-	Error        string    `json:"error,omitempty"`
-	Tags         []string  `json:"tags,omitempty"`
+	// ResponseTimeMicros is the response time in microseconds, for
+	// endpoints that answer within a millisecond. ResponseTime is the same
+	// in whole milliseconds.
+	ResponseTimeMicros int64 `json:"responseTimeUs,omitempty"`
+	// Error is a transport or IO failure, such as a refused connection or a
+	// timeout; Reason explains why a response was given a status other than
+	// UP, such as an unexpected status code
+	Error      string      `json:"error,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Protocol   string      `json:"protocol,omitempty"`
+	Probe      string      `json:"probe,omitempty"`
+	TLSVersion string      `json:"tlsVersion,omitempty"`
+	IPVersion  string      `json:"ipVersion,omitempty"`
+	// RemoteIP is the address the check connected to, and Geo its network
+	// and location when geoip_databases are configured
+	RemoteIP string `json:"remoteIp,omitempty"`
+	Geo      *Geo   `json:"geo,omitempty"`
+	// Detail is supplementary output, such as an exec check's stdout and
+	// stderr
+	Detail string `json:"detail,omitempty"`
+	// Components are the sub-component checks listed in an
+	// application/health+json response
+	Components []HealthComponent `json:"components,omitempty"`
+	// Redirects are the redirects followed to reach the final response, in
+	// order, with how long each took
+	Redirects []RedirectHop `json:"redirects,omitempty"`
+	// Steps are the results of a transaction check's steps, in the order
+	// they were sent
+	Steps []StepResult `json:"steps,omitempty"`
+	// BodySize and DecodedBodySize are the response body's size as received
+	// and after decompression, recorded for endpoints with decode_body set
+	BodySize        int64 `json:"bodySize,omitempty"`
+	DecodedBodySize int64 `json:"decodedBodySize,omitempty"`
+	// BodySnippet and Timing are collected for failed checks of endpoints
+	// at the verbose-on-failure detail level
+	BodySnippet string  `json:"bodySnippet,omitempty"`
+	Timing      *Timing `json:"timing,omitempty"`
+	// Repeats is the number of UP checks before this one that
+	// store_on_change_only left out because they repeated the check stored
+	// before them. Uptime counts the check 1+Repeats times.
+	Repeats int `json:"repeats,omitempty"`
+
+	// body is the captured response body, kept in memory only
+	body *CapturedBody
+}
+
+// setResponseTime records how long the check took to get its response
+func (c *HealthCheck) setResponseTime(d time.Duration) {
+	c.ResponseTime = d.Milliseconds()
+	c.ResponseTimeMicros = d.Microseconds()
 }
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// uploadTrace records how a request body was sent, for endpoints that
+// control the Expect: 100-continue handshake or chunked encoding. Its hooks
+// may run on the transport's goroutines.
+type uploadTrace struct {
+	mu        sync.Mutex
+	continued bool
+	sent      bool
+}
+
+// tracesUpload reports whether the way an endpoint's body is sent should be
+// recorded
+func tracesUpload(endpoint config.Endpoint) bool {
+	return endpoint.Body != "" && (endpoint.ExpectContinueTimeout > 0 || endpoint.ChunkedBody)
+}
+
+// hook adds the 100 Continue hook to a trace
+func (u *uploadTrace) hook(trace *httptrace.ClientTrace) {
+	trace.Got100Continue = func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.continued = true
+	}
+}
+
+// wrap watches the request body, which is only read to the end when it is
+// sent
+func (u *uploadTrace) wrap(req *http.Request) {
+	req.Body = &uploadBody{ReadCloser: req.Body, trace: u}
+}
+
+// describe summarises the path the upload took, e.g. "upload: got 100
+// Continue, body sent chunked"
+func (u *uploadTrace) describe(endpoint config.Endpoint, req *http.Request, resp *http.Response) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var parts []string
+	if endpoint.ExpectContinueTimeout > 0 {
+		switch {
+		case u.continued:
+			parts = append(parts, "got 100 Continue")
+		case u.sent:
+			parts = append(parts, fmt.Sprintf("no 100 Continue within %s", endpoint.ExpectContinueTimeout.ToDuration()))
+		default:
+			parts = append(parts, "server responded before 100 Continue")
+		}
+	}
+	switch {
+	case !u.sent:
+		parts = append(parts, "body not sent")
+	case resp != nil && resp.ProtoMajor == 2 && endpoint.ChunkedBody:
+		// HTTP/2 frames the body itself and has no chunked encoding
+		parts = append(parts, "body sent without Content-Length over HTTP/2")
+	case endpoint.ChunkedBody:
+		parts = append(parts, "body sent chunked")
+	default:
+		parts = append(parts, fmt.Sprintf("body sent with Content-Length %d", req.ContentLength))
+	}
+	return "upload: " + strings.Join(parts, ", ")
+}
+
+// uploadBody marks its trace as sent when the body is read to the end
+type uploadBody struct {
+	io.ReadCloser
+	trace *uploadTrace
+}
+
+func (b *uploadBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.trace.mu.Lock()
+		b.trace.sent = true
+		b.trace.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,288 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// WebSocket protocol constants (RFC 6455)
+const (
+	webSocketGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	webSocketVersion = "13"
+	webSocketPayload = "monitord"
+
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// performWebSocketCheck performs a websocket endpoint's upgrade handshake,
+// then optionally sends a ping and waits for the pong. The response time is
+// the time to complete the handshake. A connection failure is an ERROR; a
+// server that refuses the upgrade or does not answer the ping is DEGRADED.
+func (s *Service) performWebSocketCheck(ctx context.Context, client *http.Client, check HealthCheck, endpoint config.Endpoint, start time.Time) HealthCheck {
+	if timeout := endpoint.Timeout.ToDuration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// A successful handshake's body is the connection itself, which the
+	// client's timeout would wrap as read-only, so ctx bounds the check
+	wsClient := *client
+	wsClient.Timeout = 0
+
+	var remoteAddr net.Addr
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			remoteAddr = info.Conn.RemoteAddr()
+		},
+	}
+	ctx, dnsLookup := withDNSLookup(ctx)
+	key, err := webSocketKey()
+	var resp *http.Response
+	if err == nil {
+		var req *http.Request
+		req, err = newWebSocketRequest(httptrace.WithClientTrace(ctx, trace), endpoint, key)
+		if err == nil {
+			resp, err = wsClient.Do(req)
+		}
+	}
+	check.IPVersion = addressFamily(remoteAddr)
+	s.recordRemote(&check, remoteAddr)
+	if stale := dnsLookup.stale(); stale != "" {
+		check.Detail = stale
+		s.logger.Printf("WARN %s: %s", endpoint.URL, stale)
+	}
+	if err != nil {
+		check.Error = describeError(endpoint, err)
+		check.Status, check.Reason = classify(endpoint, nil, err)
+		s.logCheck(check)
+		return check
+	}
+	defer resp.Body.Close()
+	check.StatusCode = resp.StatusCode
+	check.Protocol = resp.Proto
+	if resp.TLS != nil {
+		check.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	check.setResponseTime(s.since(start))
+
+	conn, writable := resp.Body.(io.ReadWriteCloser)
+	detail := verifyUpgrade(resp, key)
+	if detail == "" && !writable {
+		detail = "connection is not writable"
+	}
+	if detail != "" {
+		check.Status, check.Reason = StatusDegraded, "websocket upgrade failed: "+detail
+		if endpoint.CaptureHeadersOnFailure {
+			check.Headers = redactHeaders(resp.Header)
+		}
+		s.logCheck(check)
+		return check
+	}
+
+	check.Status = StatusUp
+	if endpoint.WebSocketPing {
+		pingStart := s.clock.Now()
+		if err := webSocketPing(ctx, conn); err != nil {
+			check.Status, check.Reason = StatusDegraded, "websocket ping failed: "+err.Error()
+		} else {
+			check.Detail = joinDetail(check.Detail, fmt.Sprintf("pong after %dms", s.since(pingStart).Milliseconds()))
+		}
+	}
+	// Close politely; the server's reply is not waited for
+	_ = writeFrame(conn, opClose, []byte{0x03, 0xe8})
+	s.applyBaseline(&check, endpoint)
+
+	s.logCheck(check)
+	return check
+}
+
+// newWebSocketRequest builds the upgrade request for a ws:// or wss:// URL,
+// with the endpoint's headers
+func newWebSocketRequest(ctx context.Context, endpoint config.Endpoint, key string) (*http.Request, error) {
+	u, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+
+	upgrade := endpoint
+	upgrade.URL = u.String()
+	upgrade.Method, upgrade.Body = http.MethodGet, ""
+	req, err := newRequest(ctx, upgrade)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", webSocketVersion)
+	req.Header.Set("Sec-WebSocket-Key", key)
+	return req, nil
+}
+
+// verifyUpgrade checks the handshake response, describing why it is not a
+// valid upgrade or returning ""
+func verifyUpgrade(resp *http.Response, key string) string {
+	switch {
+	case resp.StatusCode != http.StatusSwitchingProtocols:
+		return fmt.Sprintf("server responded %s instead of 101 Switching Protocols", resp.Status)
+	case !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket"):
+		return fmt.Sprintf("server upgraded to %q instead of websocket", resp.Header.Get("Upgrade"))
+	case resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key):
+		return "Sec-WebSocket-Accept does not match the request key"
+	}
+	return ""
+}
+
+// webSocketKey returns a random handshake key
+func webSocketKey() (string, error) {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key[:]), nil
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept value a server must send
+// for a key
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// webSocketPing sends a ping and waits for the matching pong, skipping any
+// messages the server sends first
+func webSocketPing(ctx context.Context, conn io.ReadWriteCloser) error {
+	if err := writeFrame(conn, opPing, []byte(webSocketPayload)); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- readPong(bufio.NewReader(conn), webSocketPayload)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Closing the connection ends the pending read
+		conn.Close()
+		<-done
+		return errors.New("no pong before the check timed out")
+	}
+}
+
+// readPong reads frames until a pong carrying payload arrives
+func readPong(r *bufio.Reader, payload string) error {
+	for {
+		opcode, data, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case opPong:
+			if string(data) == payload {
+				return nil
+			}
+		case opClose:
+			return errors.New("server closed the connection")
+		}
+	}
+}
+
+// readFrame reads one frame, returning the payload of control frames and
+// discarding that of data frames
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	// Control frames are at most 125 bytes; data frames are not needed
+	if opcode < opClose {
+		_, err := io.CopyN(io.Discard, r, int64(length))
+		return opcode, nil, err
+	}
+	if length > 125 {
+		return 0, nil, fmt.Errorf("control frame of %d bytes exceeds 125", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	return opcode, data, nil
+}
+
+// writeFrame writes a single masked control frame, as clients must mask
+// everything they send
+func writeFrame(w io.Writer, opcode byte, payload []byte) error {
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// joinDetail appends to a check's detail
+func joinDetail(detail, more string) string {
+	if detail == "" {
+		return more
+	}
+	return detail + "; " + more
+}
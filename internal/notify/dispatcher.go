@@ -0,0 +1,368 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"text/template"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/metrics"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Delivery retry defaults
+const (
+	defaultRetryInterval = 30 * time.Second
+	defaultMaxAge        = 24 * time.Hour
+	maxRetryInterval     = time.Hour
+	deliveryBatchSize    = 100
+)
+
+// Pending is a notification waiting to be delivered to one notifier
+type Pending struct {
+	ID           int64
+	Notifier     string
+	Notification Notification
+	CreatedAt    time.Time
+	Attempts     int
+	NextAttempt  time.Time
+	LastError    string
+}
+
+// Queue persists notifications until they have been delivered, and records
+// each delivery in the event log
+type Queue interface {
+	EnqueueNotification(p Pending) error
+	DueNotifications(now time.Time, limit int) ([]Pending, error)
+	UpdateNotification(p Pending) error
+	DeleteNotification(id int64) error
+	PendingNotifications() (int, error)
+	HeldNotifications(notifier, url string, until time.Time) ([]Pending, error)
+	SaveEvent(event monitor.Event) error
+}
+
+// Dispatcher fans transitions out to the configured notifiers. Every
+// notification is written to the queue before delivery and only removed once
+// the notifier accepts it, so an unavailable destination is retried with
+// backoff until it succeeds or the notification exceeds the maximum age.
+type Dispatcher struct {
+	notifiers     map[string]Notifier
+	templates     map[string]*template.Template
+	routes        map[string][]string
+	quiet         *quietHours
+	queue         Queue
+	logger        *log.Logger
+	retryInterval time.Duration
+	maxAge        time.Duration
+	wake          chan struct{}
+	sent          *metrics.CounterVec
+	failed        *metrics.CounterVec
+}
+
+// NewDispatcher creates a dispatcher for the configured notifiers
+func NewDispatcher(cfg config.NotificationConfig, queue Queue, logger *log.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		notifiers:     make(map[string]Notifier),
+		templates:     make(map[string]*template.Template),
+		routes:        cfg.Routes,
+		queue:         queue,
+		logger:        logger,
+		retryInterval: cfg.RetryInterval.ToDuration(),
+		maxAge:        cfg.MaxAge.ToDuration(),
+		wake:          make(chan struct{}, 1),
+	}
+	if d.retryInterval <= 0 {
+		d.retryInterval = defaultRetryInterval
+	}
+	if d.maxAge <= 0 {
+		d.maxAge = defaultMaxAge
+	}
+	quiet, err := newQuietHours(cfg.QuietHours)
+	if err != nil {
+		return nil, err
+	}
+	d.quiet = quiet
+
+	for _, notifierCfg := range cfg.Notifiers {
+		notifier, err := newNotifier(notifierCfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := d.notifiers[notifier.Name()]; exists {
+			return nil, fmt.Errorf("duplicate notifier name %q", notifier.Name())
+		}
+		d.notifiers[notifier.Name()] = notifier
+
+		if notifierCfg.Template != "" {
+			tmpl, err := parseTemplate(notifierCfg.Name, notifierCfg.Template)
+			if err != nil {
+				return nil, fmt.Errorf("notifier %q: invalid template: %w", notifierCfg.Name, err)
+			}
+			d.templates[notifierCfg.Name] = tmpl
+		}
+	}
+
+	return d, nil
+}
+
+// Notify queues a transition for delivery to the notifiers routed to the
+// endpoint's tags. During quiet hours, transitions of endpoints not tagged
+// critical are held until quiet hours end.
+func (d *Dispatcher) Notify(t monitor.Transition) {
+	if len(d.notifiers) == 0 {
+		return
+	}
+
+	n := NewNotification(t)
+	now := time.Now()
+	var heldUntil time.Time
+	if d.quiet != nil && !isCritical(t.Check.Tags) {
+		// Pending times are compared in the local zone by the queue
+		if until := d.quiet.until(now); !until.IsZero() {
+			heldUntil = until.Local()
+		}
+	}
+	for _, name := range d.recipients(t) {
+		if !heldUntil.IsZero() {
+			d.hold(name, t, now, heldUntil)
+			continue
+		}
+		if err := d.queue.EnqueueNotification(Pending{
+			Notifier:     name,
+			Notification: d.message(name, n, t),
+			CreatedAt:    now,
+			NextAttempt:  now,
+		}); err != nil {
+			d.logger.Printf("Error queueing notification for %s via %s: %v", n.label(), name, err)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// hold queues a transition for delivery when quiet hours end. Transitions
+// already held for the endpoint are collapsed into it, so the notification
+// reports the change since quiet hours began and is dropped if the endpoint
+// returned to its earlier status.
+func (d *Dispatcher) hold(name string, t monitor.Transition, now, until time.Time) {
+	held, err := d.queue.HeldNotifications(name, t.Check.URL, until)
+	if err != nil {
+		d.logger.Printf("Error reading held notifications for %s via %s: %v", t.Check.URL, name, err)
+	}
+	held = slices.DeleteFunc(held, func(p Pending) bool {
+		return isSuccessRate(p.Notification.Status) != isSuccessRate(t.Current)
+	})
+	for _, p := range held {
+		d.remove(p)
+	}
+	if len(held) > 0 {
+		t.Previous = held[0].Notification.Previous
+		if t.Current == t.Previous {
+			d.logger.Printf("Dropped held notifications for %s via %s: back to %s during quiet hours",
+				t.Check.URL, name, t.Current)
+			return
+		}
+	}
+
+	if err := d.queue.EnqueueNotification(Pending{
+		Notifier:     name,
+		Notification: d.message(name, NewNotification(t), t),
+		CreatedAt:    now,
+		NextAttempt:  until,
+	}); err != nil {
+		d.logger.Printf("Error queueing notification for %s via %s: %v", t.Check.URL, name, err)
+		return
+	}
+	d.logger.Printf("Holding notification for %s via %s until quiet hours end at %s",
+		t.Check.URL, name, until.Format(time.RFC3339))
+}
+
+// isSuccessRate reports whether a status belongs to a success-rate alert
+func isSuccessRate(status string) bool {
+	return status == monitor.StatusSuccessRateLow || status == monitor.StatusSuccessRateOK
+}
+
+// message applies the notifier's template, if any, to a notification. A
+// template that fails to render falls back to the default message.
+func (d *Dispatcher) message(name string, n Notification, t monitor.Transition) Notification {
+	tmpl, ok := d.templates[name]
+	if !ok {
+		return n
+	}
+	message, err := renderTemplate(tmpl, newMessageData(t))
+	if err != nil {
+		d.logger.Printf("Error rendering notification for %s via %s: %v", n.label(), name, err)
+		return n
+	}
+	n.Message = message
+	return n
+}
+
+// recipients returns the names of the notifiers for a transition: those of
+// an escalation step, or the routed ones plus any an escalation reached
+func (d *Dispatcher) recipients(t monitor.Transition) []string {
+	if len(t.Notifiers) > 0 {
+		return t.Notifiers
+	}
+	names := append(d.route(t.Check.Tags), t.Escalated...)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// route returns the names of the notifiers for an endpoint with the given
+// tags, each once and in order
+func (d *Dispatcher) route(tags []string) []string {
+	var names []string
+	if len(d.routes) > 0 {
+		for _, tag := range tags {
+			names = append(names, d.routes[tag]...)
+		}
+		if len(names) == 0 {
+			names = append(names, d.routes[config.RouteFallback]...)
+		}
+	}
+	if len(names) == 0 {
+		names = slices.Collect(maps.Keys(d.notifiers))
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Pending returns the number of notifications awaiting delivery
+func (d *Dispatcher) Pending() (int, error) {
+	return d.queue.PendingNotifications()
+}
+
+// Run delivers queued notifications until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.retryInterval)
+	defer ticker.Stop()
+
+	d.deliver(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+			d.deliver(ctx)
+		case <-ticker.C:
+			d.deliver(ctx)
+		}
+	}
+}
+
+// deliver attempts every notification that is due
+func (d *Dispatcher) deliver(ctx context.Context) {
+	due, err := d.queue.DueNotifications(time.Now(), deliveryBatchSize)
+	if err != nil {
+		d.logger.Printf("Error reading pending notifications: %v", err)
+		return
+	}
+
+	if len(due) == 0 {
+		return
+	}
+	for _, p := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		d.attempt(ctx, p)
+	}
+
+	if pending, err := d.queue.PendingNotifications(); err == nil && pending > 0 {
+		d.logger.Printf("%d notifications pending delivery", pending)
+	}
+}
+
+// attempt delivers one pending notification and reschedules it on failure
+func (d *Dispatcher) attempt(ctx context.Context, p Pending) {
+	notifier, ok := d.notifiers[p.Notifier]
+	if !ok {
+		d.logger.Printf("Dropping notification for %s: notifier %q is no longer configured",
+			p.Notification.label(), p.Notifier)
+		d.remove(p)
+		return
+	}
+
+	err := notifier.Send(ctx, p.Notification)
+	d.observeAttempt(p.Notifier, err)
+	if err == nil {
+		d.logger.Printf("Sent notification for %s via %s: %s", p.Notification.label(), p.Notifier, p.Notification.Message)
+		d.remove(p)
+		if err := d.queue.SaveEvent(monitor.Event{
+			Timestamp: time.Now(),
+			Type:      monitor.EventNotification,
+			URL:       p.Notification.URL,
+			Message:   fmt.Sprintf("sent via %s: %s", p.Notifier, p.Notification.Message),
+		}); err != nil {
+			d.logger.Printf("Error recording notification event for %s: %v", p.Notification.label(), err)
+		}
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(p.CreatedAt) >= d.maxAge {
+		d.logger.Printf("Giving up on notification for %s via %s after %d attempts: %v",
+			p.Notification.label(), p.Notifier, p.Attempts+1, err)
+		d.remove(p)
+		return
+	}
+
+	p.Attempts++
+	p.LastError = err.Error()
+	p.NextAttempt = now.Add(d.backoff(p.Attempts))
+	d.logger.Printf("Error sending notification for %s via %s (attempt %d, retrying at %s): %v",
+		p.Notification.label(), p.Notifier, p.Attempts, p.NextAttempt.Format(time.RFC3339), err)
+	if err := d.queue.UpdateNotification(p); err != nil {
+		d.logger.Printf("Error rescheduling notification for %s: %v", p.Notification.label(), err)
+	}
+}
+
+// SetMetrics registers counters of delivery attempts on registry. Call it
+// before Run.
+func (d *Dispatcher) SetMetrics(registry *metrics.Registry) {
+	d.sent = registry.NewCounterVec("monitord_notifications_sent_total",
+		"Notifications delivered, by notifier.", "notifier")
+	d.failed = registry.NewCounterVec("monitord_notification_failures_total",
+		"Failed notification delivery attempts, by notifier.", "notifier")
+	for name := range d.notifiers {
+		d.sent.Add(0, name)
+		d.failed.Add(0, name)
+	}
+}
+
+// observeAttempt counts a delivery attempt when metrics are registered
+func (d *Dispatcher) observeAttempt(notifier string, err error) {
+	switch {
+	case d.sent == nil:
+	case err != nil:
+		d.failed.Inc(notifier)
+	default:
+		d.sent.Inc(notifier)
+	}
+}
+
+// backoff doubles the retry interval with each failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.retryInterval
+	for i := 1; i < attempts && delay < maxRetryInterval; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryInterval {
+		delay = maxRetryInterval
+	}
+	return delay
+}
+
+func (d *Dispatcher) remove(p Pending) {
+	if err := d.queue.DeleteNotification(p.ID); err != nil {
+		d.logger.Printf("Error removing notification for %s: %v", p.Notification.label(), err)
+	}
+}
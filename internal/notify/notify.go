@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Notification is the payload delivered to notifiers when an endpoint's
+// confirmed status changes
+type Notification struct {
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Status     string    `json:"status"`
+	Previous   string    `json:"previous_status,omitempty"`
+	Duration   string    `json:"duration,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Message    string    `json:"message"`
+}
+
+// label identifies the notification's endpoint in logs: its URL, or the name
+// for notifications about monitord itself
+func (n Notification) label() string {
+	if n.URL == "" {
+		return n.Name
+	}
+	return n.URL
+}
+
+// Notifier delivers notifications to a single destination
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// NewNotification builds the notification for a status transition
+func NewNotification(t monitor.Transition) Notification {
+	n := Notification{
+		Name:       t.Check.Name,
+		URL:        t.Check.URL,
+		Status:     t.Current,
+		Previous:   t.Previous,
+		Timestamp:  t.Check.Timestamp,
+		StatusCode: t.Check.StatusCode,
+		Error:      t.Check.Error,
+		Reason:     t.Check.Reason,
+		Tags:       t.Check.Tags,
+		Detail:     t.Detail,
+	}
+	if t.Duration > 0 {
+		n.Duration = t.Duration.Round(time.Second).String()
+	}
+
+	// Notifications about monitord itself have no URL
+	n.Message = n.Name
+	if n.URL != "" {
+		n.Message += fmt.Sprintf(" (%s)", n.URL)
+	}
+	n.Message += " is " + n.Status
+	if n.Previous != "" {
+		n.Message += fmt.Sprintf(" (was %s", n.Previous)
+		if n.Duration != "" {
+			n.Message += " for " + n.Duration
+		}
+		n.Message += ")"
+	}
+	if n.Detail != "" {
+		n.Message += ": " + n.Detail
+	}
+	return n
+}
+
+// SendTest delivers a test notification through a configured notifier,
+// bypassing routing, templates and the retry queue
+func SendTest(ctx context.Context, cfg config.NotifierConfig) error {
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return err
+	}
+	return notifier.Send(ctx, Notification{
+		Name:      "monitord",
+		Status:    "TEST",
+		Timestamp: time.Now(),
+		Message:   fmt.Sprintf("Test notification from monitord to %s", cfg.Name),
+	})
+}
+
+// newNotifier builds a notifier from its configuration
+func newNotifier(cfg config.NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case "", "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("notifier %q: url is required", cfg.Name)
+		}
+		return NewWebhook(cfg.Name, cfg.URL, cfg.Timeout.ToDuration()), nil
+	default:
+		return nil, fmt.Errorf("notifier %q: unknown type %q", cfg.Name, cfg.Type)
+	}
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+)
+
+// quietHours is a parsed daily quiet period
+type quietHours struct {
+	start    time.Duration // since midnight
+	end      time.Duration
+	location *time.Location
+}
+
+// newQuietHours parses the configured quiet hours, returning nil when none
+// are configured
+func newQuietHours(cfg *config.QuietHours) (*quietHours, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	start, err := config.ParseClock(cfg.Start)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours: start: %w", err)
+	}
+	end, err := config.ParseClock(cfg.End)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours: end: %w", err)
+	}
+	location := time.Local
+	if cfg.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("quiet_hours: %w", err)
+		}
+	}
+	return &quietHours{start: start, end: end, location: location}, nil
+}
+
+// until returns when the quiet period containing now ends, or the zero time
+// when now is outside quiet hours. Times are compared on the wall clock of
+// the configured location, so quiet hours keep their times across DST
+// changes.
+func (q *quietHours) until(now time.Time) time.Time {
+	local := now.In(q.location)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	year, month, day := local.Date()
+	endOn := func(day int) time.Time {
+		return time.Date(year, month, day, int(q.end/time.Hour), int(q.end%time.Hour/time.Minute), 0, 0, q.location)
+	}
+
+	switch {
+	case q.start < q.end:
+		if clock >= q.start && clock < q.end {
+			return endOn(day)
+		}
+	case clock >= q.start:
+		// Quiet hours run past midnight and began today
+		return endOn(day + 1)
+	case clock < q.end:
+		// Quiet hours run past midnight and began yesterday
+		return endOn(day)
+	}
+	return time.Time{}
+}
+
+// isCritical reports whether an endpoint's notifications bypass quiet hours
+func isCritical(tags []string) bool {
+	return slices.Contains(tags, config.CriticalTag)
+}
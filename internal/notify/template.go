@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// MessageData is what notifier templates are rendered with
+type MessageData struct {
+	Name     string
+	URL      string
+	Tags     []string
+	Status   string
+	Previous string
+	// Downtime is the time spent in the previous status, e.g. "5m0s", or ""
+	// when it is unknown
+	Downtime string
+	Detail   string
+	Check    monitor.HealthCheck
+}
+
+// newMessageData builds the template data for a transition
+func newMessageData(t monitor.Transition) MessageData {
+	data := MessageData{
+		Name:     t.Check.Name,
+		URL:      t.Check.URL,
+		Tags:     t.Check.Tags,
+		Status:   t.Current,
+		Previous: t.Previous,
+		Detail:   t.Detail,
+		Check:    t.Check,
+	}
+	if t.Duration > 0 {
+		data.Downtime = t.Duration.Round(time.Second).String()
+	}
+	return data
+}
+
+// parseTemplate parses a notifier's message template and renders it once
+// with sample data, so references to unknown fields are reported when the
+// notifier is configured rather than when an alert is sent
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	sample := newMessageData(monitor.Transition{
+		Check: monitor.HealthCheck{
+			Name:      "example",
+			URL:       "https://example.com",
+			Status:    monitor.StatusError,
+			Timestamp: time.Now(),
+		},
+		Previous: monitor.StatusUp,
+		Current:  monitor.StatusError,
+		Duration: time.Minute,
+	})
+	if _, err := renderTemplate(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes a message template
+func renderTemplate(tmpl *template.Template, data MessageData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// Webhook posts notifications as JSON to a URL
+type Webhook struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier
+func NewWebhook(name, url string, timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &Webhook{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the configured notifier name
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Send posts the notification and treats any non-2xx response as a failure
+func (w *Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("webhook url is invalid")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// The URL may embed a token, so keep it out of the logged error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("webhook request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Acknowledgements returns the incidents acknowledged by operators
+func (s *SQLiteStore) Acknowledgements() ([]monitor.Acknowledgement, error) {
+	db, release := s.conn()
+	defer release()
+	return queryAcknowledgements(db)
+}
+
+func queryAcknowledgements(db *sql.DB) ([]monitor.Acknowledgement, error) {
+	rows, err := db.Query("SELECT url, acknowledged_by, note, acknowledged_at, expires FROM acknowledgements ORDER BY url")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var acks []monitor.Acknowledgement
+	for rows.Next() {
+		var (
+			a       monitor.Acknowledgement
+			expires sql.NullTime
+		)
+		if err := rows.Scan(&a.URL, &a.By, &a.Note, &a.AcknowledgedAt, &expires); err != nil {
+			return nil, err
+		}
+		if expires.Valid {
+			a.Expires = &expires.Time
+		}
+		acks = append(acks, a)
+	}
+	return acks, rows.Err()
+}
+
+// SaveAcknowledgement records an acknowledgement of an endpoint's incident,
+// replacing any earlier one
+func (s *SQLiteStore) SaveAcknowledgement(ack monitor.Acknowledgement) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		return saveAcknowledgement(db, ack)
+	})
+}
+
+func saveAcknowledgement(db *sql.DB, a monitor.Acknowledgement) error {
+	var expires sql.NullTime
+	if a.Expires != nil {
+		expires = sql.NullTime{Time: *a.Expires, Valid: true}
+	}
+	_, err := db.Exec(`
+        INSERT INTO acknowledgements (url, acknowledged_by, note, acknowledged_at, expires)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT (url) DO UPDATE SET
+            acknowledged_by = excluded.acknowledged_by,
+            note = excluded.note,
+            acknowledged_at = excluded.acknowledged_at,
+            expires = excluded.expires`,
+		a.URL, a.By, a.Note, a.AcknowledgedAt, expires)
+	return err
+}
+
+// DeleteAcknowledgement removes the acknowledgement of an endpoint's incident
+func (s *SQLiteStore) DeleteAcknowledgement(url string) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec("DELETE FROM acknowledgements WHERE url = ?", url)
+		return err
+	})
+}
+
+// moveAcknowledgements copies the acknowledgements still in effect to a new
+// database file so they outlive a rollover
+func moveAcknowledgements(from, to *sql.DB, now time.Time) error {
+	acks, err := queryAcknowledgements(from)
+	if err != nil {
+		return err
+	}
+	for _, a := range acks {
+		if a.Expires != nil && !now.Before(*a.Expires) {
+			continue
+		}
+		if err := saveAcknowledgement(to, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExt is added to the name of a gzipped database file
+const archiveExt = ".gz"
+
+// ArchiveDatabase gzips a database file no longer in use into dir, as its
+// name with .gz added, then removes it along with any journal files SQLite
+// left beside it. The archive gets mode, or the defaults when it is 0. It
+// returns the archive's path.
+func ArchiveDatabase(path, dir string, mode os.FileMode) (string, error) {
+	archived := filepath.Join(dir, filepath.Base(path)+archiveExt)
+	if _, err := os.Stat(archived); err == nil {
+		return "", fmt.Errorf("%s already exists", archived)
+	}
+	if err := os.MkdirAll(dir, dirMode(mode)); err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// The archive only appears under its name once complete
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(archived)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, src); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if mode != 0 {
+		if err := os.Chmod(tmp.Name(), mode); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(tmp.Name(), archived); err != nil {
+		return "", err
+	}
+
+	for _, file := range []string{path, path + "-wal", path + "-shm", path + "-journal"} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return archived, err
+		}
+	}
+	return archived, nil
+}
+
+// OpenArchive opens a database file kept aside, such as one rotated out or
+// archived, for reading. A file whose name ends in .gz is decompressed to a
+// temporary copy first, which Close removes.
+func OpenArchive(path string) (*SQLiteStore, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, archiveExt) {
+		db, err := openDatabase(path, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &SQLiteStore{path: path, db: &handle{db: db}}, nil
+	}
+
+	copied, err := decompressArchive(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	db, err := openDatabase(copied, 0)
+	if err != nil {
+		os.Remove(copied)
+		return nil, err
+	}
+	return &SQLiteStore{path: copied, db: &handle{db: db}, temporary: true}, nil
+}
+
+// decompressArchive writes the database in a gzipped archive to a temporary
+// file and returns its path
+func decompressArchive(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	tmp, err := os.CreateTemp("", "monitord-archive-*.db")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, zr); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Change-only storage defaults
+const (
+	defaultHeartbeatInterval = time.Hour
+	defaultResponseTimeDelta = 500 * time.Millisecond
+)
+
+// ChangeOnlyStore wraps a Storage and drops UP checks that repeat the
+// previous stored check, so stable endpoints do not write a row every
+// interval. Checks that are not UP are always stored, and a heartbeat check
+// is stored at least every heartbeat interval so gaps can be explained. The
+// next check stored counts the dropped ones in its Repeats, so uptime still
+// covers them.
+type ChangeOnlyStore struct {
+	Storage
+	heartbeat time.Duration
+	delta     time.Duration
+
+	mu      sync.Mutex
+	last    map[string]monitor.HealthCheck
+	dropped map[string]droppedChecks
+}
+
+// droppedChecks are the checks of an endpoint dropped since its last stored
+// one
+type droppedChecks struct {
+	latest monitor.HealthCheck
+	count  int
+}
+
+// NewChangeOnlyStore wraps inner using the database settings
+func NewChangeOnlyStore(inner Storage, cfg config.DatabaseConfig) *ChangeOnlyStore {
+	s := &ChangeOnlyStore{
+		Storage:   inner,
+		heartbeat: cfg.HeartbeatInterval.ToDuration(),
+		delta:     cfg.ResponseTimeDelta.ToDuration(),
+		last:      make(map[string]monitor.HealthCheck),
+		dropped:   make(map[string]droppedChecks),
+	}
+	if s.heartbeat <= 0 {
+		s.heartbeat = defaultHeartbeatInterval
+	}
+	if s.delta <= 0 {
+		s.delta = defaultResponseTimeDelta
+	}
+	return s
+}
+
+// SaveCheck stores the check unless it repeats the previous stored one
+func (s *ChangeOnlyStore) SaveCheck(check monitor.HealthCheck) error {
+	s.mu.Lock()
+	previous, ok := s.last[check.URL]
+	dropped := s.dropped[check.URL]
+	if ok && !s.significant(previous, check) {
+		s.dropped[check.URL] = droppedChecks{latest: check, count: dropped.count + 1}
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	check.Repeats += dropped.count
+	if err := s.Storage.SaveCheck(check); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.last[check.URL] = check
+	delete(s.dropped, check.URL)
+	s.mu.Unlock()
+	return nil
+}
+
+// Flush stores the latest dropped check of each endpoint, counting the ones
+// dropped before it, so none are left out of uptime when monitoring stops.
+// Call it once no more checks are saved.
+func (s *ChangeOnlyStore) Flush() error {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = make(map[string]droppedChecks)
+	s.mu.Unlock()
+
+	var errs []error
+	for _, d := range dropped {
+		check := d.latest
+		check.Repeats = d.count - 1
+		if err := s.Storage.SaveCheck(check); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// significant reports whether a check differs enough from the previous
+// stored one to be worth storing
+func (s *ChangeOnlyStore) significant(previous, check monitor.HealthCheck) bool {
+	if check.Status != monitor.StatusUp || check.Status != previous.Status ||
+		check.StatusCode != previous.StatusCode {
+		return true
+	}
+	if check.Timestamp.Sub(previous.Timestamp) >= s.heartbeat {
+		return true
+	}
+	delta := time.Duration(check.ResponseTimeMicros-previous.ResponseTimeMicros) * time.Microsecond
+	return delta > s.delta || delta < -s.delta
+}
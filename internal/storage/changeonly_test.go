@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+func TestChangeOnlyKeepsUptime(t *testing.T) {
+	inner := newTestStore(t, "monitord.db")
+	store := NewChangeOnlyStore(inner, config.DatabaseConfig{StoreOnChangeOnly: true})
+	const url = "https://example.com"
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []string{
+		monitor.StatusUp, monitor.StatusUp, monitor.StatusUp, monitor.StatusUp, monitor.StatusUp,
+		monitor.StatusError,
+		monitor.StatusUp, monitor.StatusUp, monitor.StatusUp,
+	}
+	for i, status := range statuses {
+		check := testCheck(url, start.Add(time.Duration(i)*time.Minute))
+		check.Status = status
+		if err := store.SaveCheck(check); err != nil {
+			t.Fatalf("SaveCheck: %v", err)
+		}
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	saved, err := inner.QueryChecks(CheckFilter{URL: url})
+	if err != nil {
+		t.Fatalf("QueryChecks: %v", err)
+	}
+	if len(saved) >= len(statuses) {
+		t.Errorf("stored %d checks of %d, want fewer", len(saved), len(statuses))
+	}
+	summaries, err := inner.SummarizeChecks(CheckFilter{URL: url})
+	if err != nil {
+		t.Fatalf("SummarizeChecks: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("%d summaries, want 1", len(summaries))
+	}
+	if s := summaries[0]; s.Checks != len(statuses) || s.Up != len(statuses)-1 {
+		t.Errorf("summary counts %d checks, %d UP; want %d, %d", s.Checks, s.Up, len(statuses), len(statuses)-1)
+	}
+}
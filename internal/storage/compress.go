@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"sync"
+)
+
+// Bits of a check's compressed column, one per text column that may hold
+// gzip data instead of text
+const (
+	compressedError = 1 << iota
+	compressedHeaders
+	compressedDetail
+	compressedBodySnippet
+	compressedComponents
+	compressedSteps
+)
+
+// gzipWriters reuses compressors, which are costly to allocate
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// textCompressor gzips the large text columns of one check and collects
+// which ones it compressed
+type textCompressor struct {
+	threshold int
+	flags     int64
+}
+
+// column returns the value to store for a text column: the text itself,
+// or its gzip data when the text is over the threshold and compresses
+// smaller
+func (c *textCompressor) column(text string, flag int64) interface{} {
+	if c.threshold == 0 || len(text) <= c.threshold {
+		return text
+	}
+	var buf bytes.Buffer
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(&buf)
+	if _, err := io.WriteString(zw, text); err != nil {
+		return text
+	}
+	if err := zw.Close(); err != nil || buf.Len() >= len(text) {
+		return text
+	}
+	c.flags |= flag
+	return buf.Bytes()
+}
+
+// nullColumn is column for a text column that may be NULL
+func (c *textCompressor) nullColumn(text sql.NullString, flag int64) interface{} {
+	if !text.Valid {
+		return text
+	}
+	return c.column(text.String, flag)
+}
+
+// decompressColumn restores a text column read back when flags mark it as
+// compressed
+func decompressColumn(text *sql.NullString, flags, flag int64) error {
+	if flags&flag == 0 || !text.Valid {
+		return nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(text.String)))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	text.String = string(data)
+	return nil
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// DetailLevelStore wraps a Storage and trims each check to its endpoint's
+// detail level before it is stored. Checks are otherwise unchanged, so
+// metrics, alerts and the event stream still see everything collected.
+type DetailLevelStore struct {
+	Storage
+	levels func(url string) string
+}
+
+// NewDetailLevelStore wraps inner, looking up each endpoint's detail level
+// with levels when its checks are saved
+func NewDetailLevelStore(inner Storage, levels func(url string) string) *DetailLevelStore {
+	return &DetailLevelStore{Storage: inner, levels: levels}
+}
+
+// SaveCheck stores the check with the detail its endpoint's level keeps
+func (s *DetailLevelStore) SaveCheck(check monitor.HealthCheck) error {
+	return s.Storage.SaveCheck(trimCheck(check, s.levels(check.URL)))
+}
+
+// trimCheck applies a detail level to a check. The minimal level keeps the
+// outcome: status, status code, response time, error, reason, probe and
+// tags.
+// Verbose-on-failure keeps everything for failed checks and the minimal
+// fields for successful ones. Any other level stores the check as collected.
+func trimCheck(check monitor.HealthCheck, level string) monitor.HealthCheck {
+	switch level {
+	case config.DetailMinimal:
+	case config.DetailVerboseOnFailure:
+		if check.Status != monitor.StatusUp {
+			return check
+		}
+	default:
+		return check
+	}
+	return monitor.HealthCheck{
+		Name:               check.Name,
+		URL:                check.URL,
+		Status:             check.Status,
+		StatusCode:         check.StatusCode,
+		ResponseTime:       check.ResponseTime,
+		ResponseTimeMicros: check.ResponseTimeMicros,
+		Timestamp:          check.Timestamp,
+		Error:              check.Error,
+		Reason:             check.Reason,
+		Tags:               check.Tags,
+		Probe:              check.Probe,
+	}
+}
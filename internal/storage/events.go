@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// SaveEvent appends an event to the event log
+func (s *SQLiteStore) SaveEvent(event monitor.Event) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec(`
+            INSERT INTO events (timestamp, type, url, message)
+            VALUES (?, ?, ?, ?)`,
+			event.Timestamp.UnixMilli(),
+			event.Type,
+			event.URL,
+			event.Message,
+		)
+		return err
+	})
+}
+
+// QueryEvents returns the events matching the filter, newest first
+func (s *SQLiteStore) QueryEvents(filter EventFilter) ([]monitor.Event, error) {
+	query := "SELECT id, timestamp, type, url, message FROM events"
+	where, args := filter.whereClause()
+	query += where + " ORDER BY timestamp DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []monitor.Event
+	for rows.Next() {
+		var (
+			e         monitor.Event
+			timestamp int64
+			url       sql.NullString
+		)
+		if err := rows.Scan(&e.ID, &timestamp, &e.Type, &url, &e.Message); err != nil {
+			return nil, err
+		}
+		e.Timestamp = time.UnixMilli(timestamp).UTC()
+		e.URL = url.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// whereClause builds the SQL conditions and arguments for an event filter
+func (f EventFilter) whereClause() (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if f.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, f.Type)
+	}
+	if f.URL != "" {
+		conditions = append(conditions, "url = ?")
+		args = append(args, f.URL)
+	}
+	if !f.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// defaultDirMode is used for directories when no file mode is configured
+const defaultDirMode = 0755
+
+// dirMode returns the mode for directories holding files with the given
+// mode: each class that can read the files can also enter the directory
+func dirMode(mode os.FileMode) os.FileMode {
+	if mode == 0 {
+		return defaultDirMode
+	}
+	return mode | (mode&0444)>>2
+}
+
+// createFile creates an empty file with exactly the given mode, regardless
+// of the umask, so SQLite opens it rather than creating it with the default
+// permissions. SQLite gives its journal and WAL files the same mode. An
+// existing file is left untouched.
+func createFile(path string, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if errors.Is(err, fs.ErrExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,390 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// migrations upgrade the base schema in order. The database's user_version
+// records how many have been applied, so new migrations must only ever be
+// appended.
+var migrations = []func(tx *sql.Tx) error{
+	migrateNormalizedTags,
+	migrateAddHeaders,
+	migratePendingNotifications,
+	migrateAddProtocol,
+	migrateAddProbe,
+	migrateAddTLSVersion,
+	migrateEndpointRegistry,
+	migrateDropNameIndex,
+	migrateAddIPVersion,
+	migrateAddDetail,
+	migrateEvents,
+	migrateAddBodySizes,
+	migrateAddFailureDetail,
+	migrateAddReason,
+	migrateResponseTimeRollups,
+	migrateAddRemoteAddress,
+	migrateAddComponents,
+	migrateEpochTimestamps,
+	migrateEndpointOverrides,
+	migrateAddRedirects,
+	migrateAddCompressed,
+	migrateAcknowledgements,
+	migrateResponseTimeMicros,
+	migrateAddSteps,
+	migrateEndpointRemovedAt,
+	migrateAddRepeats,
+	migrateEpochEventTimestamps,
+}
+
+// migrate applies any migrations the database has not yet seen
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[i](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+		// PRAGMA statements cannot take bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNormalizedTags moves the comma-joined tags column into the tags and
+// check_tags tables
+func migrateNormalizedTags(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS tags (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL UNIQUE
+        );
+        CREATE TABLE IF NOT EXISTS check_tags (
+            check_id INTEGER NOT NULL REFERENCES health_checks(id) ON DELETE CASCADE,
+            tag_id INTEGER NOT NULL REFERENCES tags(id),
+            PRIMARY KEY (check_id, tag_id)
+        );
+        CREATE INDEX IF NOT EXISTS idx_check_tags_tag ON check_tags(tag_id, check_id);
+    `); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, tags FROM health_checks WHERE tags IS NOT NULL AND tags != ''")
+	if err != nil {
+		return err
+	}
+	legacy := make(map[int64][]string)
+	for rows.Next() {
+		var (
+			id   int64
+			tags string
+		)
+		if err := rows.Scan(&id, &tags); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy[id] = strings.Split(tags, ",")
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, tags := range legacy {
+		if err := saveTags(tx, id, tags); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("ALTER TABLE health_checks DROP COLUMN tags")
+	return err
+}
+
+// migrateAddHeaders adds the column holding response headers captured on
+// failure
+func migrateAddHeaders(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN headers TEXT")
+	return err
+}
+
+// migratePendingNotifications adds the queue of undelivered notifications
+func migratePendingNotifications(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS pending_notifications (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            notifier TEXT NOT NULL,
+            payload TEXT NOT NULL,
+            created_at DATETIME NOT NULL,
+            attempts INTEGER NOT NULL DEFAULT 0,
+            next_attempt DATETIME NOT NULL,
+            last_error TEXT
+        );
+        CREATE INDEX IF NOT EXISTS idx_pending_next_attempt ON pending_notifications(next_attempt);
+    `)
+	return err
+}
+
+// migrateAddProtocol adds the column holding the negotiated HTTP protocol
+func migrateAddProtocol(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN protocol TEXT")
+	return err
+}
+
+// migrateAddProbe adds the column naming the instance that ran each check
+func migrateAddProbe(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN probe TEXT")
+	return err
+}
+
+// migrateAddTLSVersion adds the column holding the negotiated TLS version
+func migrateAddTLSVersion(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN tls_version TEXT")
+	return err
+}
+
+// migrateEndpointRegistry adds the registry of every endpoint ever checked,
+// seeded from the existing check history
+func migrateEndpointRegistry(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS endpoints (
+            url TEXT PRIMARY KEY,
+            name TEXT NOT NULL,
+            first_seen DATETIME NOT NULL,
+            last_seen DATETIME NOT NULL
+        );
+        INSERT OR IGNORE INTO endpoints (url, name, first_seen, last_seen)
+        SELECT url, name, MIN(timestamp), MAX(timestamp)
+        FROM health_checks
+        GROUP BY url;
+    `)
+	return err
+}
+
+// migrateDropNameIndex removes the index on check names. Endpoints are
+// identified by URL and nothing queries by name, which need not be unique.
+func migrateDropNameIndex(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP INDEX IF EXISTS idx_name")
+	return err
+}
+
+// migrateAddIPVersion adds the column holding the address family each check
+// connected over
+func migrateAddIPVersion(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN ip_version TEXT")
+	return err
+}
+
+// migrateAddDetail adds the column holding supplementary check output
+func migrateAddDetail(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN detail TEXT")
+	return err
+}
+
+// migrateEvents adds the log of monitord's own lifecycle events
+func migrateEvents(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            timestamp DATETIME NOT NULL,
+            type TEXT NOT NULL,
+            url TEXT,
+            message TEXT NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
+    `)
+	return err
+}
+
+// migrateAddBodySizes adds the columns holding response body sizes before
+// and after decompression
+func migrateAddBodySizes(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN body_size INTEGER"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN decoded_body_size INTEGER")
+	return err
+}
+
+// migrateAddFailureDetail adds the columns holding the body snippet and
+// timing breakdown stored for failed checks
+func migrateAddFailureDetail(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN body_snippet TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN timing TEXT")
+	return err
+}
+
+// migrateAddReason adds the column explaining why a check was given a status
+// other than UP. Older rows keep such explanations in the error column.
+func migrateAddReason(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN reason TEXT")
+	return err
+}
+
+// migrateResponseTimeRollups adds the hourly and daily response-time
+// aggregates, with a histogram per bucket for percentiles. Checks stored
+// before it are only counted once the rollups are rebuilt.
+func migrateResponseTimeRollups(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS response_time_rollups (
+            period TEXT NOT NULL,
+            url TEXT NOT NULL,
+            probe TEXT NOT NULL,
+            bucket_start DATETIME NOT NULL,
+            count INTEGER NOT NULL,
+            sum_ms INTEGER NOT NULL,
+            min_ms INTEGER NOT NULL,
+            max_ms INTEGER NOT NULL,
+            PRIMARY KEY (period, url, probe, bucket_start)
+        );
+        CREATE TABLE IF NOT EXISTS response_time_bins (
+            period TEXT NOT NULL,
+            url TEXT NOT NULL,
+            probe TEXT NOT NULL,
+            bucket_start DATETIME NOT NULL,
+            bin INTEGER NOT NULL,
+            count INTEGER NOT NULL,
+            PRIMARY KEY (period, url, probe, bucket_start, bin)
+        );
+        CREATE INDEX IF NOT EXISTS idx_response_time_rollups_bucket ON response_time_rollups(bucket_start);
+        CREATE INDEX IF NOT EXISTS idx_response_time_bins_bucket ON response_time_bins(bucket_start);
+    `)
+	return err
+}
+
+// migrateAddRemoteAddress adds the columns holding the address a check
+// connected to and its network and location
+func migrateAddRemoteAddress(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN remote_ip TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN geo TEXT")
+	return err
+}
+
+// migrateAddComponents stores the sub-component checks of health documents
+func migrateAddComponents(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN components TEXT")
+	return err
+}
+
+// migrateEpochTimestamps stores check timestamps as integer Unix epoch
+// milliseconds instead of DATETIME text, so range scans compare numbers
+// regardless of the time zone a timestamp was written in
+func migrateEpochTimestamps(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        ALTER TABLE health_checks ADD COLUMN timestamp_ms INTEGER NOT NULL DEFAULT 0;
+        UPDATE health_checks
+            SET timestamp_ms = CAST(ROUND((julianday(timestamp) - 2440587.5) * 86400000) AS INTEGER);
+        DROP INDEX IF EXISTS idx_url_timestamp;
+        ALTER TABLE health_checks DROP COLUMN timestamp;
+        ALTER TABLE health_checks RENAME COLUMN timestamp_ms TO timestamp;
+        CREATE INDEX IF NOT EXISTS idx_url_timestamp ON health_checks(url, timestamp);
+        CREATE INDEX IF NOT EXISTS idx_health_checks_timestamp ON health_checks(timestamp);
+    `)
+	return err
+}
+
+// migrateEndpointOverrides adds the enabled states set by operators through
+// the API, so they survive restarts
+func migrateEndpointOverrides(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS endpoint_overrides (
+            url TEXT PRIMARY KEY,
+            enabled BOOLEAN NOT NULL,
+            config_enabled BOOLEAN NOT NULL,
+            updated DATETIME NOT NULL
+        )
+    `)
+	return err
+}
+
+// migrateAddRedirects stores the redirect chain followed by each check
+func migrateAddRedirects(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN redirects TEXT")
+	return err
+}
+
+// migrateAddCompressed adds the flags marking which text columns of a check
+// hold gzip data
+func migrateAddCompressed(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN compressed INTEGER NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateAcknowledgements adds the incidents acknowledged by operators, so
+// paused escalations stay paused across restarts
+func migrateAcknowledgements(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS acknowledgements (
+            url TEXT PRIMARY KEY,
+            acknowledged_by TEXT NOT NULL,
+            note TEXT NOT NULL,
+            acknowledged_at DATETIME NOT NULL,
+            expires DATETIME
+        )
+    `)
+	return err
+}
+
+// migrateResponseTimeMicros stores response times in microseconds alongside
+// the milliseconds. Earlier checks get their milliseconds scaled up.
+func migrateResponseTimeMicros(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        ALTER TABLE health_checks ADD COLUMN response_time_us INTEGER NOT NULL DEFAULT 0;
+        UPDATE health_checks SET response_time_us = COALESCE(response_time, 0) * 1000;
+    `)
+	return err
+}
+
+// migrateAddSteps stores the step results of transaction checks
+func migrateAddSteps(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN steps TEXT")
+	return err
+}
+
+// migrateEndpointRemovedAt records when an endpoint was found missing from
+// the config, so its data can be purged once it has been gone long enough
+func migrateEndpointRemovedAt(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE endpoints ADD COLUMN removed_at DATETIME")
+	return err
+}
+
+// migrateAddRepeats counts the UP checks store_on_change_only leaves out
+// with the check stored after them, so uptime still covers them
+func migrateAddRepeats(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE health_checks ADD COLUMN repeats INTEGER NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateEpochEventTimestamps stores event timestamps as integer Unix epoch
+// milliseconds, as migrateEpochTimestamps does for checks
+func migrateEpochEventTimestamps(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        ALTER TABLE events ADD COLUMN timestamp_ms INTEGER NOT NULL DEFAULT 0;
+        UPDATE events
+            SET timestamp_ms = CAST(ROUND((julianday(timestamp) - 2440587.5) * 86400000) AS INTEGER);
+        DROP INDEX IF EXISTS idx_events_timestamp;
+        ALTER TABLE events DROP COLUMN timestamp;
+        ALTER TABLE events RENAME COLUMN timestamp_ms TO timestamp;
+        CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
+    `)
+	return err
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// MultiStore wraps a primary Storage and also writes every check and event
+// to further backends. Queries are answered by the primary alone.
+type MultiStore struct {
+	Storage
+	backends []Backend
+	policy   string
+	logger   *log.Logger
+}
+
+// Backend is a further store a MultiStore writes to, named for its errors
+type Backend struct {
+	Name  string
+	Store monitor.Storage
+}
+
+// NewMultiStore writes to primary and every backend. With the best_effort
+// policy a backend that fails to save is logged and the save succeeds when
+// the primary's does; with require_all any failure fails the save.
+func NewMultiStore(primary Storage, backends []Backend, policy string, logger *log.Logger) *MultiStore {
+	return &MultiStore{Storage: primary, backends: backends, policy: policy, logger: logger}
+}
+
+// SaveCheck saves the check to the primary and every backend at once, so a
+// slow backend does not add to the others' time
+func (s *MultiStore) SaveCheck(check monitor.HealthCheck) error {
+	return s.fanOut("check for "+check.URL, func(store monitor.Storage) error {
+		return store.SaveCheck(check)
+	})
+}
+
+// SaveEvent saves the event to the primary and every backend
+func (s *MultiStore) SaveEvent(event monitor.Event) error {
+	return s.fanOut("event", func(store monitor.Storage) error {
+		return store.SaveEvent(event)
+	})
+}
+
+// fanOut runs save against the primary and the backends concurrently and
+// applies the policy to the errors. what describes the saved item in logs.
+func (s *MultiStore) fanOut(what string, save func(store monitor.Storage) error) error {
+	errs := make([]error, len(s.backends))
+	var wg sync.WaitGroup
+	for i, backend := range s.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := save(backend.Store); err != nil {
+				errs[i] = fmt.Errorf("backend %s: %w", backend.Name, err)
+			}
+		}()
+	}
+	primaryErr := save(s.Storage)
+	wg.Wait()
+
+	if s.policy == config.BackendRequireAll {
+		return errors.Join(append([]error{primaryErr}, errs...)...)
+	}
+	for _, err := range errs {
+		if err != nil {
+			s.logger.Printf("Error saving %s: %v", what, err)
+		}
+	}
+	return primaryErr
+}
+
+// Close closes the primary and every backend
+func (s *MultiStore) Close() error {
+	errs := []error{s.Storage.Close()}
+	for _, backend := range s.backends {
+		errs = append(errs, backend.Store.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/notify"
+)
+
+// EnqueueNotification stores a notification until it has been delivered
+func (s *SQLiteStore) EnqueueNotification(p notify.Pending) error {
+	payload, err := json.Marshal(p.Notification)
+	if err != nil {
+		return err
+	}
+
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec(`
+            INSERT INTO pending_notifications (notifier, payload, created_at, attempts, next_attempt, last_error)
+            VALUES (?, ?, ?, ?, ?, ?)`,
+			p.Notifier,
+			string(payload),
+			p.CreatedAt,
+			p.Attempts,
+			p.NextAttempt,
+			p.LastError,
+		)
+		return err
+	})
+}
+
+// DueNotifications returns pending notifications whose next attempt is due,
+// oldest first
+func (s *SQLiteStore) DueNotifications(now time.Time, limit int) ([]notify.Pending, error) {
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query(`
+        SELECT id, notifier, payload, created_at, attempts, next_attempt, last_error
+        FROM pending_notifications
+        WHERE next_attempt <= ?
+        ORDER BY created_at ASC
+        LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanPending(rows)
+}
+
+// HeldNotifications returns the notifications for an endpoint that are
+// being held for a notifier until the given time and have not been
+// attempted, oldest first
+func (s *SQLiteStore) HeldNotifications(notifier, url string, until time.Time) ([]notify.Pending, error) {
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query(`
+        SELECT id, notifier, payload, created_at, attempts, next_attempt, last_error
+        FROM pending_notifications
+        WHERE notifier = ? AND attempts = 0 AND next_attempt >= ?
+            AND json_extract(payload, '$.url') = ?
+        ORDER BY created_at ASC`, notifier, until, url)
+	if err != nil {
+		return nil, err
+	}
+	return scanPending(rows)
+}
+
+// scanPending reads pending notification rows and closes them
+func scanPending(rows *sql.Rows) ([]notify.Pending, error) {
+	defer rows.Close()
+
+	var pending []notify.Pending
+	for rows.Next() {
+		var (
+			p         notify.Pending
+			payload   string
+			lastError sql.NullString
+		)
+		if err := rows.Scan(&p.ID, &p.Notifier, &payload, &p.CreatedAt, &p.Attempts, &p.NextAttempt, &lastError); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(payload), &p.Notification); err != nil {
+			return nil, err
+		}
+		p.LastError = lastError.String
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// UpdateNotification records a failed delivery attempt
+func (s *SQLiteStore) UpdateNotification(p notify.Pending) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec(`
+            UPDATE pending_notifications
+            SET attempts = ?, next_attempt = ?, last_error = ?
+            WHERE id = ?`,
+			p.Attempts,
+			p.NextAttempt,
+			p.LastError,
+			p.ID,
+		)
+		return err
+	})
+}
+
+// DeleteNotification removes a delivered or abandoned notification
+func (s *SQLiteStore) DeleteNotification(id int64) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec("DELETE FROM pending_notifications WHERE id = ?", id)
+		return err
+	})
+}
+
+// PendingNotifications returns the number of notifications awaiting delivery
+func (s *SQLiteStore) PendingNotifications() (int, error) {
+	var count int
+	db, release := s.conn()
+	defer release()
+	err := db.QueryRow("SELECT COUNT(*) FROM pending_notifications").Scan(&count)
+	return count, err
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// EndpointOverrides returns the enabled states set by operators
+func (s *SQLiteStore) EndpointOverrides() ([]monitor.EndpointOverride, error) {
+	db, release := s.conn()
+	defer release()
+	return queryEndpointOverrides(db)
+}
+
+func queryEndpointOverrides(db *sql.DB) ([]monitor.EndpointOverride, error) {
+	rows, err := db.Query("SELECT url, enabled, config_enabled, updated FROM endpoint_overrides ORDER BY url")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []monitor.EndpointOverride
+	for rows.Next() {
+		var o monitor.EndpointOverride
+		if err := rows.Scan(&o.URL, &o.Enabled, &o.ConfigEnabled, &o.Updated); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SaveEndpointOverride records an operator's enabled state for an endpoint,
+// replacing any earlier one
+func (s *SQLiteStore) SaveEndpointOverride(override monitor.EndpointOverride) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		return saveEndpointOverride(db, override)
+	})
+}
+
+func saveEndpointOverride(db *sql.DB, o monitor.EndpointOverride) error {
+	_, err := db.Exec(`
+        INSERT INTO endpoint_overrides (url, enabled, config_enabled, updated)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (url) DO UPDATE SET
+            enabled = excluded.enabled,
+            config_enabled = excluded.config_enabled,
+            updated = excluded.updated`,
+		o.URL, o.Enabled, o.ConfigEnabled, o.Updated)
+	return err
+}
+
+// DeleteEndpointOverride removes the operator's enabled state for an endpoint
+func (s *SQLiteStore) DeleteEndpointOverride(url string) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec("DELETE FROM endpoint_overrides WHERE url = ?", url)
+		return err
+	})
+}
+
+// moveEndpointOverrides copies the operator overrides to a new database file
+// so they outlive a rollover
+func moveEndpointOverrides(from, to *sql.DB) error {
+	overrides, err := queryEndpointOverrides(from)
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		if err := saveEndpointOverride(to, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
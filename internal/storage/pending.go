@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+	"github.com/will-wright-eng/monitord/internal/notify"
+)
+
+// ErrUnavailable is returned by a PendingStore's queries until the database
+// is attached
+var ErrUnavailable = errors.New("database is not available yet")
+
+// PendingStore stands in for a database that could not be opened. Checks,
+// events and notifications are kept in memory, up to limit of each with the
+// oldest dropped beyond it, and written to the database when it is
+// attached; from then on everything goes straight to it.
+type PendingStore struct {
+	limit int
+
+	mu            sync.Mutex
+	store         Storage
+	queue         notify.Queue
+	checks        []monitor.HealthCheck
+	events        []monitor.Event
+	notifications []notify.Pending
+	// lastID numbers the notifications held in memory, counting down
+	// from -1 so they are never mistaken for queued rows
+	lastID  int64
+	dropped int
+}
+
+// NewPendingStore creates a pending store holding up to limit checks,
+// events and notifications each
+func NewPendingStore(limit int) *PendingStore {
+	return &PendingStore{limit: limit}
+}
+
+// checkBatcher is a Storage that can save several checks at once
+type checkBatcher interface {
+	SaveChecks(checks []monitor.HealthCheck) error
+}
+
+// Attach writes what was kept in memory to store, and queued notifications
+// to queue, then hands every later call on to them. If a write fails the
+// items not yet written stay in memory and Attach can be retried.
+func (s *PendingStore) Attach(store Storage, queue notify.Queue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if batcher, ok := store.(checkBatcher); ok && len(s.checks) > 0 {
+		if err := batcher.SaveChecks(s.checks); err != nil {
+			return err
+		}
+		s.checks = nil
+	}
+	for len(s.checks) > 0 {
+		if err := store.SaveCheck(s.checks[0]); err != nil {
+			return err
+		}
+		s.checks = s.checks[1:]
+	}
+	for len(s.events) > 0 {
+		if err := store.SaveEvent(s.events[0]); err != nil {
+			return err
+		}
+		s.events = s.events[1:]
+	}
+	for len(s.notifications) > 0 {
+		p := s.notifications[0]
+		p.ID = 0
+		if err := queue.EnqueueNotification(p); err != nil {
+			return err
+		}
+		s.notifications = s.notifications[1:]
+	}
+	s.checks, s.events, s.notifications = nil, nil, nil
+	s.store, s.queue = store, queue
+	return nil
+}
+
+// Available reports whether the database has been attached
+func (s *PendingStore) Available() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store != nil
+}
+
+// Buffered returns how many checks, events and notifications are waiting
+// in memory for the database
+func (s *PendingStore) Buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.checks) + len(s.events) + len(s.notifications)
+}
+
+// Dropped returns how many items were dropped because the buffer was full
+func (s *PendingStore) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// attached returns the database, or nil while it is unavailable
+func (s *PendingStore) attached() Storage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+// appendBounded appends item to buffer, dropping the oldest entry when the
+// buffer already holds limit. It reports whether one was dropped.
+func appendBounded[T any](buffer []T, item T, limit int) ([]T, bool) {
+	if len(buffer) < limit {
+		return append(buffer, item), false
+	}
+	return append(buffer[1:], item), true
+}
+
+// SaveCheck saves the check, or keeps it in memory until the database is
+// attached
+func (s *PendingStore) SaveCheck(check monitor.HealthCheck) error {
+	s.mu.Lock()
+	if store := s.store; store != nil {
+		s.mu.Unlock()
+		return store.SaveCheck(check)
+	}
+	defer s.mu.Unlock()
+	var dropped bool
+	if s.checks, dropped = appendBounded(s.checks, check, s.limit); dropped {
+		s.dropped++
+	}
+	return nil
+}
+
+// SaveEvent saves the event, or keeps it in memory until the database is
+// attached
+func (s *PendingStore) SaveEvent(event monitor.Event) error {
+	s.mu.Lock()
+	if store := s.store; store != nil {
+		s.mu.Unlock()
+		return store.SaveEvent(event)
+	}
+	defer s.mu.Unlock()
+	var dropped bool
+	if s.events, dropped = appendBounded(s.events, event, s.limit); dropped {
+		s.dropped++
+	}
+	return nil
+}
+
+// QueryChecks queries the database, failing with ErrUnavailable until it
+// is attached
+func (s *PendingStore) QueryChecks(filter CheckFilter) ([]monitor.HealthCheck, error) {
+	store := s.attached()
+	if store == nil {
+		return nil, ErrUnavailable
+	}
+	return store.QueryChecks(filter)
+}
+
+// StreamChecks streams checks from the database once attached
+func (s *PendingStore) StreamChecks(filter CheckFilter, fn func(monitor.HealthCheck) error) error {
+	store := s.attached()
+	if store == nil {
+		return ErrUnavailable
+	}
+	return store.StreamChecks(filter, fn)
+}
+
+// SummarizeChecks summarizes checks from the database once attached
+func (s *PendingStore) SummarizeChecks(filter CheckFilter) ([]CheckSummary, error) {
+	store := s.attached()
+	if store == nil {
+		return nil, ErrUnavailable
+	}
+	return store.SummarizeChecks(filter)
+}
+
+// ListEndpoints lists the database's endpoints once attached
+func (s *PendingStore) ListEndpoints() ([]EndpointRecord, error) {
+	store := s.attached()
+	if store == nil {
+		return nil, ErrUnavailable
+	}
+	return store.ListEndpoints()
+}
+
+// QueryEvents queries the database's events once attached
+func (s *PendingStore) QueryEvents(filter EventFilter) ([]monitor.Event, error) {
+	store := s.attached()
+	if store == nil {
+		return nil, ErrUnavailable
+	}
+	return store.QueryEvents(filter)
+}
+
+// Close closes the database once attached. Whatever is still in memory is
+// lost.
+func (s *PendingStore) Close() error {
+	store := s.attached()
+	if store == nil {
+		return nil
+	}
+	return store.Close()
+}
+
+// EnqueueNotification queues the notification, in memory until the
+// database is attached
+func (s *PendingStore) EnqueueNotification(p notify.Pending) error {
+	s.mu.Lock()
+	if queue := s.queue; queue != nil {
+		s.mu.Unlock()
+		return queue.EnqueueNotification(p)
+	}
+	defer s.mu.Unlock()
+	s.lastID--
+	p.ID = s.lastID
+	var dropped bool
+	if s.notifications, dropped = appendBounded(s.notifications, p, s.limit); dropped {
+		s.dropped++
+	}
+	return nil
+}
+
+// DueNotifications returns the notifications whose next attempt is due,
+// oldest first
+func (s *PendingStore) DueNotifications(now time.Time, limit int) ([]notify.Pending, error) {
+	s.mu.Lock()
+	if queue := s.queue; queue != nil {
+		s.mu.Unlock()
+		return queue.DueNotifications(now, limit)
+	}
+	defer s.mu.Unlock()
+	var due []notify.Pending
+	for _, p := range s.notifications {
+		if len(due) == limit {
+			break
+		}
+		if !p.NextAttempt.After(now) {
+			due = append(due, p)
+		}
+	}
+	return due, nil
+}
+
+// HeldNotifications returns the notifications for an endpoint held for a
+// notifier until the given time and not yet attempted, oldest first
+func (s *PendingStore) HeldNotifications(notifier, url string, until time.Time) ([]notify.Pending, error) {
+	s.mu.Lock()
+	if queue := s.queue; queue != nil {
+		s.mu.Unlock()
+		return queue.HeldNotifications(notifier, url, until)
+	}
+	defer s.mu.Unlock()
+	var held []notify.Pending
+	for _, p := range s.notifications {
+		if p.Notifier == notifier && p.Attempts == 0 && !p.NextAttempt.Before(until) && p.Notification.URL == url {
+			held = append(held, p)
+		}
+	}
+	return held, nil
+}
+
+// UpdateNotification records a failed delivery attempt. A notification
+// taken from memory before the database was attached has been moved to it,
+// so the attempt is not recorded there.
+func (s *PendingStore) UpdateNotification(p notify.Pending) error {
+	s.mu.Lock()
+	if p.ID > 0 {
+		queue := s.queue
+		s.mu.Unlock()
+		return queue.UpdateNotification(p)
+	}
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].ID == p.ID {
+			s.notifications[i] = p
+		}
+	}
+	return nil
+}
+
+// DeleteNotification removes a delivered or abandoned notification. One
+// delivered from memory while being moved to the database stays queued
+// there and is sent again.
+func (s *PendingStore) DeleteNotification(id int64) error {
+	s.mu.Lock()
+	if id > 0 {
+		queue := s.queue
+		s.mu.Unlock()
+		return queue.DeleteNotification(id)
+	}
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].ID == id {
+			s.notifications = append(s.notifications[:i], s.notifications[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// PendingNotifications returns the number of notifications awaiting
+// delivery
+func (s *PendingStore) PendingNotifications() (int, error) {
+	s.mu.Lock()
+	if queue := s.queue; queue != nil {
+		s.mu.Unlock()
+		return queue.PendingNotifications()
+	}
+	defer s.mu.Unlock()
+	return len(s.notifications), nil
+}
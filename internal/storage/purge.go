@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// purgedTables are the tables, other than the checks, holding data of an
+// endpoint by URL
+var purgedTables = []string{
+	"response_time_rollups",
+	"response_time_bins",
+	"events",
+	"endpoint_overrides",
+	"acknowledgements",
+	"endpoints",
+}
+
+// DeleteEndpointData deletes everything stored about an endpoint: its
+// checks, response-time rollups, events, operator override, acknowledgement
+// and registry entry. It returns how many checks were deleted. The space is
+// only returned to the filesystem by Vacuum.
+func (s *SQLiteStore) DeleteEndpointData(url string) (int64, error) {
+	var deleted int64
+	err := s.withReconnect(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// The checks' tags are deleted with them through the foreign key
+		result, err := tx.Exec("DELETE FROM health_checks WHERE url = ?", url)
+		if err != nil {
+			return err
+		}
+		if deleted, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		for _, table := range purgedTables {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE url = ?", url); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	return deleted, err
+}
+
+// MarkRemovedEndpoints records when endpoints in the registry were first
+// found missing from configured, and forgets it for those back in it
+func (s *SQLiteStore) MarkRemovedEndpoints(configured []string, now time.Time) error {
+	present := make(map[string]bool, len(configured))
+	for _, url := range configured {
+		present[url] = true
+	}
+	records, err := s.ListEndpoints()
+	if err != nil {
+		return err
+	}
+	return s.withReconnect(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for _, r := range records {
+			switch {
+			case present[r.URL] && r.RemovedAt != nil:
+				_, err = tx.Exec("UPDATE endpoints SET removed_at = NULL WHERE url = ?", r.URL)
+			case !present[r.URL] && r.RemovedAt == nil:
+				_, err = tx.Exec("UPDATE endpoints SET removed_at = ? WHERE url = ?", now, r.URL)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// RemovedEndpoints returns the endpoints found missing from the config
+// before the given time, ordered by URL
+func (s *SQLiteStore) RemovedEndpoints(before time.Time) ([]EndpointRecord, error) {
+	records, err := s.ListEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	var removed []EndpointRecord
+	for _, r := range records {
+		if r.RemovedAt != nil && r.RemovedAt.Before(before) {
+			removed = append(removed, r)
+		}
+	}
+	return removed, nil
+}
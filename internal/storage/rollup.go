@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"database/sql"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Rollup periods: every check with a response is added to the hourly and the
+// daily rollup of its endpoint and probe
+const (
+	rollupHour = "hour"
+	rollupDay  = "day"
+)
+
+var rollupPeriods = []string{rollupHour, rollupDay}
+
+// rollupBounds are the inclusive upper bounds, in milliseconds, of the
+// histogram bins kept with each rollup. A final bin holds slower responses.
+var rollupBounds = []int64{5, 10, 25, 50, 75, 100, 150, 200, 300, 400, 500, 750,
+	1000, 1500, 2000, 3000, 5000, 7500, 10000, 20000, 30000, 60000}
+
+// rollupEnd is the bucket bound used for an open-ended query
+var rollupEnd = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// rollupBin returns the histogram bin of a response time
+func rollupBin(ms int64) int {
+	bin, _ := slices.BinarySearch(rollupBounds, ms)
+	return bin
+}
+
+// bucketStart returns the start of the period containing t, in UTC
+func bucketStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	if period == rollupHour {
+		return t.Truncate(time.Hour)
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// hasResponse reports whether a check is counted in the rollups, matching the
+// checks SummarizeChecks averages
+func hasResponse(check monitor.HealthCheck) bool {
+	return check.StatusCode > 0
+}
+
+// saveRollups adds a check to its hourly and daily rollups
+func saveRollups(tx *sql.Tx, check monitor.HealthCheck) error {
+	if !hasResponse(check) {
+		return nil
+	}
+	bin := rollupBin(check.ResponseTime)
+	for _, period := range rollupPeriods {
+		start := bucketStart(period, check.Timestamp)
+		if err := addRollup(tx, period, check.URL, check.Probe, start, rollup{
+			count: 1,
+			sum:   check.ResponseTime,
+			min:   check.ResponseTime,
+			max:   check.ResponseTime,
+			bins:  map[int]int64{bin: 1},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollup aggregates the response times in one bucket
+type rollup struct {
+	count, sum, min, max int64
+	bins                 map[int]int64
+}
+
+// add counts one response time
+func (r *rollup) add(ms int64) {
+	if r.count == 0 || ms < r.min {
+		r.min = ms
+	}
+	if r.count == 0 || ms > r.max {
+		r.max = ms
+	}
+	r.count++
+	r.sum += ms
+	r.bins[rollupBin(ms)]++
+}
+
+// addRollup merges r into the stored rollup of a bucket
+func addRollup(tx *sql.Tx, period, url, probe string, start time.Time, r rollup) error {
+	if _, err := tx.Exec(`
+        INSERT INTO response_time_rollups (period, url, probe, bucket_start, count, sum_ms, min_ms, max_ms)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (period, url, probe, bucket_start) DO UPDATE SET
+            count = count + excluded.count,
+            sum_ms = sum_ms + excluded.sum_ms,
+            min_ms = MIN(min_ms, excluded.min_ms),
+            max_ms = MAX(max_ms, excluded.max_ms)`,
+		period, url, probe, start, r.count, r.sum, r.min, r.max); err != nil {
+		return err
+	}
+	for bin, count := range r.bins {
+		if _, err := tx.Exec(`
+            INSERT INTO response_time_bins (period, url, probe, bucket_start, bin, count)
+            VALUES (?, ?, ?, ?, ?, ?)
+            ON CONFLICT (period, url, probe, bucket_start, bin) DO UPDATE SET count = count + excluded.count`,
+			period, url, probe, start, bin, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupKey identifies a bucket while rollups are rebuilt
+type rollupKey struct {
+	period, url, probe string
+	start              time.Time
+}
+
+// RebuildRollups recomputes the response-time rollups from the stored checks
+// at or after since, widened to the start of its day, or from every check
+// when since is zero. It returns how many checks were counted. Checks being
+// saved meanwhile wait for it to finish.
+func (s *SQLiteStore) RebuildRollups(since time.Time) (int, error) {
+	from := bucketStart(rollupDay, since)
+	if since.IsZero() {
+		from = time.Time{}
+	}
+
+	var counted int
+	err := s.withReconnect(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// Deleting first takes the write lock, so no check is saved between
+		// reading the checks and writing their rollups
+		for _, table := range []string{"response_time_rollups", "response_time_bins"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE bucket_start >= ?", from); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.Query(`
+            SELECT url, COALESCE(probe, ''), response_time, timestamp
+            FROM health_checks
+            WHERE status_code > 0 AND timestamp >= ?`, from.UnixMilli())
+		if err != nil {
+			return err
+		}
+		rollups := make(map[rollupKey]*rollup)
+		counted = 0
+		for rows.Next() {
+			var (
+				url, probe   string
+				responseTime int64
+				timestamp    int64
+			)
+			if err := rows.Scan(&url, &probe, &responseTime, &timestamp); err != nil {
+				rows.Close()
+				return err
+			}
+			checked := time.UnixMilli(timestamp).UTC()
+			for _, period := range rollupPeriods {
+				key := rollupKey{period, url, probe, bucketStart(period, checked)}
+				r, ok := rollups[key]
+				if !ok {
+					r = &rollup{bins: make(map[int]int64)}
+					rollups[key] = r
+				}
+				r.add(responseTime)
+			}
+			counted++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for key, r := range rollups {
+			if err := addRollup(tx, key.period, key.url, key.probe, key.start, *r); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	return counted, err
+}
+
+// ResponseTimeStats summarises response times per probe and endpoint, ordered
+// by URL, from the rollups rather than the stored checks. Whole days in the
+// window are read from the daily rollups and the hours at either end from the
+// hourly ones.
+func (s *SQLiteStore) ResponseTimeStats(filter StatsFilter) ([]ResponseTimeStats, error) {
+	where, args := filter.whereClause()
+	db, release := s.conn()
+	defer release()
+
+	rows, err := db.Query(`
+        SELECT url, probe, SUM(count), SUM(sum_ms), MIN(min_ms), MAX(max_ms)
+        FROM response_time_rollups`+where+`
+        GROUP BY url, probe ORDER BY url, probe`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ResponseTimeStats
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var (
+			st  ResponseTimeStats
+			sum int64
+		)
+		if err := rows.Scan(&st.URL, &st.Probe, &st.Count, &sum, &st.Min, &st.Max); err != nil {
+			return nil, err
+		}
+		st.Mean = float64(sum) / float64(st.Count)
+		index[[2]string{st.URL, st.Probe}] = len(stats)
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bins := make([]map[int]int64, len(stats))
+	binRows, err := db.Query(`
+        SELECT url, probe, bin, SUM(count)
+        FROM response_time_bins`+where+`
+        GROUP BY url, probe, bin`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer binRows.Close()
+	for binRows.Next() {
+		var (
+			url, probe string
+			bin        int
+			count      int64
+		)
+		if err := binRows.Scan(&url, &probe, &bin, &count); err != nil {
+			return nil, err
+		}
+		i, ok := index[[2]string{url, probe}]
+		if !ok {
+			continue
+		}
+		if bins[i] == nil {
+			bins[i] = make(map[int]int64)
+		}
+		bins[i][bin] = count
+	}
+	if err := binRows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats {
+		st := &stats[i]
+		st.P50 = percentile(bins[i], *st, 0.50)
+		st.P90 = percentile(bins[i], *st, 0.90)
+		st.P95 = percentile(bins[i], *st, 0.95)
+		st.P99 = percentile(bins[i], *st, 0.99)
+	}
+	return stats, nil
+}
+
+// whereClause selects the rollups covering the filter's window: daily
+// rollups for the days it spans completely and hourly ones for the rest
+func (f StatsFilter) whereClause() (string, []interface{}) {
+	lo := bucketStart(rollupHour, f.Since)
+	if f.Since.IsZero() {
+		lo = time.Time{}
+	}
+	hi := rollupEnd
+	if !f.Until.IsZero() {
+		hi = bucketStart(rollupHour, f.Until)
+		if hi.Before(f.Until) {
+			hi = hi.Add(time.Hour)
+		}
+	}
+	dayLo := bucketStart(rollupDay, lo)
+	if dayLo.Before(lo) {
+		dayLo = dayLo.AddDate(0, 0, 1)
+	}
+	dayHi := bucketStart(rollupDay, hi)
+
+	var (
+		window string
+		args   []interface{}
+	)
+	if dayLo.Before(dayHi) {
+		window = `((period = ? AND bucket_start >= ? AND bucket_start < ?) OR
+            (period = ? AND ((bucket_start >= ? AND bucket_start < ?) OR (bucket_start >= ? AND bucket_start < ?))))`
+		args = append(args, rollupDay, dayLo, dayHi, rollupHour, lo, dayLo, dayHi, hi)
+	} else {
+		window = "(period = ? AND bucket_start >= ? AND bucket_start < ?)"
+		args = append(args, rollupHour, lo, hi)
+	}
+
+	conditions := []string{window}
+	if f.URL != "" {
+		conditions = append(conditions, "url = ?")
+		args = append(args, f.URL)
+	}
+	if f.Probe != "" {
+		conditions = append(conditions, "probe = ?")
+		args = append(args, f.Probe)
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// percentile estimates the q-th quantile from a histogram, interpolating
+// within the bin it falls in and clamping to the observed range
+func percentile(bins map[int]int64, st ResponseTimeStats, q float64) float64 {
+	rank := q * float64(st.Count)
+	var seen int64
+	for bin := 0; bin <= len(rollupBounds); bin++ {
+		n := bins[bin]
+		if n == 0 {
+			continue
+		}
+		if float64(seen+n) >= rank {
+			lower, upper := float64(0), float64(st.Max)
+			if bin > 0 {
+				lower = float64(rollupBounds[bin-1])
+			}
+			if bin < len(rollupBounds) {
+				upper = float64(rollupBounds[bin])
+			}
+			lower = math.Max(lower, float64(st.Min))
+			upper = math.Min(upper, float64(st.Max))
+			return lower + (upper-lower)*(rank-float64(seen))/float64(n)
+		}
+		seen += n
+	}
+	return float64(st.Max)
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// pathPlaceholders maps the time placeholders accepted in database paths to
+// Go time layouts
+var pathPlaceholders = strings.NewReplacer(
+	"%Y", "2006",
+	"%m", "01",
+	"%d", "02",
+	"%%", "%",
+)
+
+// isPathTemplate reports whether a database path contains time placeholders
+func isPathTemplate(path string) bool {
+	return pathPlaceholders.Replace(path) != path
+}
+
+// expandPath fills in the time placeholders of a database path, e.g.
+// "monitord-%Y%m.db" becomes "monitord-202411.db" in November 2024
+func expandPath(template string, t time.Time) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(template, '%')
+		if i < 0 || i == len(template)-1 {
+			b.WriteString(template)
+			return b.String()
+		}
+		b.WriteString(template[:i])
+		switch token := template[i : i+2]; token {
+		case "%%":
+			b.WriteByte('%')
+		case "%Y", "%m", "%d":
+			b.WriteString(t.Format(pathPlaceholders.Replace(token)))
+		default:
+			b.WriteString(token)
+		}
+		template = template[i+2:]
+	}
+}
+
+// rotate switches to a new database file when the path template expands to a
+// different file than the one open. Notifications still awaiting delivery,
+// operator overrides and acknowledgements in effect are carried over so they
+// are not stranded in the archived file. If the new file cannot be opened
+// the current one stays in use and the rollover is retried with the
+// reconnect backoff.
+func (s *SQLiteStore) rotate(now time.Time) {
+	if s.template == "" {
+		return
+	}
+	path := expandPath(s.template, now)
+
+	s.mu.RLock()
+	current := s.path == path
+	s.mu.RUnlock()
+	if current {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == path || now.Before(s.nextReopen) {
+		return
+	}
+
+	db, err := openDatabase(path, s.fileMode)
+	if err == nil {
+		// Overrides and acknowledgements are only copied, so they go
+		// first: moving the notifications removes them from the current
+		// file
+		err = moveEndpointOverrides(s.db.db, db)
+		if err == nil {
+			err = moveAcknowledgements(s.db.db, db, now)
+		}
+		if err == nil {
+			err = movePendingNotifications(s.db.db, db)
+		}
+		if err != nil {
+			db.Close()
+		}
+	}
+	if err != nil {
+		if s.backoff == 0 {
+			s.backoff = minReconnectBackoff
+		} else if s.backoff *= 2; s.backoff > maxReconnectBackoff {
+			s.backoff = maxReconnectBackoff
+		}
+		s.nextReopen = now.Add(s.backoff)
+		return
+	}
+
+	finished, onRollover := s.path, s.onRollover
+	s.replace(db, func() {
+		if onRollover != nil {
+			onRollover(finished)
+		}
+	})
+	s.path = path
+	s.backoff = 0
+	s.nextReopen = time.Time{}
+}
+
+// SetRolloverHook has fn told of the file the store rolls over from, once
+// the last query using it is done and it is closed, so it can be archived.
+// fn runs in its own goroutine, which Close waits for.
+func (s *SQLiteStore) SetRolloverHook(fn func(path string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRollover = fn
+}
+
+// movePendingNotifications transfers the notification queue between database
+// files. IDs are kept where free so deliveries already in progress still
+// update the right rows.
+func movePendingNotifications(from, to *sql.DB) error {
+	rows, err := from.Query(`
+        SELECT id, notifier, payload, created_at, attempts, next_attempt, last_error
+        FROM pending_notifications`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tx, err := to.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	for rows.Next() {
+		var (
+			id, attempts           int64
+			notifier, payload      string
+			createdAt, nextAttempt time.Time
+			lastError              sql.NullString
+		)
+		if err := rows.Scan(&id, &notifier, &payload, &createdAt, &attempts, &nextAttempt, &lastError); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+            INSERT INTO pending_notifications (id, notifier, payload, created_at, attempts, next_attempt, last_error)
+            VALUES ((SELECT CASE WHEN EXISTS (SELECT 1 FROM pending_notifications WHERE id = ?1) THEN NULL ELSE ?1 END),
+                ?, ?, ?, ?, ?, ?)`,
+			id, notifier, payload, createdAt, attempts, nextAttempt, lastError); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The old file is only kept as an archive, so leftover rows are harmless
+	for _, id := range ids {
+		from.Exec("DELETE FROM pending_notifications WHERE id = ?", id)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRotateWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStore(filepath.Join(dir, "monitord-%Y%m.db"), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer store.Close()
+	first := store.Path()
+	var clock atomic.Int64
+	clock.Store(time.Now().UnixNano())
+	store.now = func() time.Time { return time.Unix(0, clock.Load()) }
+
+	rolledOver := make(chan string, 1)
+	store.SetRolloverHook(func(path string) { rolledOver <- path })
+
+	// The clock moves to next month halfway through the saves
+	const workers, saves = 4, 50
+	var saved atomic.Int64
+	next := time.Now().AddDate(0, 1, 0).UnixNano()
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*saves)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := fmt.Sprintf("https://example.com/%d", w)
+			for i := 0; i < saves; i++ {
+				if err := store.SaveCheck(testCheck(url, time.Now())); err != nil {
+					errs <- err
+				}
+				if saved.Add(1) == workers*saves/2 {
+					clock.Store(next)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("SaveCheck across the rollover: %v", err)
+	}
+
+	if store.Path() == first {
+		t.Fatalf("store did not roll over from %s", first)
+	}
+	select {
+	case path := <-rolledOver:
+		if path != first {
+			t.Errorf("rollover hook got %s, want %s", path, first)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("rollover hook was not called")
+	}
+
+	// Every check landed in one file or the other
+	previous, err := OpenArchive(first)
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+	defer previous.Close()
+	before, err := previous.QueryChecks(CheckFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := store.QueryChecks(CheckFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) == 0 || len(after) == 0 {
+		t.Errorf("stored %d checks before the rollover and %d after, want some in each", len(before), len(after))
+	}
+	if got := len(before) + len(after); got != workers*saves {
+		t.Errorf("stored %d checks across both files, want %d", got, workers*saves)
+	}
+}
+
+func TestRolloverWaitsForHandleInUse(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "monitord-%Y%m.db"), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer store.Close()
+	rolledOver := make(chan string, 1)
+	store.SetRolloverHook(func(path string) { rolledOver <- path })
+
+	db, release := store.conn()
+	store.rotate(time.Now().AddDate(0, 1, 0))
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM health_checks").Scan(&count); err != nil {
+		t.Fatalf("query on the file rolled over from: %v", err)
+	}
+	select {
+	case path := <-rolledOver:
+		t.Fatalf("rollover hook got %s while the file was still in use", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-rolledOver:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rollover hook was not called once the file was released")
+	}
+}
@@ -2,26 +2,102 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/will-wright-eng/monitord/internal/monitor"
 )
 
+// Reconnect backoff bounds used after the database connection breaks
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 5 * time.Minute
+)
+
 type SQLiteStore struct {
-	db *sql.DB
+	// template is the configured path when it contains time placeholders;
+	// path is the file currently open
+	template string
+	path     string
+	fileMode os.FileMode
+	// compressOver is the length in bytes over which text columns are
+	// compressed; zero stores them as text
+	compressOver int
+	// temporary is set when path is a copy to remove on Close
+	temporary bool
+
+	mu         sync.RWMutex
+	db         *handle
+	backoff    time.Duration
+	nextReopen time.Time
+	reconnects atomic.Int64
+	// onRollover is told of each file the store rolls over from
+	onRollover func(path string)
+	// retiring counts replaced handles not closed yet
+	retiring sync.WaitGroup
+	// now tells the time that picks the file of a path template
+	now func() time.Time
+}
+
+// handle is a database shared by the store's callers, each holding it from
+// acquire until release. A handle replaced by a reopen or a rollover is only
+// closed once its last user is done, so no query has the database closed
+// underneath it.
+type handle struct {
+	db    *sql.DB
+	users sync.WaitGroup
+}
+
+func (h *handle) release() {
+	h.users.Done()
 }
 
-func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+// NewSQLiteStore opens the database at dbPath. A path containing %Y, %m or
+// %d placeholders names one file per period, and the store rolls over to the
+// next file when the period changes. Database files and directories the store
+// creates get fileMode, or the defaults when it is 0.
+func NewSQLiteStore(dbPath string, fileMode os.FileMode) (*SQLiteStore, error) {
+	s := &SQLiteStore{path: dbPath, fileMode: fileMode, now: time.Now}
+	if isPathTemplate(dbPath) {
+		s.template = dbPath
+		s.path = expandPath(dbPath, s.now())
+	}
+
+	db, err := openDatabase(s.path, s.fileMode)
+	if err != nil {
+		return nil, err
+	}
+	s.db = &handle{db: db}
+	return s, nil
+}
+
+// openDatabase opens the database file and brings its schema up to date.
+// dbPath may end in driver parameters, such as ?_busy_timeout=5000.
+func openDatabase(dbPath string, mode os.FileMode) (*sql.DB, error) {
+	file, params, _ := strings.Cut(dbPath, "?")
 	// Create the directory path if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(file), dirMode(mode)); err != nil {
 		return nil, err
 	}
+	if mode != 0 {
+		if err := createFile(file, mode); err != nil {
+			return nil, err
+		}
+	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	if params != "" {
+		params += "&"
+	}
+	db, err := sql.Open("sqlite3", file+"?"+params+"_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
@@ -33,9 +109,134 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		db.Close()
 		return nil, err
 	}
-	return &SQLiteStore{db: db}, nil
+	return db, nil
+}
+
+// acquire returns the current handle, rolling over to a new file first if
+// the path template calls for one. The caller must release it when done.
+func (s *SQLiteStore) acquire() *handle {
+	if s.template != "" {
+		s.rotate(s.now())
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.db.users.Add(1)
+	return s.db
+}
+
+// conn returns the current database for the caller to use until it calls
+// release
+func (s *SQLiteStore) conn() (*sql.DB, func()) {
+	h := s.acquire()
+	return h.db, h.release
+}
+
+// replace makes db the current database. The handle it replaces is closed
+// in the background once no longer in use, after which closed is called if
+// set. Call it with s.mu locked.
+func (s *SQLiteStore) replace(db *sql.DB, closed func()) {
+	old := s.db
+	s.db = &handle{db: db}
+	s.retiring.Add(1)
+	go func() {
+		defer s.retiring.Done()
+		old.users.Wait()
+		old.db.Close()
+		if closed != nil {
+			closed()
+		}
+	}()
+}
+
+// Path returns the database file currently in use
+func (s *SQLiteStore) Path() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.path
+}
+
+// Reconnects returns how many times the database has been reopened
+func (s *SQLiteStore) Reconnects() int64 {
+	return s.reconnects.Load()
+}
+
+// withReconnect runs fn and, if it fails because the database connection is
+// broken, reopens the database and retries once. Reopen attempts back off
+// exponentially while the database stays unavailable.
+func (s *SQLiteStore) withReconnect(fn func(db *sql.DB) error) error {
+	h := s.acquire()
+	err := fn(h.db)
+	h.release()
+	if err == nil || !isConnectionError(err) {
+		return err
+	}
+
+	reopened, reopenErr := s.reopen(h)
+	if reopenErr != nil {
+		return fmt.Errorf("%w (reopen failed: %v)", err, reopenErr)
+	}
+	defer reopened.release()
+	return fn(reopened.db)
+}
+
+// reopen replaces a broken database handle and returns the new one, acquired
+// for the caller. If another caller already replaced it, the new handle is
+// returned without reopening again.
+func (s *SQLiteStore) reopen(broken *handle) (*handle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != broken {
+		s.db.users.Add(1)
+		return s.db, nil
+	}
+
+	now := time.Now()
+	if now.Before(s.nextReopen) {
+		return nil, fmt.Errorf("next attempt in %s", s.nextReopen.Sub(now).Round(time.Second))
+	}
+
+	db, err := openDatabase(s.path, s.fileMode)
+	if err != nil {
+		if s.backoff == 0 {
+			s.backoff = minReconnectBackoff
+		} else if s.backoff *= 2; s.backoff > maxReconnectBackoff {
+			s.backoff = maxReconnectBackoff
+		}
+		s.nextReopen = now.Add(s.backoff)
+		return nil, err
+	}
+
+	s.replace(db, nil)
+	s.backoff = 0
+	s.nextReopen = time.Time{}
+	s.reconnects.Add(1)
+	s.db.users.Add(1)
+	return s.db, nil
 }
 
+// isConnectionError reports whether err means the database file itself is
+// unusable (deleted, moved, corrupted or unreadable) rather than a problem
+// with a single statement
+func isConnectionError(err error) bool {
+	if errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrIoErr, sqlite3.ErrCorrupt, sqlite3.ErrCantOpen,
+		sqlite3.ErrNotADB, sqlite3.ErrReadonly:
+		return true
+	}
+	return false
+}
+
+// createSchema creates the original schema and then upgrades it through the
+// migrations in migrations.go
 func createSchema(db *sql.DB) error {
 	_, err := db.Exec(`
         CREATE TABLE IF NOT EXISTS health_checks (
@@ -51,28 +252,455 @@ func createSchema(db *sql.DB) error {
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
         );
         CREATE INDEX IF NOT EXISTS idx_url_timestamp ON health_checks(url, timestamp);
-        CREATE INDEX IF NOT EXISTS idx_name ON health_checks(name);
     `)
-	return err
+	if err != nil {
+		return err
+	}
+	return migrate(db)
+}
+
+// SetCompression gzips the error, headers, detail, body snippet and
+// components of checks saved from now on when they are longer than threshold
+// bytes. Zero turns compression off. Checks read back are decompressed
+// either way. Call it before saving checks.
+func (s *SQLiteStore) SetCompression(threshold int) {
+	s.compressOver = threshold
 }
 
 func (s *SQLiteStore) SaveCheck(check monitor.HealthCheck) error {
-	tags := strings.Join(check.Tags, ",")
-	_, err := s.db.Exec(`
-        INSERT INTO health_checks (name, url, status, status_code, response_time, timestamp, error, tags)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	return s.saveChecks([]monitor.HealthCheck{check})
+}
+
+// SaveChecks saves several checks in one transaction, which is much quicker
+// than saving them one by one. Either all are saved or none are.
+func (s *SQLiteStore) SaveChecks(checks []monitor.HealthCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	return s.saveChecks(checks)
+}
+
+func (s *SQLiteStore) saveChecks(checks []monitor.HealthCheck) error {
+	return s.withReconnect(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		for _, check := range checks {
+			if err := insertCheck(tx, check, s.compressOver); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// insertCheck inserts a health check and its tags and updates the endpoint
+// registry and response-time rollups. Text columns longer than compressOver
+// bytes are compressed.
+func insertCheck(tx *sql.Tx, check monitor.HealthCheck, compressOver int) error {
+	var headers sql.NullString
+	if len(check.Headers) > 0 {
+		data, err := json.Marshal(check.Headers)
+		if err != nil {
+			return err
+		}
+		headers = sql.NullString{String: string(data), Valid: true}
+	}
+	var timing sql.NullString
+	if check.Timing != nil {
+		data, err := json.Marshal(check.Timing)
+		if err != nil {
+			return err
+		}
+		timing = sql.NullString{String: string(data), Valid: true}
+	}
+	var geo sql.NullString
+	if check.Geo != nil {
+		data, err := json.Marshal(check.Geo)
+		if err != nil {
+			return err
+		}
+		geo = sql.NullString{String: string(data), Valid: true}
+	}
+	var components sql.NullString
+	if len(check.Components) > 0 {
+		data, err := json.Marshal(check.Components)
+		if err != nil {
+			return err
+		}
+		components = sql.NullString{String: string(data), Valid: true}
+	}
+	var redirects sql.NullString
+	if len(check.Redirects) > 0 {
+		data, err := json.Marshal(check.Redirects)
+		if err != nil {
+			return err
+		}
+		redirects = sql.NullString{String: string(data), Valid: true}
+	}
+	var steps sql.NullString
+	if len(check.Steps) > 0 {
+		data, err := json.Marshal(check.Steps)
+		if err != nil {
+			return err
+		}
+		steps = sql.NullString{String: string(data), Valid: true}
+	}
+
+	text := textCompressor{threshold: compressOver}
+	errString := text.column(check.Error, compressedError)
+	headersColumn := text.nullColumn(headers, compressedHeaders)
+	detail := text.column(check.Detail, compressedDetail)
+	snippet := text.column(check.BodySnippet, compressedBodySnippet)
+	componentsColumn := text.nullColumn(components, compressedComponents)
+	stepsColumn := text.nullColumn(steps, compressedSteps)
+
+	result, err := tx.Exec(`
+        INSERT INTO health_checks (name, url, status, status_code, response_time, response_time_us, timestamp, error, headers, protocol, probe, tls_version, ip_version, detail, body_size, decoded_body_size, body_snippet, timing, reason, remote_ip, geo, components, redirects, steps, compressed, repeats)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		check.Name,
 		check.URL,
 		check.Status,
 		check.StatusCode,
 		check.ResponseTime,
-		check.Timestamp,
-		check.Error,
-		tags,
+		check.ResponseTimeMicros,
+		check.Timestamp.UnixMilli(),
+		errString,
+		headersColumn,
+		check.Protocol,
+		check.Probe,
+		check.TLSVersion,
+		check.IPVersion,
+		detail,
+		check.BodySize,
+		check.DecodedBodySize,
+		snippet,
+		timing,
+		check.Reason,
+		check.RemoteIP,
+		geo,
+		componentsColumn,
+		redirects,
+		stepsColumn,
+		text.flags,
+		check.Repeats,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := saveTags(tx, id, check.Tags); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+        INSERT INTO endpoints (url, name, first_seen, last_seen)
+        VALUES (?, ?, ?, ?)
+        ON CONFLICT (url) DO UPDATE SET name = excluded.name, last_seen = excluded.last_seen, removed_at = NULL`,
+		check.URL, check.Name, check.Timestamp, check.Timestamp); err != nil {
+		return err
+	}
+	return saveRollups(tx, check)
+}
+
+// saveTags links a health check to its tags, creating any new tag names
+func saveTags(tx *sql.Tx, checkID int64, tags []string) error {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+            INSERT OR IGNORE INTO check_tags (check_id, tag_id)
+            SELECT ?, id FROM tags WHERE name = ?`, checkID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryChecks returns the health checks matching the filter, oldest first
+func (s *SQLiteStore) QueryChecks(filter CheckFilter) ([]monitor.HealthCheck, error) {
+	var checks []monitor.HealthCheck
+	err := s.StreamChecks(filter, func(check monitor.HealthCheck) error {
+		checks = append(checks, check)
+		return nil
+	})
+	return checks, err
+}
+
+// StreamChecks calls fn for each health check matching the filter, oldest
+// first, without holding the results in memory. An error from fn stops the
+// iteration and is returned.
+func (s *SQLiteStore) StreamChecks(filter CheckFilter, fn func(monitor.HealthCheck) error) error {
+	query := `
+        SELECT h.name, h.url, h.status, h.status_code, h.response_time, h.response_time_us, h.timestamp, h.error, h.headers, h.protocol, h.probe, h.tls_version, h.ip_version, h.detail, h.body_size, h.decoded_body_size, h.body_snippet, h.timing, h.reason, h.remote_ip, h.geo, h.components, h.redirects, h.steps, h.compressed, h.repeats,
+            (SELECT json_group_array(name) FROM (
+                SELECT t.name FROM check_tags ct
+                JOIN tags t ON t.id = ct.tag_id
+                WHERE ct.check_id = h.id
+                ORDER BY ct.rowid))
+        FROM health_checks h`
+	where, args := filter.whereClause()
+	query += where + " ORDER BY h.timestamp ASC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			check      monitor.HealthCheck
+			timestamp  int64
+			errString  sql.NullString
+			headers    sql.NullString
+			protocol   sql.NullString
+			probe      sql.NullString
+			tlsVersion sql.NullString
+			ipVersion  sql.NullString
+			detail     sql.NullString
+			bodySize   sql.NullInt64
+			decoded    sql.NullInt64
+			snippet    sql.NullString
+			timing     sql.NullString
+			reason     sql.NullString
+			remoteIP   sql.NullString
+			geo        sql.NullString
+			components sql.NullString
+			redirects  sql.NullString
+			steps      sql.NullString
+			compressed int64
+			tags       string
+		)
+		if err := rows.Scan(
+			&check.Name,
+			&check.URL,
+			&check.Status,
+			&check.StatusCode,
+			&check.ResponseTime,
+			&check.ResponseTimeMicros,
+			&timestamp,
+			&errString,
+			&headers,
+			&protocol,
+			&probe,
+			&tlsVersion,
+			&ipVersion,
+			&detail,
+			&bodySize,
+			&decoded,
+			&snippet,
+			&timing,
+			&reason,
+			&remoteIP,
+			&geo,
+			&components,
+			&redirects,
+			&steps,
+			&compressed,
+			&check.Repeats,
+			&tags,
+		); err != nil {
+			return err
+		}
+		for _, column := range []struct {
+			text *sql.NullString
+			flag int64
+		}{
+			{&errString, compressedError},
+			{&headers, compressedHeaders},
+			{&detail, compressedDetail},
+			{&snippet, compressedBodySnippet},
+			{&components, compressedComponents},
+			{&steps, compressedSteps},
+		} {
+			if err := decompressColumn(column.text, compressed, column.flag); err != nil {
+				return fmt.Errorf("invalid compressed text for check: %w", err)
+			}
+		}
+		check.Timestamp = time.UnixMilli(timestamp).UTC()
+		check.Error = errString.String
+		check.Reason = reason.String
+		check.RemoteIP = remoteIP.String
+		check.Protocol = protocol.String
+		check.Probe = probe.String
+		check.TLSVersion = tlsVersion.String
+		check.IPVersion = ipVersion.String
+		check.Detail = detail.String
+		check.BodySize = bodySize.Int64
+		check.DecodedBodySize = decoded.Int64
+		check.BodySnippet = snippet.String
+		if headers.Valid {
+			if err := json.Unmarshal([]byte(headers.String), &check.Headers); err != nil {
+				return fmt.Errorf("invalid headers for check: %w", err)
+			}
+		}
+		if timing.Valid {
+			if err := json.Unmarshal([]byte(timing.String), &check.Timing); err != nil {
+				return fmt.Errorf("invalid timing for check: %w", err)
+			}
+		}
+		if geo.Valid {
+			if err := json.Unmarshal([]byte(geo.String), &check.Geo); err != nil {
+				return fmt.Errorf("invalid geo for check: %w", err)
+			}
+		}
+		if components.Valid {
+			if err := json.Unmarshal([]byte(components.String), &check.Components); err != nil {
+				return fmt.Errorf("invalid components for check: %w", err)
+			}
+		}
+		if redirects.Valid {
+			if err := json.Unmarshal([]byte(redirects.String), &check.Redirects); err != nil {
+				return fmt.Errorf("invalid redirects for check: %w", err)
+			}
+		}
+		if steps.Valid {
+			if err := json.Unmarshal([]byte(steps.String), &check.Steps); err != nil {
+				return fmt.Errorf("invalid steps for check: %w", err)
+			}
+		}
+		if err := json.Unmarshal([]byte(tags), &check.Tags); err != nil {
+			return fmt.Errorf("invalid tags for check: %w", err)
+		}
+		if len(check.Tags) == 0 {
+			check.Tags = nil
+		}
+		if err := fn(check); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SummarizeChecks aggregates the checks matching the filter per probe and
+// endpoint, ordered by URL. Names are not unique, so rows are keyed on URL and
+// carry the endpoint's most recent name. The UP checks store_on_change_only
+// left out count through the repeats of the row stored after them.
+func (s *SQLiteStore) SummarizeChecks(filter CheckFilter) ([]CheckSummary, error) {
+	query := `
+        SELECT COALESCE(h.probe, ''), h.url,
+            COALESCE((SELECT e.name FROM endpoints e WHERE e.url = h.url), MAX(h.name)), COUNT(*) + SUM(h.repeats),
+            SUM(CASE WHEN h.status = 'UP' THEN 1 ELSE 0 END) + SUM(h.repeats),
+            COUNT(CASE WHEN h.status_code > 0 THEN 1 END),
+            AVG(CASE WHEN h.status_code > 0 THEN h.response_time_us END)
+        FROM health_checks h`
+	where, args := filter.whereClause()
+	query += where + " GROUP BY COALESCE(h.probe, ''), h.url ORDER BY h.url, COALESCE(h.probe, '')"
+
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []CheckSummary
+	for rows.Next() {
+		var (
+			summary CheckSummary
+			avg     sql.NullFloat64
+		)
+		if err := rows.Scan(&summary.Probe, &summary.URL, &summary.Name, &summary.Checks, &summary.Up, &summary.Responses, &avg); err != nil {
+			return nil, err
+		}
+		summary.AvgResponseTimeMicros = avg.Float64
+		summary.AvgResponseTime = avg.Float64 / 1000
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// ListEndpoints returns every endpoint that has ever been checked, ordered
+// by URL
+func (s *SQLiteStore) ListEndpoints() ([]EndpointRecord, error) {
+	db, release := s.conn()
+	defer release()
+	rows, err := db.Query("SELECT url, name, first_seen, last_seen, removed_at FROM endpoints ORDER BY url")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []EndpointRecord
+	for rows.Next() {
+		var (
+			r         EndpointRecord
+			removedAt sql.NullTime
+		)
+		if err := rows.Scan(&r.URL, &r.Name, &r.FirstSeen, &r.LastSeen, &removedAt); err != nil {
+			return nil, err
+		}
+		if removedAt.Valid {
+			r.RemovedAt = &removedAt.Time
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// whereClause builds the SQL conditions and arguments for a filter
+func (f CheckFilter) whereClause() (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if f.URL != "" {
+		conditions = append(conditions, "h.url = ?")
+		args = append(args, f.URL)
+	}
+	if f.Probe != "" {
+		conditions = append(conditions, "h.probe = ?")
+		args = append(args, f.Probe)
+	}
+	if f.Tag != "" {
+		conditions = append(conditions, `EXISTS (
+            SELECT 1 FROM check_tags ct
+            JOIN tags t ON t.id = ct.tag_id
+            WHERE ct.check_id = h.id AND t.name = ?)`)
+		args = append(args, f.Tag)
+	}
+	if !f.Since.IsZero() {
+		conditions = append(conditions, "h.timestamp >= ?")
+		args = append(args, f.Since.UnixMilli())
+	}
+	if !f.Until.IsZero() {
+		conditions = append(conditions, "h.timestamp < ?")
+		args = append(args, f.Until.UnixMilli())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
 }
 
 func (s *SQLiteStore) Close() error {
-	return s.db.Close()
+	s.mu.RLock()
+	current := s.db
+	s.mu.RUnlock()
+	current.users.Wait()
+	err := current.db.Close()
+	s.retiring.Wait()
+	if s.temporary {
+		if removeErr := os.Remove(s.path); err == nil {
+			err = removeErr
+		}
+	}
+	return err
 }
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// newTestStore opens a store in a temporary directory, closed when the test
+// ends
+func newTestStore(t testing.TB, name string) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), name), 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testCheck returns an UP check of url at t
+func testCheck(url string, t time.Time) monitor.HealthCheck {
+	return monitor.HealthCheck{
+		Name:         "test",
+		URL:          url,
+		Status:       monitor.StatusUp,
+		StatusCode:   200,
+		ResponseTime: 12,
+		Timestamp:    t,
+	}
+}
+
+func TestOpenWithDriverParameters(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSQLiteStore(filepath.Join(dir, "monitord.db")+"?_busy_timeout=1000", 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer store.Close()
+	if _, err := os.Stat(filepath.Join(dir, "monitord.db")); err != nil {
+		t.Errorf("database file: %v", err)
+	}
+
+	db, release := store.conn()
+	defer release()
+	var foreignKeys, busyTimeout int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		t.Fatalf("PRAGMA foreign_keys: %v", err)
+	}
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		t.Fatalf("PRAGMA busy_timeout: %v", err)
+	}
+	if foreignKeys != 1 || busyTimeout != 1000 {
+		t.Errorf("foreign_keys = %d, busy_timeout = %d; want 1, 1000", foreignKeys, busyTimeout)
+	}
+}
+
+func TestReopenKeepsHandleInUseOpen(t *testing.T) {
+	store := newTestStore(t, "monitord.db")
+	db, release := store.conn()
+
+	store.mu.RLock()
+	current := store.db
+	store.mu.RUnlock()
+	reopened, err := store.reopen(current)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	reopened.release()
+
+	// The replaced handle stays usable until released
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM health_checks").Scan(&count); err != nil {
+		t.Fatalf("query on the replaced handle: %v", err)
+	}
+	release()
+	if err := store.SaveCheck(testCheck("https://example.com", time.Now())); err != nil {
+		t.Fatalf("SaveCheck after reopen: %v", err)
+	}
+}
+
+func TestReopenWhileInUse(t *testing.T) {
+	store := newTestStore(t, "monitord.db")
+
+	const workers, saves = 4, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers*saves)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := fmt.Sprintf("https://example.com/%d", w)
+			for i := 0; i < saves; i++ {
+				if err := store.SaveCheck(testCheck(url, time.Now())); err != nil {
+					errs <- fmt.Errorf("SaveCheck: %w", err)
+				}
+				if _, err := store.QueryChecks(CheckFilter{URL: url}); err != nil {
+					errs <- fmt.Errorf("QueryChecks: %w", err)
+				}
+			}
+		}()
+	}
+
+	// Replace the handle repeatedly under the workers, as a reconnect
+	// after a broken connection does
+	reopens := 0
+	for i := 0; i < 20; i++ {
+		store.mu.RLock()
+		current := store.db
+		store.mu.RUnlock()
+		reopened, err := store.reopen(current)
+		if err != nil {
+			t.Fatalf("reopen: %v", err)
+		}
+		reopened.release()
+		if reopened != current {
+			reopens++
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := store.Reconnects(); got != int64(reopens) {
+		t.Errorf("Reconnects() = %d, want %d", got, reopens)
+	}
+	checks, err := store.QueryChecks(CheckFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != workers*saves {
+		t.Errorf("stored %d checks, want %d", len(checks), workers*saves)
+	}
+}
+
+func TestSaveChecks(t *testing.T) {
+	store := newTestStore(t, "monitord.db")
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var checks []monitor.HealthCheck
+	for i := range 10 {
+		check := testCheck(fmt.Sprintf("https://example.com/%d", i%3), start.Add(time.Duration(i)*time.Minute))
+		check.Tags = []string{"batch"}
+		checks = append(checks, check)
+	}
+	if err := store.SaveChecks(checks); err != nil {
+		t.Fatalf("SaveChecks: %v", err)
+	}
+
+	saved, err := store.QueryChecks(CheckFilter{Tag: "batch"})
+	if err != nil {
+		t.Fatalf("QueryChecks: %v", err)
+	}
+	if len(saved) != len(checks) {
+		t.Fatalf("saved %d checks, want %d", len(saved), len(checks))
+	}
+	endpoints, err := store.ListEndpoints()
+	if err != nil {
+		t.Fatalf("ListEndpoints: %v", err)
+	}
+	if len(endpoints) != 3 {
+		t.Errorf("%d endpoints registered, want 3", len(endpoints))
+	}
+}
+
+// BenchmarkSaveCheck compares saving checks one at a time with saving them
+// in batches, with and without compression of a short error
+func BenchmarkSaveCheck(b *testing.B) {
+	const batch = 100
+	for _, bench := range []struct {
+		name     string
+		batch    int
+		compress int
+	}{
+		{"single", 1, 0},
+		{"single/compressed", 1, 64},
+		{"batched", batch, 0},
+		{"batched/compressed", batch, 64},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			store := newTestStore(b, "monitord.db")
+			store.SetCompression(bench.compress)
+			start := time.Now()
+			checks := make([]monitor.HealthCheck, 0, bench.batch)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				check := testCheck(fmt.Sprintf("https://example.com/%d", i%10), start.Add(time.Duration(i)*time.Second))
+				check.Status, check.Error = monitor.StatusError, "connection refused"
+				checks = append(checks, check)
+				if len(checks) < bench.batch && i < b.N-1 {
+					continue
+				}
+				var err error
+				if bench.batch == 1 {
+					err = store.SaveCheck(checks[0])
+				} else {
+					err = store.SaveChecks(checks)
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				checks = checks[:0]
+			}
+		})
+	}
+}
+
+func TestAttachSavesBufferedChecks(t *testing.T) {
+	pending := NewPendingStore(100)
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		pending.SaveCheck(testCheck("https://example.com", start.Add(time.Duration(i)*time.Minute)))
+	}
+
+	store := newTestStore(t, "monitord.db")
+	if err := pending.Attach(store, store); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if n := pending.Buffered(); n != 0 {
+		t.Errorf("%d items still buffered", n)
+	}
+	saved, err := store.QueryChecks(CheckFilter{})
+	if err != nil {
+		t.Fatalf("QueryChecks: %v", err)
+	}
+	if len(saved) != 5 {
+		t.Errorf("saved %d checks, want 5", len(saved))
+	}
+}
+
+func TestQueryEventsAcrossZones(t *testing.T) {
+	store := newTestStore(t, "monitord.db")
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	east := time.FixedZone("UTC-5", -5*60*60)
+	for i, timestamp := range []time.Time{start, start.Add(time.Hour).In(east)} {
+		event := monitor.Event{Timestamp: timestamp, Type: monitor.EventStartup, Message: fmt.Sprint(i)}
+		if err := store.SaveEvent(event); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+	}
+
+	// The later event was written in a zone whose clock reads earlier
+	events, err := store.QueryEvents(EventFilter{Since: start.Add(30 * time.Minute)})
+	if err != nil {
+		t.Fatalf("QueryEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Message != "1" {
+		t.Fatalf("events since 00:30 = %+v, want the second", events)
+	}
+	if !events[0].Timestamp.Equal(start.Add(time.Hour)) {
+		t.Errorf("timestamp = %s, want %s", events[0].Timestamp, start.Add(time.Hour))
+	}
+}
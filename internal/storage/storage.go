@@ -1,11 +1,89 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/will-wright-eng/monitord/internal/monitor"
 )
 
 // Storage defines the interface for storing health check results
 type Storage interface {
 	SaveCheck(check monitor.HealthCheck) error
+	QueryChecks(filter CheckFilter) ([]monitor.HealthCheck, error)
+	StreamChecks(filter CheckFilter, fn func(monitor.HealthCheck) error) error
+	SummarizeChecks(filter CheckFilter) ([]CheckSummary, error)
+	ListEndpoints() ([]EndpointRecord, error)
+	SaveEvent(event monitor.Event) error
+	QueryEvents(filter EventFilter) ([]monitor.Event, error)
 	Close() error
-}
\ No newline at end of file
+}
+
+// CheckFilter narrows the health checks returned by a query. Zero values
+// match everything.
+type CheckFilter struct {
+	URL   string
+	Tag   string
+	Probe string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// EventFilter narrows the events returned by a query. Zero values match
+// everything.
+type EventFilter struct {
+	Type  string
+	URL   string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// CheckSummary aggregates the checks of one endpoint as seen by one probe
+type CheckSummary struct {
+	Probe           string
+	URL             string
+	Name            string
+	Checks          int
+	Up              int
+	Responses       int     // checks that received an HTTP response
+	AvgResponseTime float64 // milliseconds, over checks that got a response
+	// AvgResponseTimeMicros is AvgResponseTime in microseconds
+	AvgResponseTimeMicros float64
+}
+
+// StatsFilter narrows the response-time statistics read from the rollups.
+// Zero values match everything; Since and Until are widened to whole hours.
+type StatsFilter struct {
+	URL   string
+	Probe string
+	Since time.Time
+	Until time.Time
+}
+
+// ResponseTimeStats summarises the response times, in milliseconds, of one
+// endpoint as seen by one probe. Percentiles are estimated from a histogram
+// and are accurate to within one of its bins.
+type ResponseTimeStats struct {
+	Probe string
+	URL   string
+	Count int64
+	Min   int64
+	Max   int64
+	Mean  float64
+	P50   float64
+	P90   float64
+	P95   float64
+	P99   float64
+}
+
+// EndpointRecord is an entry in the registry of every endpoint ever checked
+type EndpointRecord struct {
+	URL       string
+	Name      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// RemovedAt is when the daemon found the endpoint missing from the
+	// config, when purging removed endpoints is enabled
+	RemovedAt *time.Time
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+)
+
+// Vacuum rebuilds the database file to return the space left by deleted rows
+// to the filesystem, and reports how many bytes the file shrank. VACUUM
+// cannot run inside a transaction and locks the database until it finishes,
+// which can take a while for a large file.
+func (s *SQLiteStore) Vacuum() (int64, error) {
+	path := s.Path()
+	before := fileSize(path)
+	err := s.withReconnect(func(db *sql.DB) error {
+		_, err := db.Exec("VACUUM")
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return before - fileSize(path), nil
+}
+
+// fileSize returns the size of a file, or 0 if it cannot be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
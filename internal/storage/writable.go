@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CheckWritable takes the database's write lock and releases it without
+// changing anything, confirming the file can be written
+func (s *SQLiteStore) CheckWritable() error {
+	return s.withReconnect(func(db *sql.DB) error {
+		ctx := context.Background()
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return err
+		}
+		_, err = conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	})
+}
@@ -0,0 +1,137 @@
+// Package monitord embeds endpoint monitoring in other Go programs. It is a
+// deliberately small public surface over the internal packages that make up
+// the monitord daemon: the configuration, the monitor service and its check
+// results.
+//
+// The simplest use runs everything the daemon would, including storage,
+// notifications and the optional API and metrics servers:
+//
+//	m, err := monitord.New(cfg, logger)
+//	if err != nil {
+//		return err
+//	}
+//	m.Service().OnCheck(func(check monitord.HealthCheck) { ... })
+//	return m.Run(ctx)
+package monitord
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/will-wright-eng/monitord/internal/app"
+	"github.com/will-wright-eng/monitord/internal/config"
+	"github.com/will-wright-eng/monitord/internal/monitor"
+)
+
+// Configuration and result types shared with the daemon
+type (
+	Config           = config.Config
+	DatabaseConfig   = config.DatabaseConfig
+	MonitorConfig    = config.MonitorConfig
+	Endpoint         = config.Endpoint
+	Duration         = config.Duration
+	HealthCheck      = monitor.HealthCheck
+	Transition       = monitor.Transition
+	Service          = monitor.Service
+	Status           = monitor.Status
+	EndpointState    = monitor.EndpointState
+	StartError       = monitor.StartError
+	Event            = monitor.Event
+	EndpointOverride = monitor.EndpointOverride
+	CheckReport      = monitor.CheckReport
+	Acknowledgement  = monitor.Acknowledgement
+)
+
+// Storage receives every check result from a Service
+type Storage = monitor.Storage
+
+// Notifier is told about confirmed status changes
+type Notifier = monitor.Notifier
+
+// OverrideStore keeps the endpoints enabled or disabled with
+// Service.SetEnabled across restarts
+type OverrideStore = monitor.OverrideStore
+
+// AcknowledgementStore keeps the incidents acknowledged with
+// Service.Acknowledge across restarts
+type AcknowledgementStore = monitor.AcknowledgementStore
+
+// Check statuses
+const (
+	StatusUp       = monitor.StatusUp
+	StatusDegraded = monitor.StatusDegraded
+	StatusError    = monitor.StatusError
+)
+
+// defaultConfigCheck is how often an embedded monitor refreshes endpoints
+// from remote_endpoints when config_check_interval is not set
+const defaultConfigCheck = 3 * time.Minute
+
+// shutdownTimeout bounds how long Run waits for components to stop
+const shutdownTimeout = 30 * time.Second
+
+// Monitor runs the full monitord application in-process
+type Monitor struct {
+	app *app.App
+}
+
+// New validates cfg and prepares a monitor for it. Paths in cfg are used as
+// given. The configuration is not reloaded from disk; remote_endpoints, if
+// set, are still refreshed every config_check_interval.
+func New(cfg Config, logger *log.Logger) (*Monitor, error) {
+	cfg = withDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	a, err := app.NewWithReload(&cfg, logger, staticReload(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return &Monitor{app: a}, nil
+}
+
+// Service returns the monitor service, for registering callbacks and reading
+// status
+func (m *Monitor) Service() *Service {
+	return m.app.Monitor()
+}
+
+// Run monitors until ctx is cancelled, then shuts down gracefully
+func (m *Monitor) Run(ctx context.Context) error {
+	if err := m.app.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return m.app.Shutdown(shutdownCtx)
+}
+
+// NewService creates a bare monitor service that writes checks to the given
+// storage and sends status changes to notifier, which may be nil. Unlike New
+// it starts no servers and opens no database; call Start and Shutdown on it
+// directly.
+func NewService(storage Storage, notifier Notifier, logger *log.Logger, cfg Config) *Service {
+	cfg = withDefaults(cfg)
+	return monitor.NewService(storage, notifier, nil, logger, cfg, staticReload(cfg))
+}
+
+// withDefaults fills in settings a config file would be required to have
+func withDefaults(cfg Config) Config {
+	if cfg.Monitor.ConfigCheck == 0 {
+		cfg.Monitor.ConfigCheck = Duration(defaultConfigCheck)
+	}
+	return cfg
+}
+
+// staticReload returns a reload function that always yields cfg, so config
+// checks only refresh remote endpoints
+func staticReload(cfg Config) func() (*config.Config, error) {
+	return func() (*config.Config, error) {
+		c := cfg
+		return &c, nil
+	}
+}